@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/grpcapi"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/jobs"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/aniket0951/order-services/payments"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/routers"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// EVENT_BUFFER events queued per subscriber before it is dropped as too slow
+const EVENT_BUFFER = 64
+
+// Dependencies external collaborators of the service, swap them for fakes in tests.
+// Mongo is optional, without it the readiness probe skips the database check.
+// PaymentRepo, ReturnRepo, WishlistRepo, LeaseRepo and Gateway default to in-memory stores and the mock gateway
+type Dependencies struct {
+	Logger       *slog.Logger
+	Mongo        *mongo.Client
+	OrderRepo    repositories.OrderRepository
+	PaymentRepo  repositories.PaymentRepository
+	ReturnRepo   repositories.ReturnRepository
+	WishlistRepo repositories.WishlistRepository
+	LeaseRepo    repositories.LeaseRepository
+	Inventory    clients.InventoryClient
+	Gateway      payments.PaymentGateway
+}
+
+// App fully wired service
+type App struct {
+	Config          *config.Config
+	Logger          *slog.Logger
+	Mongo           *mongo.Client
+	OrderRepo       repositories.OrderRepository
+	PaymentRepo     repositories.PaymentRepository
+	Inventory       clients.InventoryClient
+	Broker          *events.Broker
+	Metrics         *prometheus.Registry
+	OrderService    services.OrderService
+	PaymentService  services.PaymentService
+	ReturnService   services.ReturnService
+	WishlistService services.WishlistService
+	HealthService   services.HealthService
+	OrderController controllers.OrderControllers
+	Router          *gin.Engine
+
+	workers workerGroup
+}
+
+// New connect to mongo and build the service from config
+func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*App, error) {
+	if cfg.Mongo.Driver == "memory" {
+		logger.Warn("using the in-memory order repository, data is lost on restart")
+		return Build(cfg, Dependencies{
+			Logger:       logger,
+			OrderRepo:    repositories.NewMemoryOrderRepository(),
+			PaymentRepo:  repositories.NewMemoryPaymentRepository(),
+			ReturnRepo:   repositories.NewMemoryReturnRepository(),
+			WishlistRepo: repositories.NewMemoryWishlistRepository(),
+			LeaseRepo:    repositories.NewMemoryLeaseRepository(),
+			Inventory:    clients.NewInventoryClient(cfg, logger),
+		}), nil
+	}
+
+	client, err := config.ConnectDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("mongo connection established", "database", cfg.Mongo.Database)
+
+	if err := repositories.CreateWishlistIndexes(ctx, client, cfg); err != nil {
+		return nil, err
+	}
+	if err := repositories.CreatePaymentIndexes(ctx, client, cfg); err != nil {
+		return nil, err
+	}
+
+	return Build(cfg, Dependencies{
+		Logger:       logger,
+		Mongo:        client,
+		OrderRepo:    repositories.NewOrderRepository(client, cfg, logger),
+		PaymentRepo:  repositories.NewPaymentRepository(client, cfg, logger),
+		ReturnRepo:   repositories.NewReturnRepository(client, cfg, logger),
+		WishlistRepo: repositories.NewWishlistRepository(client, cfg, logger),
+		LeaseRepo:    repositories.NewLeaseRepository(client, cfg, logger),
+		Inventory:    clients.NewInventoryClient(cfg, logger),
+	}), nil
+}
+
+// Build wire services, controllers and the router on top of the given dependencies
+func Build(cfg *config.Config, deps Dependencies) *App {
+	if deps.Logger == nil {
+		deps.Logger = logging.Discard()
+	}
+	if deps.PaymentRepo == nil {
+		deps.PaymentRepo = repositories.NewMemoryPaymentRepository()
+	}
+	if deps.ReturnRepo == nil {
+		deps.ReturnRepo = repositories.NewMemoryReturnRepository()
+	}
+	if deps.WishlistRepo == nil {
+		deps.WishlistRepo = repositories.NewMemoryWishlistRepository()
+	}
+	if deps.LeaseRepo == nil {
+		deps.LeaseRepo = repositories.NewMemoryLeaseRepository()
+	}
+	if deps.Gateway == nil {
+		deps.Gateway = payments.NewMockGateway(cfg.Payments.WebhookSecret)
+	}
+
+	a := &App{
+		Config:      cfg,
+		Logger:      deps.Logger,
+		Mongo:       deps.Mongo,
+		OrderRepo:   deps.OrderRepo,
+		PaymentRepo: deps.PaymentRepo,
+		Inventory:   deps.Inventory,
+		Broker:      events.NewBroker(EVENT_BUFFER),
+		Metrics:     prometheus.NewRegistry(),
+	}
+	a.Metrics.MustRegister(metrics.NewCartLinesCollector(deps.OrderRepo.CountCartItems, cfg.Health.CheckTimeout))
+	validator := helper.NewRequestValidator(cfg.Order.Categories)
+
+	limits := cfg.Order.Limits
+	a.OrderService = services.NewOrderService(deps.OrderRepo, deps.PaymentRepo, deps.Inventory, services.NewLimitsPolicy(deps.OrderRepo, services.OrderLimits{
+		MaxQuantity:         limits.MaxQuantity,
+		CategoryMaxQuantity: limits.CategoryMaxQuantity,
+		ProductMaxQuantity:  limits.ProductMaxQuantity,
+		MaxOpenOrders:       limits.MaxOpenOrders,
+		MaxProductUnits:     limits.MaxProductUnits,
+		ProductWindow:       limits.ProductWindow,
+	}), a.Broker, deps.Logger)
+	a.PaymentService = services.NewPaymentService(deps.OrderRepo, deps.PaymentRepo, deps.Gateway, a.OrderService, cfg.Payments.Currency, services.RefundPolicy{
+		MaxAttempts: cfg.Payments.RefundMaxAttempts,
+		Backoff:     cfg.Payments.RefundBackoff,
+	}, deps.Logger)
+	a.ReturnService = services.NewReturnService(deps.OrderRepo, deps.ReturnRepo, deps.PaymentRepo, deps.Inventory, cfg.Order.ReturnWindow, deps.Logger)
+	a.WishlistService = services.NewWishlistService(deps.OrderRepo, deps.WishlistRepo, a.OrderService, deps.Inventory, cfg.ProductService.Timeout, deps.Logger)
+	cartPolicy := services.CartPolicy{
+		TTL:               cfg.Order.Cart.TTL,
+		AbandonedAfter:    cfg.Order.Cart.AbandonedAfter,
+		AbandonedMinValue: cfg.Order.Cart.AbandonedMinValue,
+	}
+	a.HealthService = services.NewHealthService(a.healthChecks(), a.WorkerStatuses, cfg.Health.CheckTimeout)
+	a.OrderController = controllers.NewOrderControllers(a.OrderService, validator)
+
+	a.Router = routers.NewRouter(routers.Controllers{
+		Order:       a.OrderController,
+		OrderV2:     controllers.NewOrderV2Controllers(a.OrderService, validator),
+		OrderEvents: controllers.NewOrderEventsControllers(a.OrderService, a.Broker, cfg.HTTP.EventsHeartbeat),
+		Payment:     controllers.NewPaymentControllers(a.PaymentService, validator),
+		Cart:        controllers.NewCartControllers(a.OrderService, cartPolicy, validator),
+		Return:      controllers.NewReturnControllers(a.ReturnService, validator),
+		Wishlist:    controllers.NewWishlistControllers(a.WishlistService, validator),
+		Health:      controllers.NewHealthControllers(a.HealthService),
+	}, a.Metrics, cfg, deps.Logger)
+
+	a.AddWorker(grpcapi.NewServer(cfg.GRPCAddr(), a.OrderService, a.Broker, validator, deps.Logger))
+	a.AddWorker(jobs.NewPeriodic("refund-processor", cfg.Payments.RefundInterval, func(ctx context.Context) error {
+		_, err := a.PaymentService.ProcessDueRefunds(ctx)
+		return err
+	}, deps.Logger))
+
+	// the scheduled jobs below run on the one replica holding their lease
+	instance := jobs.InstanceID()
+	a.AddWorker(jobs.NewPeriodic("order-auto-cancel", cfg.Order.AutoCancel.Interval, func(ctx context.Context) error {
+		_, err := a.OrderService.CancelStaleOrders(ctx, cfg.Order.AutoCancel.SLAs)
+		return err
+	}, deps.Logger).WithLease(deps.LeaseRepo, instance, cfg.Order.AutoCancel.LeaseTTL))
+	a.AddWorker(jobs.NewPeriodic("cart-sweeper", cfg.Order.Cart.SweepInterval, func(ctx context.Context) error {
+		_, err := a.OrderService.SweepCarts(ctx, cartPolicy)
+		return err
+	}, deps.Logger).WithLease(deps.LeaseRepo, instance, cfg.Order.Cart.LeaseTTL))
+
+	return a
+}
+
+func (a *App) healthChecks() []services.HealthCheck {
+	var checks []services.HealthCheck
+
+	if a.Mongo != nil {
+		checks = append(checks, services.HealthCheck{
+			Name: "mongo",
+			Check: func(ctx context.Context) error {
+				return a.Mongo.Ping(ctx, readpref.Primary())
+			},
+		})
+	}
+
+	checks = append(checks, services.HealthCheck{
+		Name:  "inventory",
+		Check: a.Inventory.Ping,
+	})
+
+	return checks
+}
+
+// Close release the mongo connection when the app owns one
+func (a *App) Close(ctx context.Context) error {
+	if a.Mongo == nil {
+		return nil
+	}
+
+	if err := a.Mongo.Disconnect(ctx); err != nil {
+		return fmt.Errorf("disconnect mongo: %w", err)
+	}
+	a.Logger.Info("mongo connection closed")
+	return nil
+}
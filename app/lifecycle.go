@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aniket0951/order-services/services"
+)
+
+// Worker long running background job, it must return once ctx is cancelled
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type workerGroup struct {
+	workers []Worker
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu       sync.RWMutex
+	statuses map[string]*services.WorkerStatus
+}
+
+// AddWorker register a background worker, started by Run
+func (a *App) AddWorker(w Worker) {
+	a.workers.workers = append(a.workers.workers, w)
+	a.workers.setStatus(w.Name(), func(s *services.WorkerStatus) {
+		s.State = services.WORKER_PENDING
+	})
+}
+
+func (g *workerGroup) setStatus(name string, update func(*services.WorkerStatus)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.statuses == nil {
+		g.statuses = map[string]*services.WorkerStatus{}
+	}
+	status, ok := g.statuses[name]
+	if !ok {
+		status = &services.WorkerStatus{Name: name}
+		g.statuses[name] = status
+	}
+	update(status)
+}
+
+// WorkerStatuses snapshot of every registered worker state
+func (a *App) WorkerStatuses() []services.WorkerStatus {
+	a.workers.mu.RLock()
+	defer a.workers.mu.RUnlock()
+
+	statuses := make([]services.WorkerStatus, 0, len(a.workers.workers))
+	for _, w := range a.workers.workers {
+		if status, ok := a.workers.statuses[w.Name()]; ok {
+			statuses = append(statuses, *status)
+		}
+	}
+	return statuses
+}
+
+// StartWorkers launch every registered worker, they stop on StopWorkers or when ctx is done
+func (a *App) StartWorkers(ctx context.Context) {
+	ctx, a.workers.cancel = context.WithCancel(ctx)
+
+	for _, w := range a.workers.workers {
+		a.workers.wg.Add(1)
+		a.workers.setStatus(w.Name(), func(s *services.WorkerStatus) {
+			s.State = services.WORKER_RUNNING
+			s.StartedAt = time.Now()
+			s.Error = ""
+		})
+
+		go func(w Worker) {
+			defer a.workers.wg.Done()
+
+			err := w.Run(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				a.Logger.Error("worker stopped", "worker", w.Name(), "error", err)
+				a.workers.setStatus(w.Name(), func(s *services.WorkerStatus) {
+					s.State = services.WORKER_FAILED
+					s.Error = err.Error()
+				})
+				return
+			}
+
+			a.workers.setStatus(w.Name(), func(s *services.WorkerStatus) {
+				s.State = services.WORKER_STOPPED
+			})
+		}(w)
+	}
+}
+
+// StopWorkers cancel the workers and wait for them until ctx expires
+func (a *App) StopWorkers(ctx context.Context) error {
+	if a.workers.cancel == nil {
+		return nil
+	}
+	a.workers.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		a.workers.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("timed out waiting for background workers")
+	}
+}
+
+// Server http server with the configured timeouts
+func (a *App) Server() *http.Server {
+	return &http.Server{
+		Addr:         a.Config.Addr(),
+		Handler:      a.Router,
+		ReadTimeout:  a.Config.HTTP.ReadTimeout,
+		WriteTimeout: a.Config.HTTP.WriteTimeout,
+		IdleTimeout:  a.Config.HTTP.IdleTimeout,
+	}
+}
+
+// Run serve http and the background workers until ctx is cancelled, then drain
+// in-flight requests, stop the workers and disconnect mongo within the shutdown timeout
+func (a *App) Run(ctx context.Context) error {
+	srv := a.Server()
+	a.StartWorkers(ctx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		a.Logger.Info("http server listening", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.Logger.Info("shutdown signal received, draining requests")
+	case runErr = <-serveErr:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+
+	if err := a.StopWorkers(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+
+	if err := a.Close(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+
+	a.Logger.Info("shutdown complete")
+	return runErr
+}
@@ -1,22 +1,60 @@
 package main
 
 import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aniket0951/order-services/app"
 	"github.com/aniket0951/order-services/config"
-	"github.com/aniket0951/order-services/routers"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/services"
+	"github.com/aniket0951/order-services/tracing"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	logger := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	slog.SetDefault(logger)
+
+	logger.Info("effective configuration", "config", cfg.Redacted())
+
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.New()
 
-	router.SetTrustedProxies(nil)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, services.Version)
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	defer config.CloseClientDB()
+	application, err := app.New(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("failed to start", "error", err)
+		os.Exit(1)
+	}
 
-	router.Static("static", "static")
+	runErr := application.Run(ctx)
 
-	routers.OrderRouter(router)
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
 
-	router.Run(":8080")
+	if runErr != nil {
+		logger.Error("stopped with error", "error", runErr)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,151 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// InventoryClient product service calls that keep stock in sync with orders
+type InventoryClient interface {
+	UpdateProductCount(ctx context.Context, tag string, num string, productId string) error
+	// GetProduct current price and stock of a selling variant, ErrProductNotFound once it is gone
+	GetProduct(ctx context.Context, productSellingId string) (ProductInfo, error)
+	Ping(ctx context.Context) error
+}
+
+// ErrProductNotFound the product service no longer sells the variant
+var ErrProductNotFound = errors.New("product is no longer sold")
+
+// ProductInfo selling variant as the product service knows it now
+type ProductInfo struct {
+	ProductId        string `json:"prod_id"`
+	ProductSellingID string `json:"prod_selling_id"`
+	Price            int64  `json:"price"`
+	Available        int64  `json:"available"`
+}
+
+type inventoryClient struct {
+	baseURL    string
+	timeout    time.Duration
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+func NewInventoryClient(cfg *config.Config, logger *slog.Logger) InventoryClient {
+	return &inventoryClient{
+		baseURL:    cfg.ProductService.BaseURL,
+		timeout:    cfg.ProductService.Timeout,
+		httpClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		logger:     logger,
+	}
+}
+
+// update product counts accordingly, tag is increase or decrease
+func (c *inventoryClient) UpdateProductCount(ctx context.Context, tag string, num string, productId string) (err error) {
+	defer func(start time.Time) { metrics.ObserveInventory("update_product_count", start, err) }(time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	reqUrl := c.baseURL + helper.INCREASE_DECREASE_PRODUCT + "?tag=" + tag + "&number=" + num + "&product_id=" + productId
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, reqUrl, &bytes.Buffer{})
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, resErr := c.httpClient.Do(req)
+
+	if resErr != nil {
+		return resErr
+	}
+
+	defer resp.Body.Close()
+
+	c.logger.DebugContext(ctx, "product count updated", "product_id", productId, "tag", tag, "number", num, "status", resp.StatusCode)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("product service responded %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (c *inventoryClient) GetProduct(ctx context.Context, productSellingId string) (_ ProductInfo, err error) {
+	defer func(start time.Time) { metrics.ObserveInventory("get_product", start, err) }(time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	reqUrl := c.baseURL + helper.GET_SELLING_PRODUCT + "?prod_selling_id=" + url.QueryEscape(productSellingId)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return ProductInfo{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ProductInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return ProductInfo{}, ErrProductNotFound
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return ProductInfo{}, fmt.Errorf("product service responded %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	// the product service wraps the variant in its response envelope
+	envelope := struct {
+		Data ProductInfo `json:"data"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil {
+		return ProductInfo{}, fmt.Errorf("decode product service response: %w", err)
+	}
+	return envelope.Data, nil
+}
+
+// Ping product service is reachable and not failing, any non 5xx answer counts
+func (c *inventoryClient) Ping(ctx context.Context) (err error) {
+	defer func(start time.Time) { metrics.ObserveInventory("ping", start, err) }(time.Now())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("product service responded %d", resp.StatusCode)
+	}
+	return nil
+}
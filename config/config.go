@@ -0,0 +1,787 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v3"
+)
+
+// Config effective service settings, resolved in order
+// defaults -> yaml file -> .env -> process environment
+type Config struct {
+	// local development, accepts well-known secrets that must never reach production
+	Dev            bool                 `yaml:"dev"`
+	HTTP           HTTPConfig           `yaml:"http"`
+	GRPC           GRPCConfig           `yaml:"grpc"`
+	Mongo          MongoConfig          `yaml:"mongo"`
+	Collections    CollectionsConfig    `yaml:"collections"`
+	ProductService ProductServiceConfig `yaml:"product_service"`
+	Order          OrderConfig          `yaml:"order"`
+	Payments       PaymentsConfig       `yaml:"payments"`
+	Health         HealthConfig         `yaml:"health"`
+	Log            LogConfig            `yaml:"log"`
+	Tracing        TracingConfig        `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// keep-alive comment interval on server-sent event streams
+	EventsHeartbeat time.Duration `yaml:"events_heartbeat"`
+	// legacy /api/order routes advertise these dates in Deprecation and Sunset headers
+	LegacyDeprecatedAt time.Time `yaml:"legacy_deprecated_at"`
+	LegacySunsetAt     time.Time `yaml:"legacy_sunset_at"`
+}
+
+// GRPCConfig internal service-to-service api, served next to http
+type GRPCConfig struct {
+	Port string `yaml:"port"`
+}
+
+type MongoConfig struct {
+	// mongo, or memory to keep everything in process for local development
+	Driver           string        `yaml:"driver"`
+	URI              string        `yaml:"uri"`
+	Database         string        `yaml:"database"`
+	ConnectTimeout   time.Duration `yaml:"connect_timeout"`
+	OperationTimeout time.Duration `yaml:"operation_timeout"`
+	// per repository method overrides of OperationTimeout, keyed by method name
+	OperationTimeouts map[string]time.Duration `yaml:"operation_timeouts"`
+}
+
+type CollectionsConfig struct {
+	Orders       string `yaml:"orders"`
+	OrderCart    string `yaml:"order_cart"`
+	OrderTrack   string `yaml:"order_track"`
+	OrderHistory string `yaml:"order_history"`
+	Payments     string `yaml:"payments"`
+	Refunds      string `yaml:"refunds"`
+	Leases       string `yaml:"leases"`
+	Returns      string `yaml:"returns"`
+	Wishlists    string `yaml:"wishlists"`
+}
+
+type ProductServiceConfig struct {
+	BaseURL string        `yaml:"base_url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type PaymentsConfig struct {
+	// payment gateway implementation, only mock is available for now
+	Gateway  string `yaml:"gateway"`
+	Currency string `yaml:"currency"`
+	// shared secret the gateway signs its callbacks with
+	WebhookSecret string `yaml:"webhook_secret"`
+	// how often pending refunds are sent to the gateway
+	RefundInterval time.Duration `yaml:"refund_interval"`
+	// gateway calls per refund before it is marked failed, the wait between
+	// them starts at RefundBackoff and doubles every attempt
+	RefundMaxAttempts int           `yaml:"refund_max_attempts"`
+	RefundBackoff     time.Duration `yaml:"refund_backoff"`
+}
+
+type TracingConfig struct {
+	// none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// grpc or http/protobuf, endpoint comes from OTEL_EXPORTER_OTLP_ENDPOINT
+	OTLPProtocol string  `yaml:"otlp_protocol"`
+	ServiceName  string  `yaml:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+type OrderConfig struct {
+	Categories []string         `yaml:"categories"`
+	AutoCancel AutoCancelConfig `yaml:"auto_cancel"`
+	Cart       CartConfig       `yaml:"cart"`
+	Limits     LimitsConfig     `yaml:"limits"`
+	// completed orders can be returned for this long after completion
+	ReturnWindow time.Duration `yaml:"return_window"`
+}
+
+// LimitsConfig quantity limits checked before an order or cart line is stored, 0 disables a limit
+type LimitsConfig struct {
+	// units per order or cart line, a category entry overrides it and a
+	// product entry, keyed by product id, overrides both
+	MaxQuantity         int64            `yaml:"max_quantity"`
+	CategoryMaxQuantity map[string]int64 `yaml:"category_max_quantity"`
+	ProductMaxQuantity  map[string]int64 `yaml:"product_max_quantity"`
+	// orders awaiting payment, placed or dispatched at once per user
+	MaxOpenOrders int64 `yaml:"max_open_orders"`
+	// units of the same product a user may order within ProductWindow
+	MaxProductUnits int64         `yaml:"max_product_units"`
+	ProductWindow   time.Duration `yaml:"product_window"`
+}
+
+// AutoCancelConfig scheduler cancelling orders stuck in a status
+type AutoCancelConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	// the replica holding the lease runs the scan, the lease lapses when not renewed within this
+	LeaseTTL time.Duration `yaml:"lease_ttl"`
+	// how long an order may stay in a status before it is cancelled, keyed by
+	// status. Statuses without an entry, or set to 0, are never cancelled automatically
+	SLAs map[string]time.Duration `yaml:"slas"`
+}
+
+// CartConfig sweeper expiring idle cart lines and reporting abandoned carts
+type CartConfig struct {
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+	LeaseTTL      time.Duration `yaml:"lease_ttl"`
+	// cart lines untouched for this long are removed with their CART order
+	TTL time.Duration `yaml:"ttl"`
+	// a cart idle this long and worth at least AbandonedMinValue counts as
+	// abandoned, an event goes out once per abandonment
+	AbandonedAfter    time.Duration `yaml:"abandoned_after"`
+	AbandonedMinValue float64       `yaml:"abandoned_min_value"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Port:               "8080",
+			ReadTimeout:        15 * time.Second,
+			WriteTimeout:       30 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			ShutdownTimeout:    20 * time.Second,
+			EventsHeartbeat:    15 * time.Second,
+			LegacyDeprecatedAt: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+			LegacySunsetAt:     time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+		},
+		GRPC: GRPCConfig{
+			Port: "9090",
+		},
+		Mongo: MongoConfig{
+			Driver:           "mongo",
+			URI:              "mongodb://localhost:27017",
+			Database:         "mautodb",
+			ConnectTimeout:   10 * time.Second,
+			OperationTimeout: 5 * time.Second,
+		},
+		Collections: CollectionsConfig{
+			Orders:       "orders",
+			OrderCart:    "order_cart",
+			OrderTrack:   "order_track",
+			OrderHistory: "order_history",
+			Payments:     "payments",
+			Refunds:      "refunds",
+			Leases:       "leases",
+			Returns:      "returns",
+			Wishlists:    "wishlists",
+		},
+		ProductService: ProductServiceConfig{
+			BaseURL: "http://localhost:5000/api/",
+			Timeout: 5 * time.Second,
+		},
+		Order: OrderConfig{
+			Categories: []string{"ELECTRONICS", "MOBILES", "FASHION", "HOME", "BOOKS", "GROCERY"},
+			AutoCancel: AutoCancelConfig{
+				Interval: time.Minute,
+				LeaseTTL: 3 * time.Minute,
+				SLAs: map[string]time.Duration{
+					"PENDING_PAYMENT": 30 * time.Minute,
+					"PLACED":          72 * time.Hour,
+				},
+			},
+			Cart: CartConfig{
+				SweepInterval:  time.Hour,
+				LeaseTTL:       3 * time.Hour,
+				TTL:            30 * 24 * time.Hour,
+				AbandonedAfter: 24 * time.Hour,
+			},
+			Limits: LimitsConfig{
+				MaxQuantity:     10,
+				MaxOpenOrders:   20,
+				MaxProductUnits: 30,
+				ProductWindow:   24 * time.Hour,
+			},
+			ReturnWindow: 14 * 24 * time.Hour,
+		},
+		Payments: PaymentsConfig{
+			Gateway:           "mock",
+			Currency:          "INR",
+			RefundInterval:    10 * time.Second,
+			RefundMaxAttempts: 5,
+			RefundBackoff:     30 * time.Second,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPProtocol: "grpc",
+			ServiceName:  "order-service",
+			SampleRatio:  1,
+		},
+	}
+}
+
+// Load build the config from defaults, the optional CONFIG_FILE yaml, .env and the environment
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if err := LoadEnv(); err != nil {
+		return nil, err
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadYAML(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadEnv load .env into the process environment, a missing file is not an error
+func LoadEnv() error {
+	err := godotenv.Load(".env")
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("load .env: %w", err)
+	}
+
+	return nil
+}
+
+func (cfg *Config) loadYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (cfg *Config) loadEnv() error {
+	if err := setBool(&cfg.Dev, "DEV_MODE"); err != nil {
+		return err
+	}
+	setString(&cfg.HTTP.Port, "HTTP_PORT")
+	setString(&cfg.GRPC.Port, "GRPC_PORT")
+	setString(&cfg.Mongo.Driver, "DB_DRIVER")
+	setString(&cfg.Mongo.URI, "DB_URL")
+	setString(&cfg.Mongo.Database, "DB_NAME")
+	setString(&cfg.Collections.Orders, "ORDERS")
+	setString(&cfg.Collections.OrderCart, "ORDER_CART")
+	setString(&cfg.Collections.OrderTrack, "ORDER_TRACK")
+	setString(&cfg.Collections.OrderHistory, "ORDER_HISTORY")
+	setString(&cfg.Collections.Payments, "PAYMENTS")
+	setString(&cfg.Collections.Refunds, "REFUNDS")
+	setString(&cfg.Collections.Leases, "LEASES")
+	setString(&cfg.Collections.Returns, "RETURNS")
+	setString(&cfg.Collections.Wishlists, "WISHLISTS")
+	setString(&cfg.ProductService.BaseURL, "PRODUCT_SERVICE_URL")
+	setList(&cfg.Order.Categories, "ORDER_CATEGORIES")
+	setString(&cfg.Payments.Gateway, "PAYMENT_GATEWAY")
+	setString(&cfg.Payments.Currency, "PAYMENT_CURRENCY")
+	setString(&cfg.Payments.WebhookSecret, "PAYMENT_WEBHOOK_SECRET")
+	setString(&cfg.Log.Level, "LOG_LEVEL")
+	setString(&cfg.Log.Format, "LOG_FORMAT")
+	setString(&cfg.Tracing.Exporter, "OTEL_TRACES_EXPORTER")
+	setString(&cfg.Tracing.OTLPProtocol, "OTEL_EXPORTER_OTLP_PROTOCOL")
+	setString(&cfg.Tracing.ServiceName, "OTEL_SERVICE_NAME")
+
+	if err := setFloat(&cfg.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO"); err != nil {
+		return err
+	}
+
+	if err := setInt(&cfg.Payments.RefundMaxAttempts, "PAYMENT_REFUND_MAX_ATTEMPTS"); err != nil {
+		return err
+	}
+
+	ints := map[string]*int64{
+		"ORDER_MAX_QUANTITY":      &cfg.Order.Limits.MaxQuantity,
+		"ORDER_MAX_OPEN_ORDERS":   &cfg.Order.Limits.MaxOpenOrders,
+		"ORDER_MAX_PRODUCT_UNITS": &cfg.Order.Limits.MaxProductUnits,
+	}
+
+	for key, field := range ints {
+		if err := setInt64(field, key); err != nil {
+			return err
+		}
+	}
+
+	if err := setInt64Map(&cfg.Order.Limits.CategoryMaxQuantity, "ORDER_CATEGORY_MAX_QUANTITY"); err != nil {
+		return err
+	}
+
+	if err := setInt64Map(&cfg.Order.Limits.ProductMaxQuantity, "ORDER_PRODUCT_MAX_QUANTITY"); err != nil {
+		return err
+	}
+
+	if err := setFloat(&cfg.Order.Cart.AbandonedMinValue, "CART_ABANDONED_MIN_VALUE"); err != nil {
+		return err
+	}
+
+	if err := setDurationMap(&cfg.Order.AutoCancel.SLAs, "AUTO_CANCEL_SLAS"); err != nil {
+		return err
+	}
+
+	durations := map[string]*time.Duration{
+		"HTTP_READ_TIMEOUT":       &cfg.HTTP.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":      &cfg.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":       &cfg.HTTP.IdleTimeout,
+		"HTTP_SHUTDOWN_TIMEOUT":   &cfg.HTTP.ShutdownTimeout,
+		"HTTP_EVENTS_HEARTBEAT":   &cfg.HTTP.EventsHeartbeat,
+		"DB_CONNECT_TIMEOUT":      &cfg.Mongo.ConnectTimeout,
+		"DB_OPERATION_TIMEOUT":    &cfg.Mongo.OperationTimeout,
+		"PRODUCT_SERVICE_TIMEOUT": &cfg.ProductService.Timeout,
+		"HEALTH_CHECK_TIMEOUT":    &cfg.Health.CheckTimeout,
+		"PAYMENT_REFUND_INTERVAL": &cfg.Payments.RefundInterval,
+		"PAYMENT_REFUND_BACKOFF":  &cfg.Payments.RefundBackoff,
+		"AUTO_CANCEL_INTERVAL":    &cfg.Order.AutoCancel.Interval,
+		"AUTO_CANCEL_LEASE_TTL":   &cfg.Order.AutoCancel.LeaseTTL,
+		"CART_SWEEP_INTERVAL":     &cfg.Order.Cart.SweepInterval,
+		"CART_LEASE_TTL":          &cfg.Order.Cart.LeaseTTL,
+		"CART_TTL":                &cfg.Order.Cart.TTL,
+		"CART_ABANDONED_AFTER":    &cfg.Order.Cart.AbandonedAfter,
+		"ORDER_PRODUCT_WINDOW":    &cfg.Order.Limits.ProductWindow,
+		"ORDER_RETURN_WINDOW":     &cfg.Order.ReturnWindow,
+	}
+
+	for key, field := range durations {
+		if err := setDuration(field, key); err != nil {
+			return err
+		}
+	}
+
+	if err := setDate(&cfg.HTTP.LegacyDeprecatedAt, "LEGACY_API_DEPRECATED_AT"); err != nil {
+		return err
+	}
+
+	return setDate(&cfg.HTTP.LegacySunsetAt, "LEGACY_API_SUNSET_AT")
+}
+
+func setString(field *string, key string) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		*field = val
+	}
+}
+
+func setList(field *[]string, key string) {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*field = items
+}
+
+func setFloat(field *float64, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*field = f
+	return nil
+}
+
+func setBool(field *bool, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*field = b
+	return nil
+}
+
+func setInt(field *int, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*field = i
+	return nil
+}
+
+func setInt64(field *int64, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	i, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*field = i
+	return nil
+}
+
+// NAME=number pairs separated by commas, replacing the whole map
+func setInt64Map(field *map[string]int64, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	items := map[string]int64{}
+	for _, pair := range strings.Split(val, ",") {
+		name, raw, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return fmt.Errorf("invalid %s: %q is not NAME=number", key, pair)
+		}
+		i, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		items[strings.TrimSpace(name)] = i
+	}
+	*field = items
+	return nil
+}
+
+// STATUS=duration pairs separated by commas, replacing the whole map
+func setDurationMap(field *map[string]time.Duration, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	items := map[string]time.Duration{}
+	for _, pair := range strings.Split(val, ",") {
+		name, raw, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return fmt.Errorf("invalid %s: %q is not NAME=duration", key, pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		items[strings.TrimSpace(name)] = d
+	}
+	*field = items
+	return nil
+}
+
+// dates as 2006-01-02 or full RFC 3339 timestamps
+func setDate(field *time.Time, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, val)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*field = t
+	return nil
+}
+
+func setDuration(field *time.Duration, key string) error {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*field = d
+	return nil
+}
+
+// secrets published in examples and docs, anyone could sign callbacks with them
+var knownSecrets = map[string]bool{
+	"mock-webhook-secret": true,
+	"secret":              true,
+	"changeme":            true,
+}
+
+// Validate check required settings are present and well formed
+func (cfg *Config) Validate() error {
+	var errs []string
+
+	if cfg.HTTP.Port == "" {
+		errs = append(errs, "http.port is required")
+	}
+
+	if cfg.GRPC.Port == "" {
+		errs = append(errs, "grpc.port is required")
+	} else if cfg.GRPC.Port == cfg.HTTP.Port {
+		errs = append(errs, "grpc.port must differ from http.port")
+	}
+
+	if cfg.Mongo.Driver != "mongo" && cfg.Mongo.Driver != "memory" {
+		errs = append(errs, "mongo.driver must be mongo or memory")
+	}
+
+	if !strings.HasPrefix(cfg.Mongo.URI, "mongodb://") && !strings.HasPrefix(cfg.Mongo.URI, "mongodb+srv://") {
+		errs = append(errs, "mongo.uri must start with mongodb:// or mongodb+srv://")
+	}
+
+	if cfg.Mongo.Database == "" {
+		errs = append(errs, "mongo.database is required")
+	}
+
+	if cfg.HTTP.ReadTimeout <= 0 || cfg.HTTP.WriteTimeout <= 0 || cfg.HTTP.IdleTimeout <= 0 {
+		errs = append(errs, "http read, write and idle timeouts must be positive")
+	}
+
+	if cfg.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, "http.shutdown_timeout must be positive")
+	}
+
+	if cfg.HTTP.EventsHeartbeat <= 0 {
+		errs = append(errs, "http.events_heartbeat must be positive")
+	}
+
+	if !cfg.HTTP.LegacySunsetAt.After(cfg.HTTP.LegacyDeprecatedAt) {
+		errs = append(errs, "http.legacy_sunset_at must be after http.legacy_deprecated_at")
+	}
+
+	if cfg.Mongo.ConnectTimeout <= 0 {
+		errs = append(errs, "mongo.connect_timeout must be positive")
+	}
+
+	if cfg.Mongo.OperationTimeout <= 0 {
+		errs = append(errs, "mongo.operation_timeout must be positive")
+	}
+
+	if cfg.ProductService.Timeout <= 0 {
+		errs = append(errs, "product_service.timeout must be positive")
+	}
+
+	if cfg.Collections.Orders == "" || cfg.Collections.OrderCart == "" ||
+		cfg.Collections.OrderTrack == "" || cfg.Collections.OrderHistory == "" ||
+		cfg.Collections.Payments == "" || cfg.Collections.Refunds == "" ||
+		cfg.Collections.Leases == "" || cfg.Collections.Returns == "" ||
+		cfg.Collections.Wishlists == "" {
+		errs = append(errs, "all collection names are required")
+	}
+
+	if u, err := url.Parse(cfg.ProductService.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, "product_service.base_url must be an absolute url")
+	}
+
+	if cfg.Health.CheckTimeout <= 0 {
+		errs = append(errs, "health.check_timeout must be positive")
+	}
+
+	if len(cfg.Order.Categories) == 0 {
+		errs = append(errs, "order.categories must not be empty")
+	}
+
+	if cfg.Order.AutoCancel.Interval <= 0 {
+		errs = append(errs, "order.auto_cancel.interval must be positive")
+	}
+
+	if cfg.Order.AutoCancel.LeaseTTL <= cfg.Order.AutoCancel.Interval {
+		errs = append(errs, "order.auto_cancel.lease_ttl must be longer than the interval")
+	}
+
+	for status, sla := range cfg.Order.AutoCancel.SLAs {
+		switch status {
+		case "PENDING_PAYMENT", "PLACED":
+		default:
+			errs = append(errs, "order.auto_cancel.slas only takes PENDING_PAYMENT and PLACED, got "+status)
+		}
+		if sla < 0 {
+			errs = append(errs, "order.auto_cancel.slas must not be negative")
+		}
+	}
+
+	if cfg.Order.Cart.SweepInterval <= 0 {
+		errs = append(errs, "order.cart.sweep_interval must be positive")
+	}
+
+	if cfg.Order.Cart.LeaseTTL <= cfg.Order.Cart.SweepInterval {
+		errs = append(errs, "order.cart.lease_ttl must be longer than the sweep interval")
+	}
+
+	if cfg.Order.Cart.AbandonedAfter <= 0 || cfg.Order.Cart.TTL <= cfg.Order.Cart.AbandonedAfter {
+		errs = append(errs, "order.cart.ttl must be longer than a positive abandoned_after")
+	}
+
+	if cfg.Order.Cart.AbandonedMinValue < 0 {
+		errs = append(errs, "order.cart.abandoned_min_value must not be negative")
+	}
+
+	if cfg.Order.ReturnWindow <= 0 {
+		errs = append(errs, "order.return_window must be positive")
+	}
+
+	limits := cfg.Order.Limits
+	if limits.MaxQuantity < 0 || limits.MaxOpenOrders < 0 || limits.MaxProductUnits < 0 {
+		errs = append(errs, "order.limits must not be negative")
+	}
+
+	if limits.MaxProductUnits > 0 && limits.ProductWindow <= 0 {
+		errs = append(errs, "order.limits.product_window must be positive when max_product_units is set")
+	}
+
+	for category, max := range limits.CategoryMaxQuantity {
+		known := false
+		for _, allowed := range cfg.Order.Categories {
+			known = known || strings.EqualFold(allowed, category)
+		}
+		if !known {
+			errs = append(errs, "order.limits.category_max_quantity has unknown category "+category)
+		}
+		if max < 0 {
+			errs = append(errs, "order.limits.category_max_quantity must not be negative")
+		}
+	}
+
+	for productId, max := range limits.ProductMaxQuantity {
+		if !primitive.IsValidObjectID(productId) {
+			errs = append(errs, "order.limits.product_max_quantity keys must be product ids, got "+productId)
+		}
+		if max < 0 {
+			errs = append(errs, "order.limits.product_max_quantity must not be negative")
+		}
+	}
+
+	if cfg.Payments.Gateway != "mock" {
+		errs = append(errs, "payments.gateway must be mock")
+	}
+
+	if cfg.Payments.Currency == "" {
+		errs = append(errs, "payments.currency is required")
+	}
+
+	if cfg.Payments.WebhookSecret == "" {
+		errs = append(errs, "payments.webhook_secret is required")
+	} else if !cfg.Dev && knownSecrets[cfg.Payments.WebhookSecret] {
+		errs = append(errs, "payments.webhook_secret is a well-known value, set a private secret or enable dev mode")
+	}
+
+	if cfg.Payments.RefundInterval <= 0 || cfg.Payments.RefundBackoff <= 0 {
+		errs = append(errs, "payments refund interval and backoff must be positive")
+	}
+
+	if cfg.Payments.RefundMaxAttempts < 1 {
+		errs = append(errs, "payments.refund_max_attempts must be at least 1")
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, "log.level must be one of debug, info, warn, error")
+	}
+
+	switch strings.ToLower(cfg.Log.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, "log.format must be json or text")
+	}
+
+	switch strings.ToLower(cfg.Tracing.Exporter) {
+	case "none", "stdout", "console", "otlp":
+	default:
+		errs = append(errs, "tracing.exporter must be one of none, stdout, otlp")
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		errs = append(errs, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Addr http listen address
+func (cfg *Config) Addr() string {
+	return ":" + cfg.HTTP.Port
+}
+
+// GRPCAddr grpc listen address
+func (cfg *Config) GRPCAddr() string {
+	return ":" + cfg.GRPC.Port
+}
+
+// Redacted copy of the config safe to print, credentials stripped from urls
+func (cfg *Config) Redacted() Config {
+	out := *cfg
+	out.Mongo.URI = redactURL(cfg.Mongo.URI)
+	out.ProductService.BaseURL = redactURL(cfg.ProductService.BaseURL)
+	if out.Payments.WebhookSecret != "" {
+		out.Payments.WebhookSecret = "xxxxx"
+	}
+	return out
+}
+
+// String effective settings as yaml with secrets redacted
+func (cfg *Config) String() string {
+	data, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateWebhookSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		dev     bool
+		wantErr string
+	}{
+		{name: "missing", secret: "", wantErr: "payments.webhook_secret is required"},
+		{name: "missing in dev mode", secret: "", dev: true, wantErr: "payments.webhook_secret is required"},
+		{name: "well-known", secret: "mock-webhook-secret", wantErr: "well-known value"},
+		{name: "well-known in dev mode", secret: "mock-webhook-secret", dev: true},
+		{name: "private", secret: "3f9c2a7e51b04d6a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Payments.WebhookSecret = tt.secret
+			cfg.Dev = tt.dev
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAutoCancelSLAs(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		wantErr bool
+	}{
+		{name: "pending payment", status: "PENDING_PAYMENT"},
+		{name: "placed", status: "PLACED"},
+		{name: "dispatched", status: "DISPATCHED", wantErr: true},
+		{name: "unknown", status: "LOST", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Payments.WebhookSecret = "3f9c2a7e51b04d6a"
+			cfg.Order.AutoCancel.SLAs = map[string]time.Duration{tt.status: time.Hour}
+
+			err := cfg.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate err = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "order.auto_cancel.slas") {
+				t.Fatalf("Validate err = %v", err)
+			}
+		})
+	}
+}
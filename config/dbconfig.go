@@ -3,79 +3,36 @@ package config
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
-	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
-func LoadEnv() {
-	err := godotenv.Load(".env")
+// ConnectDB open a mongo client and verify it with a ping
+func ConnectDB(ctx context.Context, cfg *Config) (*mongo.Client, error) {
+	// command monitor emits a span per mongo command
+	clientOptions := options.Client().ApplyURI(cfg.Mongo.URI).SetMonitor(otelmongo.NewMonitor())
 
-	if err != nil {
-		panic(err)
-	}
-}
-
-func EnvMongoURI() string {
-	LoadEnv()
-	var dbURL = os.Getenv("DB_URL")
-
-	return dbURL
-}
-
-var client *mongo.Client
-
-func ResolveClientDB() *mongo.Client {
-	if client != nil {
-		return client
-	}
-
-	var err error
-
-	clientOptions := options.Client().ApplyURI(EnvMongoURI())
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, cfg.Mongo.ConnectTimeout)
 	defer cancel()
-	client, err = mongo.Connect(ctx, clientOptions)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// check the connection
-	err = client.Ping(context.Background(), nil)
 
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("connect mongo: %w", err)
 	}
 
-	fmt.Println("Connection established...")
-	return client
-}
-
-func CloseClientDB() {
-	if client == nil {
-		return
-	}
-
-	err := client.Disconnect(context.TODO())
-	if err != nil {
-		log.Fatal(err)
+	// check the connection
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("ping mongo: %w", err)
 	}
 
-	// TODO optional you can log your closed MongoDB client
-	fmt.Println("Connection to MongoDB closed.")
+	return client, nil
 }
 
-// DB Client instance
-var DB = ResolveClientDB()
-
 // GetCollection getting database collections
-func GetCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	// var local = "golangAPI"
-	var remote = "mautodb"
-	collection := client.Database(remote).Collection(collectionName)
+func GetCollection(client *mongo.Client, database, collectionName string) *mongo.Collection {
+	collection := client.Database(database).Collection(collectionName)
 	return collection
 }
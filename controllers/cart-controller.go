@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ABANDONED_CARTS_LIMIT carts returned when the request sets no limit
+const ABANDONED_CARTS_LIMIT = 100
+
+// CartControllers cart reports for marketing
+type CartControllers interface {
+	ListAbandonedCarts(*gin.Context)
+}
+
+type cartControllers struct {
+	orderService services.OrderService
+	policy       services.CartPolicy
+	validator    *helper.RequestValidator
+}
+
+func NewCartControllers(orderService services.OrderService, policy services.CartPolicy, validator *helper.RequestValidator) CartControllers {
+	return &cartControllers{
+		orderService: orderService,
+		policy:       policy,
+		validator:    validator,
+	}
+}
+
+// GET /marketing/abandoned-carts?idle_hours=&min_value=&limit=
+func (c *cartControllers) ListAbandonedCarts(ctx *gin.Context) {
+	query := dto.AbandonedCartsQuery{}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		helper.BuildUnProcessableEntity(ctx, errors.New("idle_hours, min_value and limit must be numbers"))
+		return
+	}
+	if c.validator.CheckValidation(&query, ctx) {
+		return
+	}
+
+	idle := c.policy.AbandonedAfter
+	if query.IdleHours > 0 {
+		idle = time.Duration(query.IdleHours) * time.Hour
+	}
+	minValue := c.policy.AbandonedMinValue
+	if query.MinValue > 0 {
+		minValue = query.MinValue
+	}
+	limit := int64(ABANDONED_CARTS_LIMIT)
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+
+	carts, err := c.orderService.GetAbandonedCarts(ctx.Request.Context(), idle, minValue, limit)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.DATA_FOUND, carts, helper.CART_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,63 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAbandonedCartsRoute(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+
+	// a cart line of a user who left a day and a half ago
+	userId := primitive.NewObjectID()
+	order := models.Orders{Id: primitive.NewObjectID(), UserId: userId, OrderStatus: helper.CART, TotalPrice: 250}
+	if _, err := a.OrderRepo.PlaceSingleOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	idleSince := primitive.NewDateTimeFromTime(time.Now().Add(-36 * time.Hour))
+	if err := a.OrderRepo.AddToCartOrder(ctx, models.OrderCarts{OrderId: order.Id, UserId: userId, CreatedAt: idleSince, UpdatedAt: idleSince}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantCarts int
+	}{
+		{name: "configured defaults", query: "", wantCode: http.StatusOK, wantCarts: 1},
+		{name: "idle longer", query: "?idle_hours=48", wantCode: http.StatusOK, wantCarts: 0},
+		{name: "value above the cart", query: "?min_value=300", wantCode: http.StatusOK, wantCarts: 0},
+		{name: "every filter", query: "?idle_hours=12&min_value=200&limit=5", wantCode: http.StatusOK, wantCarts: 1},
+		{name: "not a number", query: "?idle_hours=abc", wantCode: http.StatusUnprocessableEntity},
+		{name: "limit too large", query: "?limit=501", wantCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := doRequest(t, a.Router, http.MethodGet, "/api/v2/marketing/abandoned-carts"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body %v", rec.Code, tt.wantCode, envelope)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			carts, _ := envelope[helper.CART_DATA].([]interface{})
+			if len(carts) != tt.wantCarts {
+				t.Fatalf("carts = %v, want %d", carts, tt.wantCarts)
+			}
+			if tt.wantCarts > 0 {
+				if cart := carts[0].(map[string]interface{}); cart["user_id"] != userId.Hex() || cart["value"] != 250.0 || cart["notified"] != false {
+					t.Fatalf("cart = %v", cart)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthControllers interface {
+	Liveness(*gin.Context)
+	Readiness(*gin.Context)
+	Status(*gin.Context)
+}
+
+type healthControllers struct {
+	healthService services.HealthService
+}
+
+func NewHealthControllers(healthService services.HealthService) HealthControllers {
+	return &healthControllers{
+		healthService: healthService,
+	}
+}
+
+// process is up, no dependency is checked
+func (c *healthControllers) Liveness(ctx *gin.Context) {
+	response := helper.BuildSuccessResponse("alive", helper.EmptyObj{}, helper.DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *healthControllers) Readiness(ctx *gin.Context) {
+	ready, dependencies, workers := c.healthService.Ready(ctx.Request.Context())
+
+	data := gin.H{"dependencies": dependencies, "workers": workers}
+
+	if !ready {
+		response := helper.BuildFailedResponse("not ready", "one or more dependencies are unavailable", data, helper.DATA)
+		ctx.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	response := helper.BuildSuccessResponse("ready", data, helper.DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *healthControllers) Status(ctx *gin.Context) {
+	status := c.healthService.Status(ctx.Request.Context())
+
+	code := http.StatusOK
+	if status.Status != services.STATUS_UP {
+		code = http.StatusServiceUnavailable
+	}
+
+	ctx.JSON(code, status)
+}
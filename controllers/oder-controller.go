@@ -8,7 +8,6 @@ import (
 	"github.com/aniket0951/order-services/helper"
 	"github.com/aniket0951/order-services/services"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type OrderControllers interface {
@@ -21,11 +20,13 @@ type OrderControllers interface {
 
 type orderControllers struct {
 	orderService services.OrderService
+	validator    *helper.RequestValidator
 }
 
-func NewOrderControllers(orderService services.OrderService) OrderControllers {
+func NewOrderControllers(orderService services.OrderService, validator *helper.RequestValidator) OrderControllers {
 	return &orderControllers{
 		orderService: orderService,
+		validator:    validator,
 	}
 }
 
@@ -38,19 +39,17 @@ func (c *orderControllers) PlaceSingleOrder(ctx *gin.Context) {
 		return
 	}
 
-	st := validator.New()
-
-	if stErr := st.Struct(&order); helper.CheckError(stErr, ctx) {
+	if c.validator.CheckValidation(&order, ctx) {
 		return
 	}
 
-	err := c.orderService.PlaceSingleOrder(order)
+	placed, err := c.orderService.PlaceSingleOrder(ctx.Request.Context(), order)
 
 	if helper.CheckError(err, ctx) {
 		return
 	}
 
-	response := helper.BuildSuccessResponse("order has been placed successfully", helper.EmptyObj{}, helper.ORDER_DATA)
+	response := helper.BuildSuccessResponse("order has been placed successfully", placed, helper.ORDER_DATA)
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -63,19 +62,17 @@ func (c *orderControllers) AddToCart(ctx *gin.Context) {
 		return
 	}
 
-	st := validator.New()
-
-	if stErr := st.Struct(&order); helper.CheckError(stErr, ctx) {
+	if c.validator.CheckValidation(&order, ctx) {
 		return
 	}
 
-	err := c.orderService.AddToCart(order)
+	added, err := c.orderService.AddToCart(ctx.Request.Context(), order)
 
 	if helper.CheckError(err, ctx) {
 		return
 	}
 
-	response := helper.BuildSuccessResponse("order has been added into cart successfully", helper.EmptyObj{}, helper.ORDER_DATA)
+	response := helper.BuildSuccessResponse("order has been added into cart successfully", added, helper.ORDER_DATA)
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -86,7 +83,7 @@ func (c *orderControllers) RemoveItemFromCart(ctx *gin.Context) {
 		return
 	}
 
-	err := c.orderService.RemoveItemFromCart(orderId)
+	err := c.orderService.RemoveItemFromCart(ctx.Request.Context(), orderId)
 
 	if helper.CheckError(err, ctx) {
 		return
@@ -104,12 +101,12 @@ func (c *orderControllers) UpdateOrderStatus(ctx *gin.Context) {
 		return
 	}
 
-	if status != helper.DISPATCHED || status != helper.COMPLETED || status != helper.CART {
+	if status != helper.DISPATCHED && status != helper.COMPLETED {
 		helper.BuildUnProcessableEntity(ctx, errors.New("invalid status passed"))
 		return
 	}
 
-	err := c.orderService.UpdateOrderStatus(orderId, status)
+	err := c.orderService.UpdateOrderStatus(ctx.Request.Context(), orderId, status)
 
 	if helper.CheckError(err, ctx) {
 		return
@@ -126,7 +123,7 @@ func (c *orderControllers) CancelOrder(ctx *gin.Context) {
 		return
 	}
 
-	err := c.orderService.CancelOrder(orderId)
+	err := c.orderService.CancelOrder(ctx.Request.Context(), orderId)
 
 	if helper.CheckError(err, ctx) {
 		return
@@ -0,0 +1,329 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aniket0951/order-services/app"
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type stubInventory struct{}
+
+func (stubInventory) UpdateProductCount(ctx context.Context, tag string, num string, productId string) error {
+	return nil
+}
+
+func (stubInventory) GetProduct(ctx context.Context, productSellingId string) (clients.ProductInfo, error) {
+	return clients.ProductInfo{ProductSellingID: productSellingId, Price: 99, Available: 100}, nil
+}
+
+func (stubInventory) Ping(ctx context.Context) error { return nil }
+
+// product service that takes no stock updates
+type downInventory struct{ stubInventory }
+
+func (downInventory) UpdateProductCount(ctx context.Context, tag string, num string, productId string) error {
+	return errors.New("product service unavailable")
+}
+
+func newTestApp(t *testing.T) *app.App {
+	return newTestAppWith(t, stubInventory{})
+}
+
+func newTestAppWith(t *testing.T, inventory clients.InventoryClient) *app.App {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Payments.WebhookSecret = "test-webhook-secret"
+	return app.Build(cfg, app.Dependencies{
+		OrderRepo: repositories.NewMemoryOrderRepository(),
+		Inventory: inventory,
+	})
+}
+
+func doRequest(t *testing.T, router http.Handler, method, target string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	envelope := map[string]interface{}{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &envelope)
+	return rec, envelope
+}
+
+func validOrderBody() dto.CreateOrderDTO {
+	return dto.CreateOrderDTO{
+		ProductId:        primitive.NewObjectID().Hex(),
+		Category:         "BOOKS",
+		ProductSellingID: primitive.NewObjectID().Hex(),
+		Quantity:         2,
+		Price:            99,
+		UserId:           primitive.NewObjectID().Hex(),
+	}
+}
+
+// placed and paid order, ready for fulfilment
+func placeOrder(t *testing.T, a *app.App) models.Orders {
+	t.Helper()
+	placed, err := a.OrderService.PlaceSingleOrder(context.Background(), validOrderBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payOrder(t, a, placed.Id.Hex())
+	placed.OrderStatus = helper.PLACED
+	return placed
+}
+
+func TestAppsKeepTheirOwnCategoriesAndMetrics(t *testing.T) {
+	cfg := config.Default()
+	cfg.Payments.WebhookSecret = "test-webhook-secret"
+	cfg.Order.Categories = []string{"TOYS"}
+	toys := app.Build(cfg, app.Dependencies{
+		OrderRepo: repositories.NewMemoryOrderRepository(),
+		Inventory: stubInventory{},
+	})
+	books := newTestApp(t)
+
+	order := validOrderBody()
+	order.Category = "TOYS"
+	if rec, _ := doRequest(t, toys.Router, http.MethodPost, "/api/v2/orders", order); rec.Code != http.StatusCreated {
+		t.Fatalf("TOYS on the app that sells them = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec, _ := doRequest(t, books.Router, http.MethodPost, "/api/v2/orders", order); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("TOYS on the default app = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := toys.OrderService.AddToCart(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		app  *app.App
+		want string
+	}{
+		{app: toys, want: "order_service_cart_lines 1"},
+		{app: books, want: "order_service_cart_lines 0"},
+	} {
+		rec := httptest.NewRecorder()
+		tt.app.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Fatalf("/metrics = %d, want %q in the body", rec.Code, tt.want)
+		}
+	}
+}
+
+func TestPlaceOrderStockNotUpdated(t *testing.T) {
+	a := newTestAppWith(t, downInventory{})
+
+	// both versions report the placed order with the stock it could not take
+	for _, tt := range []struct {
+		target   string
+		wantCode int
+	}{
+		{target: "/api/order/place-single-order", wantCode: http.StatusOK},
+		{target: "/api/v2/orders", wantCode: http.StatusCreated},
+	} {
+		rec, envelope := doRequest(t, a.Router, http.MethodPost, tt.target, validOrderBody())
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s = %d, body %s", tt.target, rec.Code, rec.Body.String())
+		}
+		if order := envelope[helper.ORDER_DATA].(map[string]interface{}); order["stock_not_updated"] != true || order["id"] == "" {
+			t.Fatalf("%s order = %v", tt.target, order)
+		}
+	}
+}
+
+func TestOrderRoutes(t *testing.T) {
+	invalid := validOrderBody()
+	invalid.ProductId = "not-an-id"
+	invalid.Quantity = -1
+	invalid.Category = "TOYS"
+
+	tests := []struct {
+		name       string
+		method     string
+		target     func(placed models.Orders) string
+		body       interface{}
+		wantCode   int
+		wantStatus bool
+		wantKey    string
+		wantFields []string
+	}{
+		{
+			name:       "place order",
+			method:     http.MethodPost,
+			target:     func(models.Orders) string { return "/api/order/place-single-order" },
+			body:       validOrderBody(),
+			wantCode:   http.StatusOK,
+			wantStatus: true,
+			wantKey:    helper.ORDER_DATA,
+		},
+		{
+			name:     "place order empty body",
+			method:   http.MethodPost,
+			target:   func(models.Orders) string { return "/api/order/place-single-order" },
+			body:     map[string]string{},
+			wantCode: http.StatusBadRequest,
+			wantKey:  helper.DATA,
+		},
+		{
+			name:       "place order field errors use json names",
+			method:     http.MethodPost,
+			target:     func(models.Orders) string { return "/api/order/place-single-order" },
+			body:       invalid,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantKey:    helper.DATA,
+			wantFields: []string{"prod_id", "category", "quantity"},
+		},
+		{
+			name:       "add to cart",
+			method:     http.MethodPost,
+			target:     func(models.Orders) string { return "/api/order/add-to-card" },
+			body:       validOrderBody(),
+			wantCode:   http.StatusOK,
+			wantStatus: true,
+			wantKey:    helper.ORDER_DATA,
+		},
+		{
+			name:     "remove from cart without order id",
+			method:   http.MethodDelete,
+			target:   func(models.Orders) string { return "/api/order/remove-item-from-cart" },
+			wantCode: http.StatusBadRequest,
+			wantKey:  helper.DATA,
+		},
+		{
+			name:   "remove unknown cart item",
+			method: http.MethodDelete,
+			target: func(models.Orders) string {
+				return "/api/order/remove-item-from-cart?order_id=" + primitive.NewObjectID().Hex()
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantKey:  helper.DATA,
+		},
+		{
+			name:   "dispatch order",
+			method: http.MethodPut,
+			target: func(o models.Orders) string {
+				return "/api/order/update-order-status?status=DISPATCHED&order_id=" + o.Id.Hex()
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: true,
+			wantKey:    helper.ORDER_DATA,
+		},
+		{
+			name:   "update with unknown status",
+			method: http.MethodPut,
+			target: func(o models.Orders) string {
+				return "/api/order/update-order-status?status=LOST&order_id=" + o.Id.Hex()
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantKey:  helper.DATA,
+		},
+		{
+			name:   "move order back to the cart",
+			method: http.MethodPut,
+			target: func(o models.Orders) string {
+				return "/api/order/update-order-status?status=CART&order_id=" + o.Id.Hex()
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantKey:  helper.DATA,
+		},
+		{
+			name:   "complete order that was not dispatched",
+			method: http.MethodPut,
+			target: func(o models.Orders) string {
+				return "/api/order/update-order-status?status=COMPLETED&order_id=" + o.Id.Hex()
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantKey:  helper.DATA,
+		},
+		{
+			name:       "cancel order",
+			method:     http.MethodPut,
+			target:     func(o models.Orders) string { return "/api/order/cancel-order?order_id=" + o.Id.Hex() },
+			wantCode:   http.StatusOK,
+			wantStatus: true,
+			wantKey:    helper.ORDER_DATA,
+		},
+		{
+			name:     "cancel with invalid id",
+			method:   http.MethodPut,
+			target:   func(models.Orders) string { return "/api/order/cancel-order?order_id=123" },
+			wantCode: http.StatusUnprocessableEntity,
+			wantKey:  helper.DATA,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			placed := placeOrder(t, a)
+
+			rec, envelope := doRequest(t, a.Router, tt.method, tt.target(placed), tt.body)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if envelope["status"] != tt.wantStatus {
+				t.Fatalf("envelope status = %v, want %v", envelope["status"], tt.wantStatus)
+			}
+			for _, key := range []string{"message", "error", tt.wantKey} {
+				if _, ok := envelope[key]; !ok {
+					t.Fatalf("envelope missing %q: %s", key, rec.Body.String())
+				}
+			}
+
+			if tt.wantFields != nil {
+				fieldErrors, _ := envelope["errors"].([]interface{})
+				got := map[string]bool{}
+				for _, fe := range fieldErrors {
+					got[fe.(map[string]interface{})["field"].(string)] = true
+				}
+				for _, field := range tt.wantFields {
+					if !got[field] {
+						t.Fatalf("missing field error for %s in %v", field, fieldErrors)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHealthRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	for _, target := range []string{"/healthz", "/readyz", "/status"} {
+		rec, _ := doRequest(t, a.Router, http.MethodGet, target, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s = %d, body %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
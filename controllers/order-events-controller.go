@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrderEventsControllers server-sent event streams of order status changes.
+// Every event id is the order track id, clients reconnecting with
+// Last-Event-ID get the changes they missed replayed from order_track
+type OrderEventsControllers interface {
+	StreamOrder(*gin.Context)
+	StreamUserOrders(*gin.Context)
+}
+
+type orderEventsControllers struct {
+	orderService services.OrderService
+	broker       *events.Broker
+	heartbeat    time.Duration
+}
+
+func NewOrderEventsControllers(orderService services.OrderService, broker *events.Broker, heartbeat time.Duration) OrderEventsControllers {
+	return &orderEventsControllers{
+		orderService: orderService,
+		broker:       broker,
+		heartbeat:    heartbeat,
+	}
+}
+
+// GET /orders/:id/events, ends once the order is completed or cancelled
+func (c *orderEventsControllers) StreamOrder(ctx *gin.Context) {
+	orderId, err := helper.ValidatePrimitiveId(ctx.Param("id"))
+	if helper.CheckError(err, ctx) {
+		return
+	}
+
+	// subscribe before the replay so nothing published in between is lost
+	sub := c.broker.Subscribe(func(event events.OrderEvent) bool {
+		return event.Track.OrderId == orderId
+	})
+	defer sub.Close()
+
+	order, err := c.orderService.GetOrder(ctx.Request.Context(), orderId.Hex())
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	replay, err := c.orderService.GetOrderTrackSince(ctx.Request.Context(), orderId.Hex(), ctx.GetHeader("Last-Event-ID"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	if len(replay) == 0 && helper.IsFinalStatus(order.OrderStatus) {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	c.stream(ctx, sub, replay, true)
+}
+
+// GET /users/:userId/orders/events, every status change of the user's orders
+func (c *orderEventsControllers) StreamUserOrders(ctx *gin.Context) {
+	userId, err := helper.ValidatePrimitiveId(ctx.Param("userId"))
+	if helper.CheckError(err, ctx) {
+		return
+	}
+
+	sub := c.broker.Subscribe(func(event events.OrderEvent) bool {
+		return event.Type == events.ORDER_STATUS_CHANGED && event.UserId == userId
+	})
+	defer sub.Close()
+
+	var replay []models.OrderTrack
+	if lastEventId := ctx.GetHeader("Last-Event-ID"); lastEventId != "" {
+		replay, err = c.orderService.GetUserOrderTrackSince(ctx.Request.Context(), userId.Hex(), lastEventId)
+		if checkResourceError(err, ctx) {
+			return
+		}
+	}
+
+	c.stream(ctx, sub, replay, false)
+}
+
+// write the replayed entries then live events until the client leaves, the
+// subscription is dropped as too slow or, with untilFinal, the order is done
+func (c *orderEventsControllers) stream(ctx *gin.Context, sub *events.Subscription, replay []models.OrderTrack, untilFinal bool) {
+	// long lived response, the server write timeout must not cut it off
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	sent := make(map[primitive.ObjectID]bool, len(replay))
+	for _, track := range replay {
+		writeTrackEvent(ctx.Writer, track)
+		sent[track.Id] = true
+		if untilFinal && helper.IsFinalStatus(track.OrderStatus) {
+			ctx.Writer.Flush()
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(c.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, _ = io.WriteString(ctx.Writer, ": keep-alive\n\n")
+			ctx.Writer.Flush()
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if sent[event.Track.Id] {
+				continue
+			}
+			writeTrackEvent(ctx.Writer, event.Track)
+			ctx.Writer.Flush()
+			if untilFinal && helper.IsFinalStatus(event.Track.OrderStatus) {
+				return
+			}
+		}
+	}
+}
+
+func writeTrackEvent(w io.Writer, track models.OrderTrack) {
+	_ = sse.Encode(w, sse.Event{
+		Id:    track.Id.Hex(),
+		Event: events.ORDER_STATUS_CHANGED,
+		Data:  track,
+	})
+}
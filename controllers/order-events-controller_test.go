@@ -0,0 +1,227 @@
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	track models.OrderTrack
+}
+
+// open an event stream against a real listener, the recorder cannot stream
+func openStream(t *testing.T, ctx context.Context, url, lastEventId string) (*http.Response, <-chan sseEvent) {
+	t.Helper()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	out := make(chan sseEvent)
+	go func() {
+		defer close(out)
+		scanner := bufio.NewScanner(res.Body)
+		current := sseEvent{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id:"):
+				current.id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			case strings.HasPrefix(line, "event:"):
+				current.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &current.track)
+			case line == "" && current.id != "":
+				out <- current
+				current = sseEvent{}
+			}
+		}
+	}()
+	return res, out
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return sseEvent{}
+}
+
+// wait until the stream handler has subscribed so published events reach it
+func waitForSubscribers(t *testing.T, broker *events.Broker, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.Subscribers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscribers = %d, want %d", broker.Subscribers(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamOrderEvents(t *testing.T) {
+	a := newTestApp(t)
+	server := httptest.NewServer(a.Router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	placed := placeOrder(t, a)
+
+	res, stream := openStream(t, ctx, server.URL+"/api/v2/orders/"+placed.Id.Hex()+"/events", "")
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status = %d, content type %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	for _, want := range []string{helper.PLACED} {
+		replayed := nextEvent(t, stream)
+		if replayed.event != events.ORDER_STATUS_CHANGED || replayed.track.OrderStatus != want || replayed.id != replayed.track.Id.Hex() {
+			t.Fatalf("replayed = %+v, want %s", replayed, want)
+		}
+	}
+
+	waitForSubscribers(t, a.Broker, 1)
+	if err := a.OrderService.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if live := nextEvent(t, stream); live.track.OrderStatus != helper.REFUND_PENDING {
+		t.Fatalf("live = %+v", live)
+	}
+	if _, err := a.PaymentService.ProcessDueRefunds(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if live := nextEvent(t, stream); live.track.OrderStatus != helper.REFUNDED {
+		t.Fatalf("live = %+v", live)
+	}
+
+	// the stream ends once the refund has settled
+	select {
+	case _, ok := <-stream:
+		if ok {
+			t.Fatal("unexpected event after REFUNDED")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end after REFUNDED")
+	}
+}
+
+func TestStreamOrderEventsResume(t *testing.T) {
+	a := newTestApp(t)
+	server := httptest.NewServer(a.Router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	placed := placeOrder(t, a)
+	if err := a.OrderService.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PaymentService.ProcessDueRefunds(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tracks, _ := a.OrderRepo.GetAllOrderTrack(ctx, placed.Id)
+	last := tracks[len(tracks)-1]
+
+	_, stream := openStream(t, ctx, server.URL+"/api/v2/orders/"+placed.Id.Hex()+"/events", tracks[1].Id.Hex())
+	if event := nextEvent(t, stream); event.track.Id != tracks[2].Id {
+		t.Fatalf("resumed = %+v, want %s", event, tracks[2].Id.Hex())
+	}
+
+	res, _ := openStream(t, ctx, server.URL+"/api/v2/orders/"+placed.Id.Hex()+"/events", last.Id.Hex())
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("finished order status = %d, want 204", res.StatusCode)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		lastId   string
+		wantCode int
+	}{
+		{name: "unknown order", target: "/api/v2/orders/" + primitive.NewObjectID().Hex() + "/events", wantCode: http.StatusNotFound},
+		{name: "invalid order id", target: "/api/v2/orders/abc/events", wantCode: http.StatusUnprocessableEntity},
+		{name: "invalid last event id", target: "/api/v2/orders/" + placed.Id.Hex() + "/events", lastId: "abc", wantCode: http.StatusUnprocessableEntity},
+		{name: "invalid user id", target: "/api/v2/users/abc/orders/events", wantCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := openStream(t, ctx, server.URL+tt.target, tt.lastId)
+			if res.StatusCode != tt.wantCode {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestStreamUserOrderEvents(t *testing.T) {
+	a := newTestApp(t)
+	server := httptest.NewServer(a.Router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first := placeOrder(t, a)
+	body := validOrderBody()
+	body.UserId = first.UserId.Hex()
+	second, err := a.OrderService.PlaceSingleOrder(ctx, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payOrder(t, a, second.Id.Hex())
+	other := placeOrder(t, a)
+
+	if err := a.OrderService.UpdateOrderStatus(ctx, first.Id.Hex(), helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+	firstTracks, _ := a.OrderRepo.GetAllOrderTrack(ctx, first.Id)
+
+	streamURL := server.URL + "/api/v2/users/" + first.UserId.Hex() + "/orders/events"
+	_, stream := openStream(t, ctx, streamURL, "")
+	waitForSubscribers(t, a.Broker, 1)
+
+	for _, id := range []primitive.ObjectID{other.Id, second.Id} {
+		if err := a.OrderService.UpdateOrderStatus(ctx, id.Hex(), helper.DISPATCHED); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the other user's change is filtered out
+	if event := nextEvent(t, stream); event.track.OrderId != second.Id {
+		t.Fatalf("event = %+v, want order %s", event, second.Id.Hex())
+	}
+
+	_, resumed := openStream(t, ctx, streamURL, firstTracks[len(firstTracks)-1].Id.Hex())
+	if event := nextEvent(t, resumed); event.track.OrderId != second.Id {
+		t.Fatalf("resumed = %+v, want order %s", event, second.Id.Hex())
+	}
+}
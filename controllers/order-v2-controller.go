@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+)
+
+// OrderV2Controllers resource oriented handlers for /api/v2, sharing OrderService with the legacy routes
+type OrderV2Controllers interface {
+	CreateOrder(*gin.Context)
+	GetOrder(*gin.Context)
+	EditOrder(*gin.Context)
+	Reorder(*gin.Context)
+	CancelOrder(*gin.Context)
+	ShipOrder(*gin.Context)
+	UpdateOrderStatus(*gin.Context)
+
+	ListCartItems(*gin.Context)
+	AddCartItem(*gin.Context)
+	RemoveCartItem(*gin.Context)
+}
+
+type orderV2Controllers struct {
+	orderService services.OrderService
+	validator    *helper.RequestValidator
+}
+
+func NewOrderV2Controllers(orderService services.OrderService, validator *helper.RequestValidator) OrderV2Controllers {
+	return &orderV2Controllers{
+		orderService: orderService,
+		validator:    validator,
+	}
+}
+
+// 404 for unknown resources, 422 for everything else
+func checkResourceError(err error, ctx *gin.Context) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, repositories.ErrOrderNotFound) || errors.Is(err, services.ErrNotInCart) ||
+		errors.Is(err, repositories.ErrPaymentNotFound) || errors.Is(err, repositories.ErrReturnNotFound) ||
+		errors.Is(err, repositories.ErrRefundNotFound) || errors.Is(err, repositories.ErrWishlistItemNotFound) {
+		helper.BuildNotFoundResponse(ctx, err)
+		return true
+	}
+
+	return helper.CheckError(err, ctx)
+}
+
+func (c *orderV2Controllers) bindCreateOrder(ctx *gin.Context, order *dto.CreateOrderDTO) bool {
+	_ = ctx.ShouldBindJSON(order)
+
+	if (*order == dto.CreateOrderDTO{}) {
+		helper.RequestBodyEmptyResponse(ctx)
+		return false
+	}
+
+	return !c.validator.CheckValidation(order, ctx)
+}
+
+// POST /orders
+func (c *orderV2Controllers) CreateOrder(ctx *gin.Context) {
+	order := dto.CreateOrderDTO{}
+	if !c.bindCreateOrder(ctx, &order) {
+		return
+	}
+
+	placed, err := c.orderService.PlaceSingleOrder(ctx.Request.Context(), order)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	ctx.Header("Location", "/api/v2/orders/"+placed.Id.Hex())
+	response := helper.BuildSuccessResponse("order has been placed successfully", placed, helper.ORDER_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GET /orders/:id
+func (c *orderV2Controllers) GetOrder(ctx *gin.Context) {
+	order, err := c.orderService.GetOrder(ctx.Request.Context(), ctx.Param("id"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.DATA_FOUND, order, helper.ORDER_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// PATCH /orders/:id, change the quantity or variant of an order that has not been dispatched
+func (c *orderV2Controllers) EditOrder(ctx *gin.Context) {
+	edit := dto.EditOrderDTO{}
+	_ = ctx.ShouldBindJSON(&edit)
+
+	if (edit == dto.EditOrderDTO{}) {
+		helper.RequestBodyEmptyResponse(ctx)
+		return
+	}
+	if c.validator.CheckValidation(&edit, ctx) {
+		return
+	}
+
+	order, err := c.orderService.EditOrder(ctx.Request.Context(), ctx.Param("id"), edit)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, order, helper.ORDER_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /orders/:id/reorder, 201 when anything was ordered again, 200 when nothing is available
+func (c *orderV2Controllers) Reorder(ctx *gin.Context) {
+	reorderReq := dto.ReorderDTO{}
+	_ = ctx.ShouldBindJSON(&reorderReq)
+
+	if c.validator.CheckValidation(&reorderReq, ctx) {
+		return
+	}
+
+	reorder, err := c.orderService.Reorder(ctx.Request.Context(), ctx.Param("id"), reorderReq.Mode)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	if len(reorder.Orders) == 0 {
+		response := helper.BuildSuccessResponse("nothing of the order is available any more", reorder, helper.REORDER_DATA)
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+
+	response := helper.BuildSuccessResponse("order has been placed again successfully", reorder, helper.REORDER_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// POST /orders/:id/cancel, a quantity in the body cancels only that many unshipped units
+func (c *orderV2Controllers) CancelOrder(ctx *gin.Context) {
+	orderId := ctx.Param("id")
+
+	cancel := dto.CancelOrderDTO{}
+	_ = ctx.ShouldBindJSON(&cancel)
+	if c.validator.CheckValidation(&cancel, ctx) {
+		return
+	}
+
+	if cancel.Quantity > 0 {
+		order, err := c.orderService.CancelOrderUnits(ctx.Request.Context(), orderId, cancel.Quantity)
+		if checkResourceError(err, ctx) {
+			return
+		}
+
+		response := helper.BuildSuccessResponse("order units have been cancelled successfully", order, helper.ORDER_DATA)
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+
+	err := c.orderService.CancelOrder(ctx.Request.Context(), orderId)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	order, err := c.orderService.GetOrder(ctx.Request.Context(), orderId)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("order has been cancel successfully", order, helper.ORDER_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /orders/:id/shipments, units left unshipped are backordered
+func (c *orderV2Controllers) ShipOrder(ctx *gin.Context) {
+	shipment := dto.ShipOrderDTO{}
+	_ = ctx.ShouldBindJSON(&shipment)
+	if c.validator.CheckValidation(&shipment, ctx) {
+		return
+	}
+
+	order, err := c.orderService.ShipOrderUnits(ctx.Request.Context(), ctx.Param("id"), shipment.Quantity)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("order units have been shipped", order, helper.ORDER_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// PUT /orders/:id/status
+func (c *orderV2Controllers) UpdateOrderStatus(ctx *gin.Context) {
+	statusUpdate := dto.UpdateOrderStatusDTO{}
+	_ = ctx.ShouldBindJSON(&statusUpdate)
+	statusUpdate.OrderId = ctx.Param("id")
+
+	if c.validator.CheckValidation(&statusUpdate, ctx) {
+		return
+	}
+
+	if statusUpdate.OrderStatus != helper.DISPATCHED && statusUpdate.OrderStatus != helper.COMPLETED {
+		helper.BuildUnProcessableEntity(ctx, errors.New("invalid status passed"))
+		return
+	}
+
+	err := c.orderService.UpdateOrderStatus(ctx.Request.Context(), statusUpdate.OrderId, statusUpdate.OrderStatus)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, helper.EmptyObj{}, helper.ORDER_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GET /carts/:userId/items
+func (c *orderV2Controllers) ListCartItems(ctx *gin.Context) {
+	items, err := c.orderService.GetCartItems(ctx.Request.Context(), ctx.Param("userId"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.FETCHED_SUCCESS, items, helper.ORDER_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /carts/:userId/items, the path user wins over any user_id in the body
+func (c *orderV2Controllers) AddCartItem(ctx *gin.Context) {
+	order := dto.CreateOrderDTO{}
+	_ = ctx.ShouldBindJSON(&order)
+	order.UserId = ctx.Param("userId")
+
+	if c.validator.CheckValidation(&order, ctx) {
+		return
+	}
+
+	added, err := c.orderService.AddToCart(ctx.Request.Context(), order)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("order has been added into cart successfully", added, helper.ORDER_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// DELETE /carts/:userId/items/:orderId
+func (c *orderV2Controllers) RemoveCartItem(ctx *gin.Context) {
+	err := c.orderService.RemoveItemFromUserCart(ctx.Request.Context(), ctx.Param("userId"), ctx.Param("orderId"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,227 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aniket0951/order-services/helper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOrderV2Routes(t *testing.T) {
+	a := newTestApp(t)
+
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders", validOrderBody())
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d, body %s", rec.Code, rec.Body.String())
+	}
+	orderId := envelope[helper.ORDER_DATA].(map[string]interface{})["id"].(string)
+	if rec.Header().Get("Location") != "/api/v2/orders/"+orderId {
+		t.Fatalf("Location = %q", rec.Header().Get("Location"))
+	}
+	if rec.Header().Get("Deprecation") != "" {
+		t.Fatal("v2 routes must not be marked deprecated")
+	}
+	payOrder(t, a, orderId)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       interface{}
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "get order", method: http.MethodGet, target: "/api/v2/orders/" + orderId, wantCode: http.StatusOK, wantStatus: helper.PLACED},
+		{name: "get unknown order", method: http.MethodGet, target: "/api/v2/orders/" + primitive.NewObjectID().Hex(), wantCode: http.StatusNotFound},
+		{name: "get invalid id", method: http.MethodGet, target: "/api/v2/orders/abc", wantCode: http.StatusUnprocessableEntity},
+		{name: "status with unknown value", method: http.MethodPut, target: "/api/v2/orders/" + orderId + "/status", body: map[string]string{"order_status": "LOST"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "dispatch", method: http.MethodPut, target: "/api/v2/orders/" + orderId + "/status", body: map[string]string{"order_status": helper.DISPATCHED}, wantCode: http.StatusOK},
+		{name: "cancel dispatched order", method: http.MethodPost, target: "/api/v2/orders/" + orderId + "/cancel", wantCode: http.StatusUnprocessableEntity},
+		{name: "cancel unknown order", method: http.MethodPost, target: "/api/v2/orders/" + primitive.NewObjectID().Hex() + "/cancel", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := doRequest(t, a.Router, tt.method, tt.target, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantStatus != "" {
+				order := envelope[helper.ORDER_DATA].(map[string]interface{})
+				if order["order_status"] != tt.wantStatus {
+					t.Fatalf("order_status = %v, want %s", order["order_status"], tt.wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestPartialOrderRoutes(t *testing.T) {
+	a := newTestApp(t)
+	placed := placeOrder(t, a)
+	orderPath := "/api/v2/orders/" + placed.Id.Hex()
+
+	tests := []struct {
+		name      string
+		target    string
+		body      interface{}
+		wantCode  int
+		wantOrder map[string]interface{}
+	}{
+		{name: "ship nothing", target: orderPath + "/shipments", body: map[string]int{"quantity": 0}, wantCode: http.StatusUnprocessableEntity},
+		{name: "ship one unit", target: orderPath + "/shipments", body: map[string]int{"quantity": 1}, wantCode: http.StatusOK,
+			wantOrder: map[string]interface{}{"order_status": helper.DISPATCHED, "shipped_quantity": 1.0, "backordered_quantity": 1.0}},
+		{name: "cancel more than is unshipped", target: orderPath + "/cancel", body: map[string]int{"quantity": 2}, wantCode: http.StatusUnprocessableEntity},
+		{name: "negative quantity", target: orderPath + "/cancel", body: map[string]int{"quantity": -1}, wantCode: http.StatusUnprocessableEntity},
+		{name: "cancel the backordered unit", target: orderPath + "/cancel", body: map[string]int{"quantity": 1}, wantCode: http.StatusOK,
+			wantOrder: map[string]interface{}{"order_status": helper.DISPATCHED, "quantity": 1.0, "total_price": 99.0, "cancelled_quantity": 1.0, "backordered_quantity": 0.0}},
+		{name: "ship an unknown order", target: "/api/v2/orders/" + primitive.NewObjectID().Hex() + "/shipments", body: map[string]int{"quantity": 1}, wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := doRequest(t, a.Router, http.MethodPost, tt.target, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			order, _ := envelope[helper.ORDER_DATA].(map[string]interface{})
+			for key, want := range tt.wantOrder {
+				if order[key] != want {
+					t.Fatalf("%s = %v, want %v, order %v", key, order[key], want, order)
+				}
+			}
+		})
+	}
+}
+
+func TestEditOrderRoute(t *testing.T) {
+	a := newTestApp(t)
+	placed := placeOrder(t, a)
+	orderPath := "/api/v2/orders/" + placed.Id.Hex()
+
+	tests := []struct {
+		name      string
+		target    string
+		body      interface{}
+		wantCode  int
+		wantOrder map[string]interface{}
+	}{
+		{name: "empty body", target: orderPath, wantCode: http.StatusBadRequest},
+		{name: "invalid variant", target: orderPath, body: map[string]interface{}{"prod_selling_id": "abc"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "more than was paid", target: orderPath, body: map[string]interface{}{"quantity": 3}, wantCode: http.StatusUnprocessableEntity},
+		{name: "fewer units", target: orderPath, body: map[string]interface{}{"quantity": 1}, wantCode: http.StatusOK,
+			wantOrder: map[string]interface{}{"order_status": helper.PLACED, "quantity": 1.0, "total_price": 99.0}},
+		{name: "unknown order", target: "/api/v2/orders/" + primitive.NewObjectID().Hex(), body: map[string]interface{}{"quantity": 1}, wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := doRequest(t, a.Router, http.MethodPatch, tt.target, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			order, _ := envelope[helper.ORDER_DATA].(map[string]interface{})
+			for key, want := range tt.wantOrder {
+				if order[key] != want {
+					t.Fatalf("%s = %v, want %v, order %v", key, order[key], want, order)
+				}
+			}
+		})
+	}
+}
+
+func TestReorderRoute(t *testing.T) {
+	a := newTestApp(t)
+	placed := placeOrder(t, a)
+	reorderPath := "/api/v2/orders/" + placed.Id.Hex() + "/reorder"
+
+	tests := []struct {
+		name     string
+		target   string
+		body     interface{}
+		wantCode int
+	}{
+		{name: "missing mode", target: reorderPath, wantCode: http.StatusUnprocessableEntity},
+		{name: "unknown mode", target: reorderPath, body: map[string]string{"mode": "WISHLIST"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "unknown order", target: "/api/v2/orders/" + primitive.NewObjectID().Hex() + "/reorder", body: map[string]string{"mode": "ORDER"}, wantCode: http.StatusNotFound},
+		{name: "as a new order", target: reorderPath, body: map[string]string{"mode": "ORDER"}, wantCode: http.StatusCreated},
+		{name: "into the cart", target: reorderPath, body: map[string]string{"mode": "CART"}, wantCode: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := doRequest(t, a.Router, http.MethodPost, tt.target, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusCreated {
+				return
+			}
+			reorder := envelope[helper.REORDER_DATA].(map[string]interface{})
+			orders := reorder["orders"].([]interface{})
+			if reorder["source_order_id"] != placed.Id.Hex() || len(orders) != 1 || orders[0].(map[string]interface{})["total_price"] != 198.0 {
+				t.Fatalf("reorder = %v", reorder)
+			}
+		})
+	}
+}
+
+func TestCartV2Routes(t *testing.T) {
+	a := newTestApp(t)
+	userId := primitive.NewObjectID().Hex()
+	itemsPath := "/api/v2/carts/" + userId + "/items"
+
+	body := validOrderBody()
+	body.UserId = ""
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, itemsPath, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add = %d, body %s", rec.Code, rec.Body.String())
+	}
+	added := envelope[helper.ORDER_DATA].(map[string]interface{})
+	if added["user_id"] != userId || added["order_status"] != helper.CART {
+		t.Fatalf("added order = %v", added)
+	}
+	orderId := added["id"].(string)
+
+	rec, envelope = doRequest(t, a.Router, http.MethodGet, itemsPath, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list = %d", rec.Code)
+	}
+	if items := envelope[helper.ORDER_DATA].([]interface{}); len(items) != 1 {
+		t.Fatalf("cart items = %v", items)
+	}
+
+	otherUser := "/api/v2/carts/" + primitive.NewObjectID().Hex() + "/items/" + orderId
+	if rec, _ := doRequest(t, a.Router, http.MethodDelete, otherUser, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("removing another user's item = %d, want 404", rec.Code)
+	}
+
+	if rec, _ := doRequest(t, a.Router, http.MethodDelete, itemsPath+"/"+orderId, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("remove = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if items, _ := a.OrderService.GetCartItems(context.Background(), userId); len(items) != 0 {
+		t.Fatalf("cart after remove = %v", items)
+	}
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	a := newTestApp(t)
+
+	rec, _ := doRequest(t, a.Router, http.MethodPost, "/api/order/place-single-order", validOrderBody())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("legacy place = %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Deprecation"), "@") {
+		t.Fatalf("Deprecation = %q", rec.Header().Get("Deprecation"))
+	}
+	if rec.Header().Get("Sunset") == "" {
+		t.Fatal("missing Sunset header")
+	}
+	if !strings.Contains(rec.Header().Get("Link"), `rel="successor-version"`) {
+		t.Fatalf("Link = %q", rec.Header().Get("Link"))
+	}
+}
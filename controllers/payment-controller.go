@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/payments"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+)
+
+// callback bodies larger than this are rejected unread
+const MAX_CALLBACK_BYTES = 64 << 10
+
+type PaymentControllers interface {
+	Checkout(*gin.Context)
+	ListPayments(*gin.Context)
+	ListRefunds(*gin.Context)
+	RetryRefund(*gin.Context)
+	Callback(*gin.Context)
+}
+
+type paymentControllers struct {
+	paymentService services.PaymentService
+	validator      *helper.RequestValidator
+}
+
+func NewPaymentControllers(paymentService services.PaymentService, validator *helper.RequestValidator) PaymentControllers {
+	return &paymentControllers{
+		paymentService: paymentService,
+		validator:      validator,
+	}
+}
+
+// POST /orders/:id/payments, payment intent for an order awaiting payment
+func (c *paymentControllers) Checkout(ctx *gin.Context) {
+	payment, err := c.paymentService.Checkout(ctx.Request.Context(), ctx.Param("id"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("payment intent created", payment, helper.PAYMENT_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GET /orders/:id/payments
+func (c *paymentControllers) ListPayments(ctx *gin.Context) {
+	attempts, err := c.paymentService.GetOrderPayments(ctx.Request.Context(), ctx.Param("id"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.FETCHED_SUCCESS, attempts, helper.PAYMENT_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GET /orders/:id/refunds, refunds of the order with their settlement status
+func (c *paymentControllers) ListRefunds(ctx *gin.Context) {
+	refunds, err := c.paymentService.GetOrderRefunds(ctx.Request.Context(), ctx.Param("id"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.FETCHED_SUCCESS, refunds, helper.REFUND_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /refunds/:refundId/retry, an operator queues a refund the processor gave up on
+func (c *paymentControllers) RetryRefund(ctx *gin.Context) {
+	retry := dto.RetryRefundDTO{}
+	_ = ctx.ShouldBindJSON(&retry)
+
+	if c.validator.CheckValidation(&retry, ctx) {
+		return
+	}
+
+	refund, err := c.paymentService.RetryRefund(ctx.Request.Context(), ctx.Param("refundId"), retry.Operator)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("refund has been queued again", refund, helper.REFUND_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /payments/callback, called by the gateway with the signed outcome of an intent
+func (c *paymentControllers) Callback(ctx *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, MAX_CALLBACK_BYTES))
+	if err != nil {
+		helper.RequestBodyEmptyResponse(ctx)
+		return
+	}
+
+	payment, err := c.paymentService.HandleCallback(ctx.Request.Context(), payload, ctx.GetHeader(payments.SIGNATURE_HEADER))
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		helper.BuildUnauthorizedResponse(ctx, err)
+		return
+	}
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, payment, helper.PAYMENT_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,175 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aniket0951/order-services/app"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/payments"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// post a gateway callback signed with the configured webhook secret
+func sendCallback(t *testing.T, a *app.App, intentId, status, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	payload, _ := json.Marshal(payments.CallbackEvent{IntentId: intentId, Status: status})
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/payments/callback", bytes.NewReader(payload))
+	req.Header.Set(payments.SIGNATURE_HEADER, payments.NewMockGateway(secret).Sign(payload))
+	rec := httptest.NewRecorder()
+	a.Router.ServeHTTP(rec, req)
+	return rec
+}
+
+func checkout(t *testing.T, a *app.App, orderId string) map[string]interface{} {
+	t.Helper()
+
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders/"+orderId+"/payments", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("checkout = %d, body %s", rec.Code, rec.Body.String())
+	}
+	return envelope[helper.PAYMENT_DATA].(map[string]interface{})
+}
+
+// pay the order through checkout and a successful gateway callback
+func payOrder(t *testing.T, a *app.App, orderId string) {
+	t.Helper()
+
+	payment := checkout(t, a, orderId)
+	rec := sendCallback(t, a, payment["intent_id"].(string), payments.CALLBACK_SUCCEEDED, a.Config.Payments.WebhookSecret)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback = %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPaymentRoutes(t *testing.T) {
+	a := newTestApp(t)
+	secret := a.Config.Payments.WebhookSecret
+
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders", validOrderBody())
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d", rec.Code)
+	}
+	order := envelope[helper.ORDER_DATA].(map[string]interface{})
+	orderId := order["id"].(string)
+	if order["order_status"] != helper.PENDING_PAYMENT {
+		t.Fatalf("new order status = %v", order["order_status"])
+	}
+
+	dispatch := map[string]string{"order_status": helper.DISPATCHED}
+	if rec, _ := doRequest(t, a.Router, http.MethodPut, "/api/v2/orders/"+orderId+"/status", dispatch); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("dispatching an unpaid order = %d, want 422", rec.Code)
+	}
+
+	payment := checkout(t, a, orderId)
+	if payment["status"] != helper.PAYMENT_PENDING || payment["client_secret"] == "" || payment["amount"] != order["total_price"] {
+		t.Fatalf("payment = %v", payment)
+	}
+	intentId := payment["intent_id"].(string)
+
+	tests := []struct {
+		name     string
+		intentId string
+		secret   string
+		wantCode int
+	}{
+		{name: "bad signature", intentId: intentId, secret: "wrong", wantCode: http.StatusUnauthorized},
+		{name: "unknown intent", intentId: "pi_unknown", secret: secret, wantCode: http.StatusNotFound},
+		{name: "success", intentId: intentId, secret: secret, wantCode: http.StatusOK},
+		{name: "redelivered", intentId: intentId, secret: secret, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendCallback(t, a, tt.intentId, payments.CALLBACK_SUCCEEDED, tt.secret)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("callback = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+		})
+	}
+
+	rec, envelope = doRequest(t, a.Router, http.MethodGet, "/api/v2/orders/"+orderId+"/payments", nil)
+	attempts, _ := envelope[helper.PAYMENT_DATA].([]interface{})
+	if rec.Code != http.StatusOK || len(attempts) != 1 || attempts[0].(map[string]interface{})["status"] != helper.PAYMENT_SUCCEEDED {
+		t.Fatalf("list = %d, %v", rec.Code, envelope)
+	}
+	if _, ok := attempts[0].(map[string]interface{})["client_secret"]; ok {
+		t.Fatal("client secret must only be returned by checkout")
+	}
+
+	if rec, _ := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders/"+orderId+"/payments", nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("checkout of a paid order = %d, want 422", rec.Code)
+	}
+	if rec, _ := doRequest(t, a.Router, http.MethodPut, "/api/v2/orders/"+orderId+"/status", dispatch); rec.Code != http.StatusOK {
+		t.Fatalf("dispatching a paid order = %d", rec.Code)
+	}
+	if rec, _ := doRequest(t, a.Router, http.MethodGet, "/api/v2/orders/"+primitive.NewObjectID().Hex()+"/payments", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("payments of an unknown order = %d, want 404", rec.Code)
+	}
+}
+
+func TestRefundRoutes(t *testing.T) {
+	a := newTestApp(t)
+	placed := placeOrder(t, a)
+	refundsURL := "/api/v2/orders/" + placed.Id.Hex() + "/refunds"
+
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders/"+placed.Id.Hex()+"/cancel", nil)
+	if rec.Code != http.StatusOK || envelope[helper.ORDER_DATA].(map[string]interface{})["order_status"] != helper.REFUND_PENDING {
+		t.Fatalf("cancel = %d, %v", rec.Code, envelope)
+	}
+
+	rec, envelope = doRequest(t, a.Router, http.MethodGet, refundsURL, nil)
+	refunds, _ := envelope[helper.REFUND_DATA].([]interface{})
+	if rec.Code != http.StatusOK || len(refunds) != 1 {
+		t.Fatalf("refunds = %d, %v", rec.Code, envelope)
+	}
+	refund := refunds[0].(map[string]interface{})
+	if refund["status"] != helper.PAYMENT_PENDING || refund["kind"] != helper.REFUND_FULL || refund["amount"] != placed.TotalPrice {
+		t.Fatalf("refund = %v", refund)
+	}
+
+	if rec, _ := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders/"+placed.Id.Hex()+"/cancel", nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("cancel twice = %d, want 422", rec.Code)
+	}
+
+	retryURL := "/api/v2/refunds/" + refund["id"].(string) + "/retry"
+	retries := []struct {
+		name     string
+		target   string
+		body     interface{}
+		wantCode int
+	}{
+		{name: "no operator", target: retryURL, wantCode: http.StatusUnprocessableEntity},
+		{name: "refund still pending", target: retryURL, body: map[string]string{"operator": "ops"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "unknown refund", target: "/api/v2/refunds/" + primitive.NewObjectID().Hex() + "/retry", body: map[string]string{"operator": "ops"}, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range retries {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec, _ := doRequest(t, a.Router, http.MethodPost, tt.target, tt.body); rec.Code != tt.wantCode {
+				t.Fatalf("retry = %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+
+	if _, err := a.PaymentService.ProcessDueRefunds(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, envelope = doRequest(t, a.Router, http.MethodGet, refundsURL, nil)
+	if refund := envelope[helper.REFUND_DATA].([]interface{})[0].(map[string]interface{}); refund["status"] != helper.PAYMENT_SUCCEEDED {
+		t.Fatalf("settled refund = %v", refund)
+	}
+	_, envelope = doRequest(t, a.Router, http.MethodGet, "/api/v2/orders/"+placed.Id.Hex(), nil)
+	if status := envelope[helper.ORDER_DATA].(map[string]interface{})["order_status"]; status != helper.REFUNDED {
+		t.Fatalf("order status = %v, want REFUNDED", status)
+	}
+
+	if rec, _ := doRequest(t, a.Router, http.MethodGet, "/api/v2/orders/"+primitive.NewObjectID().Hex()+"/refunds", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("refunds of an unknown order = %d, want 404", rec.Code)
+	}
+}
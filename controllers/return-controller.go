@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ReturnControllers customer return requests and the operator steps that settle them
+type ReturnControllers interface {
+	RequestReturn(*gin.Context)
+	ListOrderReturns(*gin.Context)
+	GetReturn(*gin.Context)
+	ReviewReturn(*gin.Context)
+	SchedulePickup(*gin.Context)
+	InspectReturn(*gin.Context)
+	RetryRefund(*gin.Context)
+}
+
+type returnControllers struct {
+	returnService services.ReturnService
+	validator     *helper.RequestValidator
+}
+
+func NewReturnControllers(returnService services.ReturnService, validator *helper.RequestValidator) ReturnControllers {
+	return &returnControllers{
+		returnService: returnService,
+		validator:     validator,
+	}
+}
+
+// POST /orders/:id/returns
+func (c *returnControllers) RequestReturn(ctx *gin.Context) {
+	request := dto.CreateReturnDTO{}
+	_ = ctx.ShouldBindJSON(&request)
+
+	if c.validator.CheckValidation(&request, ctx) {
+		return
+	}
+
+	ret, err := c.returnService.RequestReturn(ctx.Request.Context(), ctx.Param("id"), request)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	ctx.Header("Location", "/api/v2/returns/"+ret.Id.Hex())
+	response := helper.BuildSuccessResponse("return has been requested", ret, helper.RETURN_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GET /orders/:id/returns
+func (c *returnControllers) ListOrderReturns(ctx *gin.Context) {
+	returns, err := c.returnService.GetOrderReturns(ctx.Request.Context(), ctx.Param("id"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.FETCHED_SUCCESS, returns, helper.RETURN_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GET /returns/:returnId, the return with its timeline
+func (c *returnControllers) GetReturn(ctx *gin.Context) {
+	ret, err := c.returnService.GetReturn(ctx.Request.Context(), ctx.Param("returnId"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.DATA_FOUND, ret, helper.RETURN_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /returns/:returnId/review, approve or reject a requested return
+func (c *returnControllers) ReviewReturn(ctx *gin.Context) {
+	review := dto.ReviewReturnDTO{}
+	_ = ctx.ShouldBindJSON(&review)
+
+	if c.validator.CheckValidation(&review, ctx) {
+		return
+	}
+
+	ret, err := c.returnService.ReviewReturn(ctx.Request.Context(), ctx.Param("returnId"), review)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, ret, helper.RETURN_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /returns/:returnId/pickup
+func (c *returnControllers) SchedulePickup(ctx *gin.Context) {
+	pickup := dto.SchedulePickupDTO{}
+	_ = ctx.ShouldBindJSON(&pickup)
+
+	if c.validator.CheckValidation(&pickup, ctx) {
+		return
+	}
+
+	ret, err := c.returnService.SchedulePickup(ctx.Request.Context(), ctx.Param("returnId"), pickup)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, ret, helper.RETURN_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /returns/:returnId/inspection, a passed inspection restocks and refunds the return
+func (c *returnControllers) InspectReturn(ctx *gin.Context) {
+	inspection := dto.ReturnInspectionDTO{}
+	_ = ctx.ShouldBindJSON(&inspection)
+
+	if c.validator.CheckValidation(&inspection, ctx) {
+		return
+	}
+
+	ret, err := c.returnService.InspectReturn(ctx.Request.Context(), ctx.Param("returnId"), inspection)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, ret, helper.RETURN_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /returns/:returnId/refund, request again the refund of a REFUND_FAILED return
+func (c *returnControllers) RetryRefund(ctx *gin.Context) {
+	retry := dto.RetryReturnRefundDTO{}
+	_ = ctx.ShouldBindJSON(&retry)
+
+	if c.validator.CheckValidation(&retry, ctx) {
+		return
+	}
+
+	ret, err := c.returnService.RetryRefund(ctx.Request.Context(), ctx.Param("returnId"), retry)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.UPDATE_SUCCESS, ret, helper.RETURN_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,76 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/helper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReturnRoutes(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+
+	placed := placeOrder(t, a)
+	for _, status := range []string{helper.DISPATCHED, helper.COMPLETED} {
+		if err := a.OrderService.UpdateOrderStatus(ctx, placed.Id.Hex(), status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	open := placeOrder(t, a)
+
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, "/api/v2/orders/"+placed.Id.Hex()+"/returns",
+		map[string]interface{}{"quantity": 1, "reason": "DAMAGED", "comment": "spine broken"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %v", rec.Code, envelope)
+	}
+	returnId := envelope[helper.RETURN_DATA].(map[string]interface{})["id"].(string)
+	if rec.Header().Get("Location") != "/api/v2/returns/"+returnId {
+		t.Fatalf("location = %q", rec.Header().Get("Location"))
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		body       interface{}
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "unknown reason", method: http.MethodPost, target: "/api/v2/orders/" + placed.Id.Hex() + "/returns", body: map[string]interface{}{"quantity": 1, "reason": "BORED"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "order not completed", method: http.MethodPost, target: "/api/v2/orders/" + open.Id.Hex() + "/returns", body: map[string]interface{}{"quantity": 1, "reason": "OTHER"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "unknown order", method: http.MethodPost, target: "/api/v2/orders/" + primitive.NewObjectID().Hex() + "/returns", body: map[string]interface{}{"quantity": 1, "reason": "OTHER"}, wantCode: http.StatusNotFound},
+		{name: "unknown return", method: http.MethodGet, target: "/api/v2/returns/" + primitive.NewObjectID().Hex(), wantCode: http.StatusNotFound},
+		{name: "invalid decision", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/review", body: map[string]interface{}{"decision": "MAYBE", "operator": "ops"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "inspect before pickup", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/inspection", body: map[string]interface{}{"passed": true, "operator": "warehouse"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "approve", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/review", body: map[string]interface{}{"decision": "APPROVE", "operator": "ops"}, wantCode: http.StatusOK, wantStatus: helper.RETURN_APPROVED},
+		{name: "schedule pickup", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/pickup", body: map[string]interface{}{"pickup_at": time.Now().Add(time.Hour), "operator": "ops"}, wantCode: http.StatusOK, wantStatus: helper.RETURN_PICKUP_SCHEDULED},
+		{name: "inspection without a verdict", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/inspection", body: map[string]interface{}{"operator": "warehouse"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "inspect", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/inspection", body: map[string]interface{}{"passed": true, "restock_quantity": 1, "operator": "warehouse"}, wantCode: http.StatusOK, wantStatus: helper.RETURN_COMPLETED},
+		{name: "retry refund of a completed return", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/refund", body: map[string]interface{}{"operator": "ops"}, wantCode: http.StatusUnprocessableEntity},
+		{name: "retry refund without an operator", method: http.MethodPost, target: "/api/v2/returns/" + returnId + "/refund", body: map[string]interface{}{}, wantCode: http.StatusUnprocessableEntity},
+		{name: "get", method: http.MethodGet, target: "/api/v2/returns/" + returnId, wantCode: http.StatusOK, wantStatus: helper.RETURN_COMPLETED},
+	}
+
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := doRequest(t, a.Router, tt.method, tt.target, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body %v", rec.Code, tt.wantCode, envelope)
+			}
+			if tt.wantStatus != "" {
+				if ret := envelope[helper.RETURN_DATA].(map[string]interface{}); ret["status"] != tt.wantStatus {
+					t.Fatalf("return = %v, want %s", ret, tt.wantStatus)
+				}
+			}
+		})
+	}
+
+	rec, envelope = doRequest(t, a.Router, http.MethodGet, "/api/v2/orders/"+placed.Id.Hex()+"/returns", nil)
+	if list, _ := envelope[helper.RETURN_DATA].([]interface{}); rec.Code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("status = %d, body %v", rec.Code, envelope)
+	}
+}
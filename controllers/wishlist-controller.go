@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/services"
+	"github.com/gin-gonic/gin"
+)
+
+// WishlistControllers save-for-later items of a user and moves between them and the cart
+type WishlistControllers interface {
+	ListWishlist(*gin.Context)
+	SaveForLater(*gin.Context)
+	MoveToCart(*gin.Context)
+	RemoveWishlistItem(*gin.Context)
+}
+
+type wishlistControllers struct {
+	wishlistService services.WishlistService
+	validator       *helper.RequestValidator
+}
+
+func NewWishlistControllers(wishlistService services.WishlistService, validator *helper.RequestValidator) WishlistControllers {
+	return &wishlistControllers{
+		wishlistService: wishlistService,
+		validator:       validator,
+	}
+}
+
+// GET /wishlists/:userId/items
+func (c *wishlistControllers) ListWishlist(ctx *gin.Context) {
+	items, err := c.wishlistService.GetWishlist(ctx.Request.Context(), ctx.Param("userId"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse(helper.FETCHED_SUCCESS, items, helper.WISHLIST_DATA)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /wishlists/:userId/items, move a cart line of the user into the wishlist
+func (c *wishlistControllers) SaveForLater(ctx *gin.Context) {
+	request := dto.SaveForLaterDTO{}
+	_ = ctx.ShouldBindJSON(&request)
+
+	if c.validator.CheckValidation(&request, ctx) {
+		return
+	}
+
+	item, err := c.wishlistService.SaveForLater(ctx.Request.Context(), ctx.Param("userId"), request.OrderId)
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("item has been saved for later", item, helper.WISHLIST_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// POST /wishlists/:userId/items/:itemId/move-to-cart, the cart line takes the current price
+func (c *wishlistControllers) MoveToCart(ctx *gin.Context) {
+	line, err := c.wishlistService.MoveToCart(ctx.Request.Context(), ctx.Param("userId"), ctx.Param("itemId"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	response := helper.BuildSuccessResponse("item has been moved into cart", line, helper.ORDER_DATA)
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// DELETE /wishlists/:userId/items/:itemId
+func (c *wishlistControllers) RemoveWishlistItem(ctx *gin.Context) {
+	err := c.wishlistService.RemoveWishlistItem(ctx.Request.Context(), ctx.Param("userId"), ctx.Param("itemId"))
+	if checkResourceError(err, ctx) {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aniket0951/order-services/helper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWishlistRoutes(t *testing.T) {
+	a := newTestApp(t)
+	userId := primitive.NewObjectID().Hex()
+	itemsPath := "/api/v2/wishlists/" + userId + "/items"
+
+	// the stub product service sells everything at 99
+	body := validOrderBody()
+	body.Price = 120
+	rec, envelope := doRequest(t, a.Router, http.MethodPost, "/api/v2/carts/"+userId+"/items", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add to cart = %d, body %s", rec.Code, rec.Body.String())
+	}
+	cartLineId := envelope[helper.ORDER_DATA].(map[string]interface{})["id"].(string)
+
+	if rec, _ := doRequest(t, a.Router, http.MethodPost, itemsPath, map[string]string{}); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("save without order_id = %d, want 422", rec.Code)
+	}
+	otherUser := "/api/v2/wishlists/" + primitive.NewObjectID().Hex() + "/items"
+	if rec, _ := doRequest(t, a.Router, http.MethodPost, otherUser, map[string]string{"order_id": cartLineId}); rec.Code != http.StatusNotFound {
+		t.Fatalf("saving another user's cart line = %d, want 404", rec.Code)
+	}
+
+	rec, envelope = doRequest(t, a.Router, http.MethodPost, itemsPath, map[string]string{"order_id": cartLineId})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("save for later = %d, body %s", rec.Code, rec.Body.String())
+	}
+	itemId := envelope[helper.WISHLIST_DATA].(map[string]interface{})["id"].(string)
+
+	rec, envelope = doRequest(t, a.Router, http.MethodGet, itemsPath, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list = %d", rec.Code)
+	}
+	items := envelope[helper.WISHLIST_DATA].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("wishlist = %v", items)
+	}
+	if item := items[0].(map[string]interface{}); item["saved_price"] != 120.0 || item["current_price"] != 99.0 || item["price_drop"] != 21.0 {
+		t.Fatalf("wishlist item = %v", item)
+	}
+
+	rec, envelope = doRequest(t, a.Router, http.MethodPost, itemsPath+"/"+itemId+"/move-to-cart", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("move to cart = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if line := envelope[helper.ORDER_DATA].(map[string]interface{}); line["order_status"] != helper.CART || line["total_price"] != 198.0 {
+		t.Fatalf("cart line = %v", line)
+	}
+
+	if rec, _ := doRequest(t, a.Router, http.MethodDelete, itemsPath+"/"+itemId, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("removing a moved item = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,10 @@
+package docs
+
+import (
+	_ "embed"
+)
+
+// OpenAPI the service's OpenAPI 3 document, keep it in step with the routers
+//
+//go:embed openapi.json
+var OpenAPI []byte
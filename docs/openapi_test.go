@@ -0,0 +1,68 @@
+package docs_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aniket0951/order-services/app"
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/docs"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+type stubInventory struct{}
+
+func (stubInventory) UpdateProductCount(ctx context.Context, tag string, num string, productId string) error {
+	return nil
+}
+
+func (stubInventory) GetProduct(ctx context.Context, productSellingId string) (clients.ProductInfo, error) {
+	return clients.ProductInfo{ProductSellingID: productSellingId, Price: 99, Available: 100}, nil
+}
+
+func (stubInventory) Ping(ctx context.Context) error { return nil }
+
+var ginParam = regexp.MustCompile(`[:*](\w+)`)
+
+// gin /orders/:id becomes openapi /orders/{id}
+func openAPIPath(ginPath string) string {
+	return ginParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+func TestEveryRouteHasSpecEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(docs.OpenAPI, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid json: %v", err)
+	}
+
+	a := app.Build(config.Default(), app.Dependencies{
+		OrderRepo: repositories.NewMemoryOrderRepository(),
+		Inventory: stubInventory{},
+	})
+
+	for _, route := range a.Router.Routes() {
+		// static assets are files, not api operations
+		if strings.HasPrefix(route.Path, "/static/") {
+			continue
+		}
+
+		path := openAPIPath(route.Path)
+		operations, ok := spec.Paths[path]
+		if !ok {
+			t.Errorf("route %s %s has no path entry %s in docs/openapi.json", route.Method, route.Path, path)
+			continue
+		}
+		if _, ok := operations[strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s has no %s operation in docs/openapi.json", route.Method, route.Path, strings.ToLower(route.Method))
+		}
+	}
+}
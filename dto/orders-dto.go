@@ -1,15 +1,44 @@
 package dto
 
 type CreateOrderDTO struct {
-	ProductId        string `json:"prod_id" validate:"required"`
-	Category         string `json:"category" validate:"required"`
-	ProductSellingID string `json:"prod_selling_id" validate:"required"`
-	Quantity         int64  `json:"quantity" validate:"required"`
+	ProductId        string `json:"prod_id" validate:"required,objectid"`
+	Category         string `json:"category" validate:"required,category"`
+	ProductSellingID string `json:"prod_selling_id" validate:"required,objectid"`
+	Quantity         int64  `json:"quantity" validate:"required,gt=0"`
 	Price            int64  `json:"price" validate:"required"`
-	UserId           string `json:"user_id" validate:"required"`
+	UserId           string `json:"user_id" validate:"required,objectid"`
 }
 
 type UpdateOrderStatusDTO struct {
-	OrderId     string `json:"order_id" validate:"required"`
+	OrderId     string `json:"order_id" validate:"required,objectid"`
 	OrderStatus string `json:"order_status" validate:"required"`
 }
+
+// EditOrderDTO changes to an order before dispatch, zero values keep the current quantity and variant.
+// A new variant of the ordered product is charged at the price the product service sells it for
+type EditOrderDTO struct {
+	Quantity         int64  `json:"quantity" validate:"gte=0"`
+	ProductSellingID string `json:"prod_selling_id" validate:"omitempty,objectid"`
+}
+
+// ReorderDTO buy a previous order again as a new order or into the cart
+type ReorderDTO struct {
+	Mode string `json:"mode" validate:"required,oneof=ORDER CART"`
+}
+
+// CancelOrderDTO optional body of a cancellation, no quantity cancels the whole order
+type CancelOrderDTO struct {
+	Quantity int64 `json:"quantity" validate:"gte=0"`
+}
+
+// ShipOrderDTO units of the order sent out in one shipment
+type ShipOrderDTO struct {
+	Quantity int64 `json:"quantity" validate:"required,gt=0"`
+}
+
+// AbandonedCartsQuery filters of the abandoned cart report, zero values fall back to the configured policy
+type AbandonedCartsQuery struct {
+	IdleHours int64   `json:"idle_hours" form:"idle_hours" validate:"gte=0"`
+	MinValue  float64 `json:"min_value" form:"min_value" validate:"gte=0"`
+	Limit     int64   `json:"limit" form:"limit" validate:"gte=0,lte=500"`
+}
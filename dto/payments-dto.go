@@ -0,0 +1,6 @@
+package dto
+
+// RetryRefundDTO operator queueing a failed refund for the gateway again
+type RetryRefundDTO struct {
+	Operator string `json:"operator" validate:"required"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+type CreateReturnDTO struct {
+	Quantity int64  `json:"quantity" validate:"required,gt=0"`
+	Reason   string `json:"reason" validate:"required,return_reason"`
+	Comment  string `json:"comment" validate:"max=500"`
+}
+
+// ReviewReturnDTO an operator approving or rejecting a requested return
+type ReviewReturnDTO struct {
+	Decision string `json:"decision" validate:"required,oneof=APPROVE REJECT"`
+	Operator string `json:"operator" validate:"required"`
+	Note     string `json:"note" validate:"max=500"`
+}
+
+type SchedulePickupDTO struct {
+	PickupAt time.Time `json:"pickup_at" validate:"required"`
+	Operator string    `json:"operator" validate:"required"`
+	Note     string    `json:"note" validate:"max=500"`
+}
+
+// ReturnInspectionDTO outcome of checking the returned units, passed is required so false is explicit
+type ReturnInspectionDTO struct {
+	Passed          *bool  `json:"passed" validate:"required"`
+	RestockQuantity int64  `json:"restock_quantity" validate:"gte=0"`
+	Operator        string `json:"operator" validate:"required"`
+	Notes           string `json:"notes" validate:"max=500"`
+}
+
+// RetryReturnRefundDTO an operator asking for the refund of a return to be requested again
+type RetryReturnRefundDTO struct {
+	Operator string `json:"operator" validate:"required"`
+}
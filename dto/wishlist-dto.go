@@ -0,0 +1,6 @@
+package dto
+
+// SaveForLaterDTO cart line of the user to move into the wishlist
+type SaveForLaterDTO struct {
+	OrderId string `json:"order_id" validate:"required,objectid"`
+}
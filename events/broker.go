@@ -0,0 +1,103 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ORDER_STATUS_CHANGED event type published for every new order track entry
+const ORDER_STATUS_CHANGED = "order.status_changed"
+
+// CART_ABANDONED event type published once per abandoned cart
+const CART_ABANDONED = "cart.abandoned"
+
+// OrderEvent something that happened to an order, Track carries the new status.
+// Cart events leave Track empty and carry the abandoned cart instead
+type OrderEvent struct {
+	Type   string
+	UserId primitive.ObjectID
+	Track  models.OrderTrack
+	Cart   models.AbandonedCart
+}
+
+// Publisher accepts order events, publishing never blocks the caller
+type Publisher interface {
+	Publish(event OrderEvent)
+}
+
+// Broker in-process fan out of order events to subscribers
+type Broker struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	buffer int
+}
+
+// Subscription receives matching events on C until Close
+type Subscription struct {
+	C      <-chan OrderEvent
+	ch     chan OrderEvent
+	filter func(OrderEvent) bool
+	broker *Broker
+	once   sync.Once
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		subs:   map[*Subscription]struct{}{},
+		buffer: buffer,
+	}
+}
+
+// Subscribe events accepted by filter, nil filter receives everything
+func (b *Broker) Subscribe(filter func(OrderEvent) bool) *Subscription {
+	ch := make(chan OrderEvent, b.buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Publish deliver to every matching subscriber. A subscriber whose buffer is
+// full is closed rather than slowing the publisher down; it can resume from
+// the order track using the id of the last event it saw
+func (b *Broker) Publish(event OrderEvent) {
+	b.mu.RLock()
+	var slow []*Subscription
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.Close()
+	}
+}
+
+// Close stop receiving events, C is closed
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Subscribers number of open subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
@@ -0,0 +1,248 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	orderv1 "github.com/aniket0951/order-services/proto/order/v1"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// orderServer grpc front of OrderService, same rules as the http api
+type orderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+
+	orderService services.OrderService
+	broker       *events.Broker
+	validator    *helper.RequestValidator
+	// closed on shutdown so open watches end and graceful stop can complete
+	stopping <-chan struct{}
+}
+
+// NewOrderServer order api, stopping may be nil when watches never need to be cut short
+func NewOrderServer(orderService services.OrderService, broker *events.Broker, validator *helper.RequestValidator, stopping <-chan struct{}) orderv1.OrderServiceServer {
+	return &orderServer{
+		orderService: orderService,
+		broker:       broker,
+		validator:    validator,
+		stopping:     stopping,
+	}
+}
+
+// NotFound for unknown orders, FailedPrecondition for rejected requests,
+// like the 404 and 422 of the http api
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrOrderNotFound), errors.Is(err, services.ErrNotInCart):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+}
+
+// InvalidArgument listing every failed field
+func (s *orderServer) validate(obj interface{}) error {
+	fieldErrors, err := s.validator.ValidateStruct(obj)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		messages = append(messages, fe.Message)
+	}
+	return status.Error(codes.InvalidArgument, "invalid request: "+strings.Join(messages, "; "))
+}
+
+func validateId(name, id string) error {
+	if _, err := helper.ValidatePrimitiveId(id); err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s must be a valid object id", name)
+	}
+	return nil
+}
+
+func toProtoOrder(order models.Orders) *orderv1.Order {
+	return &orderv1.Order{
+		Id:               order.Id.Hex(),
+		ProductSellingId: order.ProductSellingID.Hex(),
+		ProductId:        order.ProductId.Hex(),
+		Quantity:         order.Quantity,
+		Category:         order.Category,
+		TotalPrice:       order.TotalPrice,
+		UserId:           order.UserId.Hex(),
+		OrderStatus:      order.OrderStatus,
+		CreatedAt:        toTimestamp(order.CreatedAt),
+		UpdatedAt:        toTimestamp(order.UpdatedAt),
+		StockNotUpdated:  order.StockNotUpdated,
+	}
+}
+
+func toProtoEvent(track models.OrderTrack) *orderv1.OrderStatusEvent {
+	event := &orderv1.OrderStatusEvent{
+		OrderId:     track.OrderId.Hex(),
+		OrderStatus: track.OrderStatus,
+		CreatedAt:   toTimestamp(track.CreatedAt),
+	}
+	if !track.Id.IsZero() {
+		event.Id = track.Id.Hex()
+	}
+	return event
+}
+
+func toTimestamp(t primitive.DateTime) *timestamppb.Timestamp {
+	return timestamppb.New(t.Time())
+}
+
+func (s *orderServer) PlaceOrder(ctx context.Context, req *orderv1.PlaceOrderRequest) (*orderv1.Order, error) {
+	order := dto.CreateOrderDTO{
+		ProductId:        req.GetProductId(),
+		Category:         req.GetCategory(),
+		ProductSellingID: req.GetProductSellingId(),
+		Quantity:         req.GetQuantity(),
+		Price:            req.GetPrice(),
+		UserId:           req.GetUserId(),
+	}
+	if err := s.validate(&order); err != nil {
+		return nil, err
+	}
+
+	placed, err := s.orderService.PlaceSingleOrder(ctx, order)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoOrder(placed), nil
+}
+
+func (s *orderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	if err := validateId("order_id", req.GetOrderId()); err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderService.GetOrder(ctx, req.GetOrderId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoOrder(order), nil
+}
+
+func (s *orderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	if err := validateId("user_id", req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	orders, err := s.orderService.ListOrders(ctx, req.GetUserId(), req.GetOrderStatus())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &orderv1.ListOrdersResponse{Orders: make([]*orderv1.Order, 0, len(orders))}
+	for _, order := range orders {
+		res.Orders = append(res.Orders, toProtoOrder(order))
+	}
+	return res, nil
+}
+
+func (s *orderServer) UpdateOrderStatus(ctx context.Context, req *orderv1.UpdateOrderStatusRequest) (*orderv1.Order, error) {
+	statusUpdate := dto.UpdateOrderStatusDTO{
+		OrderId:     req.GetOrderId(),
+		OrderStatus: req.GetOrderStatus(),
+	}
+	if err := s.validate(&statusUpdate); err != nil {
+		return nil, err
+	}
+
+	if statusUpdate.OrderStatus != helper.DISPATCHED && statusUpdate.OrderStatus != helper.COMPLETED {
+		return nil, status.Error(codes.InvalidArgument, "order_status must be DISPATCHED or COMPLETED")
+	}
+
+	if err := s.orderService.UpdateOrderStatus(ctx, statusUpdate.OrderId, statusUpdate.OrderStatus); err != nil {
+		return nil, toStatus(err)
+	}
+	return s.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: statusUpdate.OrderId})
+}
+
+func (s *orderServer) CancelOrder(ctx context.Context, req *orderv1.CancelOrderRequest) (*orderv1.Order, error) {
+	if err := validateId("order_id", req.GetOrderId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.orderService.CancelOrder(ctx, req.GetOrderId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return s.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: req.GetOrderId()})
+}
+
+func (s *orderServer) WatchOrder(req *orderv1.WatchOrderRequest, stream orderv1.OrderService_WatchOrderServer) error {
+	if err := validateId("order_id", req.GetOrderId()); err != nil {
+		return err
+	}
+	orderId, _ := helper.ConvertStringToPrimitive(req.GetOrderId())
+	ctx := stream.Context()
+
+	// subscribe before reading the current state so no change slips in between
+	sub := s.broker.Subscribe(func(event events.OrderEvent) bool {
+		return event.Track.OrderId == orderId
+	})
+	defer sub.Close()
+
+	order, err := s.orderService.GetOrder(ctx, req.GetOrderId())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	// orders that never changed status have no track entry yet
+	current := models.OrderTrack{OrderId: order.Id, OrderStatus: order.OrderStatus, CreatedAt: order.UpdatedAt}
+	tracks, err := s.orderService.GetOrderTrack(ctx, req.GetOrderId())
+	if err != nil {
+		return toStatus(err)
+	}
+	if len(tracks) > 0 {
+		current = tracks[len(tracks)-1]
+	}
+
+	if err := stream.Send(toProtoEvent(current)); err != nil {
+		return err
+	}
+	if helper.IsFinalStatus(current.OrderStatus) {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return toStatus(ctx.Err())
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "watch fell behind, resume with GetOrder")
+			}
+			// anything buffered up to the current state was already covered by it
+			if bytes.Compare(event.Track.Id[:], current.Id[:]) <= 0 {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(event.Track)); err != nil {
+				return err
+			}
+			if helper.IsFinalStatus(event.Track.OrderStatus) {
+				return nil
+			}
+		}
+	}
+}
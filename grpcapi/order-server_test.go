@@ -0,0 +1,255 @@
+package grpcapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/app"
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/grpcapi"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/payments"
+	orderv1 "github.com/aniket0951/order-services/proto/order/v1"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type stubInventory struct{}
+
+func (stubInventory) UpdateProductCount(ctx context.Context, tag string, num string, productId string) error {
+	return nil
+}
+
+func (stubInventory) GetProduct(ctx context.Context, productSellingId string) (clients.ProductInfo, error) {
+	return clients.ProductInfo{ProductSellingID: productSellingId, Price: 99, Available: 100}, nil
+}
+
+func (stubInventory) Ping(ctx context.Context) error { return nil }
+
+// product service that takes no stock updates
+type downInventory struct{ stubInventory }
+
+func (downInventory) UpdateProductCount(ctx context.Context, tag string, num string, productId string) error {
+	return errors.New("product service unavailable")
+}
+
+// in-process client talking to the order api over a bufconn listener
+func newTestClient(t *testing.T) (orderv1.OrderServiceClient, *app.App) {
+	return newTestClientWith(t, stubInventory{})
+}
+
+func newTestClientWith(t *testing.T, inventory clients.InventoryClient) (orderv1.OrderServiceClient, *app.App) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Payments.WebhookSecret = "test-webhook-secret"
+	a := app.Build(cfg, app.Dependencies{
+		OrderRepo: repositories.NewMemoryOrderRepository(),
+		Inventory: inventory,
+	})
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	orderv1.RegisterOrderServiceServer(server, grpcapi.NewOrderServer(a.OrderService, a.Broker, helper.NewRequestValidator(cfg.Order.Categories), nil))
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return orderv1.NewOrderServiceClient(conn), a
+}
+
+// settle the order's payment the way the gateway callback does
+func payOrder(t *testing.T, a *app.App, orderId string) {
+	t.Helper()
+	ctx := context.Background()
+
+	payment, err := a.PaymentService.Checkout(ctx, orderId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := json.Marshal(payments.CallbackEvent{IntentId: payment.IntentId, Status: payments.CALLBACK_SUCCEEDED})
+	signature := payments.NewMockGateway(a.Config.Payments.WebhookSecret).Sign(payload)
+	if _, err := a.PaymentService.HandleCallback(ctx, payload, signature); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func validPlaceOrder() *orderv1.PlaceOrderRequest {
+	return &orderv1.PlaceOrderRequest{
+		ProductId:        primitive.NewObjectID().Hex(),
+		Category:         "BOOKS",
+		ProductSellingId: primitive.NewObjectID().Hex(),
+		Quantity:         2,
+		Price:            99,
+		UserId:           primitive.NewObjectID().Hex(),
+	}
+}
+
+func TestPlaceOrderStockNotUpdated(t *testing.T) {
+	client, _ := newTestClientWith(t, downInventory{})
+	ctx := context.Background()
+
+	placed, err := client.PlaceOrder(ctx, validPlaceOrder())
+	if err != nil || !placed.GetStockNotUpdated() || placed.GetOrderStatus() != helper.PENDING_PAYMENT {
+		t.Fatalf("PlaceOrder = %+v, %v", placed, err)
+	}
+	if got, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: placed.GetId()}); err != nil || got.GetStockNotUpdated() {
+		t.Fatalf("GetOrder = %+v, %v", got, err)
+	}
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Fatalf("code = %s, want %s (err %v)", got, code, err)
+	}
+}
+
+func TestOrderServerUnary(t *testing.T) {
+	client, a := newTestClient(t)
+	ctx := context.Background()
+
+	req := validPlaceOrder()
+	placed, err := client.PlaceOrder(ctx, req)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.GetOrderStatus() != helper.PENDING_PAYMENT || placed.GetTotalPrice() != 198 {
+		t.Fatalf("placed = %+v", placed)
+	}
+
+	_, err = client.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{OrderId: placed.GetId(), OrderStatus: helper.DISPATCHED})
+	wantCode(t, err, codes.FailedPrecondition)
+	payOrder(t, a, placed.GetId())
+
+	got, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: placed.GetId()})
+	if err != nil || got.GetId() != placed.GetId() {
+		t.Fatalf("GetOrder = %+v, %v", got, err)
+	}
+
+	list, err := client.ListOrders(ctx, &orderv1.ListOrdersRequest{UserId: req.GetUserId(), OrderStatus: helper.PLACED})
+	if err != nil || len(list.GetOrders()) != 1 {
+		t.Fatalf("ListOrders = %+v, %v", list, err)
+	}
+
+	dispatched, err := client.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{OrderId: placed.GetId(), OrderStatus: helper.DISPATCHED})
+	if err != nil || dispatched.GetOrderStatus() != helper.DISPATCHED {
+		t.Fatalf("UpdateOrderStatus = %+v, %v", dispatched, err)
+	}
+
+	_, err = client.CancelOrder(ctx, &orderv1.CancelOrderRequest{OrderId: placed.GetId()})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	second, err := client.PlaceOrder(ctx, validPlaceOrder())
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	payOrder(t, a, second.GetId())
+	cancelled, err := client.CancelOrder(ctx, &orderv1.CancelOrderRequest{OrderId: second.GetId()})
+	if err != nil || cancelled.GetOrderStatus() != helper.REFUND_PENDING {
+		t.Fatalf("CancelOrder = %+v, %v", cancelled, err)
+	}
+
+	if _, err := a.PaymentService.ProcessDueRefunds(ctx); err != nil {
+		t.Fatal(err)
+	}
+	refunded, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: second.GetId()})
+	if err != nil || refunded.GetOrderStatus() != helper.REFUNDED {
+		t.Fatalf("GetOrder after refund = %+v, %v", refunded, err)
+	}
+}
+
+func TestOrderServerErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	invalid := validPlaceOrder()
+	invalid.Category = "UNKNOWN"
+	_, err := client.PlaceOrder(ctx, invalid)
+	wantCode(t, err, codes.InvalidArgument)
+
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: "bad-id"})
+	wantCode(t, err, codes.InvalidArgument)
+
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{OrderId: primitive.NewObjectID().Hex()})
+	wantCode(t, err, codes.NotFound)
+
+	_, err = client.CancelOrder(ctx, &orderv1.CancelOrderRequest{OrderId: primitive.NewObjectID().Hex()})
+	wantCode(t, err, codes.NotFound)
+
+	placed, err := client.PlaceOrder(ctx, validPlaceOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{OrderId: placed.GetId(), OrderStatus: helper.CART})
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+func TestWatchOrder(t *testing.T) {
+	client, a := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	placed, err := client.PlaceOrder(ctx, validPlaceOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payOrder(t, a, placed.GetId())
+
+	stream, err := client.WatchOrder(ctx, &orderv1.WatchOrderRequest{OrderId: placed.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil || first.GetOrderStatus() != helper.PLACED {
+		t.Fatalf("first event = %+v, %v", first, err)
+	}
+
+	// an entry older than the state already sent, as if buffered before it was read
+	orderId, _ := primitive.ObjectIDFromHex(placed.GetId())
+	a.Broker.Publish(events.OrderEvent{Type: events.ORDER_STATUS_CHANGED, Track: models.OrderTrack{
+		Id: primitive.NewObjectIDFromTimestamp(time.Now().Add(-time.Minute)), OrderId: orderId, OrderStatus: helper.PENDING_PAYMENT,
+	}})
+
+	for _, next := range []string{helper.DISPATCHED, helper.COMPLETED} {
+		if _, err := client.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{OrderId: placed.GetId(), OrderStatus: next}); err != nil {
+			t.Fatal(err)
+		}
+
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if event.GetOrderStatus() != next || event.GetOrderId() != placed.GetId() || event.GetId() == "" {
+			t.Fatalf("event = %+v, want %s", event, next)
+		}
+	}
+
+	// the stream ends once the order is completed
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("expected the stream to end after COMPLETED")
+	}
+}
@@ -0,0 +1,65 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/helper"
+	orderv1 "github.com/aniket0951/order-services/proto/order/v1"
+	"github.com/aniket0951/order-services/services"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// Server grpc listener run as an app worker, stopped gracefully with the app
+type Server struct {
+	addr     string
+	server   *grpc.Server
+	stopping chan struct{}
+	logger   *slog.Logger
+}
+
+func NewServer(addr string, orderService services.OrderService, broker *events.Broker, validator *helper.RequestValidator, logger *slog.Logger) *Server {
+	stopping := make(chan struct{})
+
+	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	orderv1.RegisterOrderServiceServer(server, NewOrderServer(orderService, broker, validator, stopping))
+
+	return &Server{
+		addr:     addr,
+		server:   server,
+		stopping: stopping,
+		logger:   logger,
+	}
+}
+
+func (s *Server) Name() string {
+	return "grpc-server"
+}
+
+// Run serve until ctx is cancelled, then end open watches and let in-flight calls finish
+func (s *Server) Run(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("grpc listen: %w", err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		s.logger.Info("grpc server listening", "addr", s.addr)
+		serveErr <- s.server.Serve(lis)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		close(s.stopping)
+		s.server.GracefulStop()
+		s.logger.Info("grpc server stopped")
+		return ctx.Err()
+	}
+}
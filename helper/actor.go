@@ -0,0 +1,22 @@
+package helper
+
+import "context"
+
+// ACTOR_AUTO_CANCEL status changes made by the auto-cancel scheduler
+const ACTOR_AUTO_CANCEL = "system:auto-cancel"
+
+// ACTOR_REFUND_PROCESSOR refunds the background processor gave up on
+const ACTOR_REFUND_PROCESSOR = "system:refund-processor"
+
+type actorKey struct{}
+
+// WithActor record who is changing orders with this context, it ends up on the order track
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor stored on the context, empty for requests made through the api
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -1,5 +1,5 @@
 package helper
 
-var BASEURL = "http://localhost:5000/api/"
-
-var INCREASE_DECREASE_PRODUCT = BASEURL + "product/increase-decrease-product"
+// product service paths, relative to the configured product service base url
+var INCREASE_DECREASE_PRODUCT = "product/increase-decrease-product"
+var GET_SELLING_PRODUCT = "product/get-selling-product"
@@ -33,11 +33,76 @@ var USER_TYPE = ""
 var DATA = "data"
 var USER_DATA = "user_data"
 var ORDER_DATA = "order_data"
+var PAYMENT_DATA = "payment_data"
+var REFUND_DATA = "refund_data"
+var CART_DATA = "cart_data"
+var RETURN_DATA = "return_data"
+var REORDER_DATA = "reorder_data"
+var WISHLIST_DATA = "wishlist_data"
 
 // order Status tags
 
+var PENDING_PAYMENT = "PENDING_PAYMENT"
 var PLACED = "PLACED"
 var DISPATCHED = "DISPATCHED"
 var COMPLETED = "COMPLETED"
 var CANCELLED = "CANCELLED"
+var REFUND_PENDING = "REFUND_PENDING"
+var REFUNDED = "REFUNDED"
 var CART = "CART"
+
+// payment attempt status tags
+
+var PAYMENT_PENDING = "PENDING"
+var PAYMENT_SUCCEEDED = "SUCCEEDED"
+var PAYMENT_FAILED = "FAILED"
+
+// refund tags, refunds share the payment attempt status tags
+
+var REFUND_FULL = "FULL"
+var REFUND_PARTIAL = "PARTIAL"
+var REFUND_REASON_CANCELLED = "ORDER_CANCELLED"
+var REFUND_REASON_LATE_PAYMENT = "PAID_AFTER_CANCELLATION"
+var REFUND_REASON_RETURNED = "ORDER_RETURNED"
+var REFUND_REASON_EDITED = "ORDER_EDITED"
+
+// return status tags, every status is also a step of the return timeline
+
+var RETURN_REQUESTED = "REQUESTED"
+var RETURN_APPROVED = "APPROVED"
+var RETURN_REJECTED = "REJECTED"
+var RETURN_PICKUP_SCHEDULED = "PICKUP_SCHEDULED"
+var RETURN_INSPECTION_FAILED = "INSPECTION_FAILED"
+var RETURN_COMPLETED = "COMPLETED"
+var RETURN_REFUND_FAILED = "REFUND_FAILED"
+
+// return timeline steps that do not change the status
+
+var RETURN_INSPECTED = "INSPECTED"
+var RETURN_RESTOCKED = "RESTOCKED"
+var RETURN_RESTOCK_FAILED = "RESTOCK_FAILED"
+var RETURN_REFUND_REQUESTED = "REFUND_REQUESTED"
+
+// return reasons a customer can give
+
+var RETURN_REASONS = []string{"DAMAGED", "WRONG_ITEM", "NOT_AS_DESCRIBED", "NO_LONGER_NEEDED", "OTHER"}
+
+// reorder modes, a new order awaiting payment or lines in the user's cart
+
+var REORDER_AS_ORDER = "ORDER"
+var REORDER_TO_CART = "CART"
+
+// why items of a previous order can not be bought again
+
+var UNAVAILABLE_DISCONTINUED = "DISCONTINUED"
+var UNAVAILABLE_OUT_OF_STOCK = "OUT_OF_STOCK"
+
+// IsFinalStatus no further status change follows these
+func IsFinalStatus(status string) bool {
+	return status == COMPLETED || status == CANCELLED || status == REFUNDED
+}
+
+// IsClosedStatus cancelled orders, whether or not their refund has settled
+func IsClosedStatus(status string) bool {
+	return status == CANCELLED || status == REFUND_PENDING || status == REFUNDED
+}
@@ -70,6 +70,16 @@ func BuildUnProcessableEntity(ctx *gin.Context, err error) {
 	ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, response)
 }
 
+func BuildNotFoundResponse(ctx *gin.Context, err error) {
+	response := BuildFailedResponse(DATA_NOT_FOUND, err.Error(), EmptyObj{}, DATA)
+	ctx.AbortWithStatusJSON(http.StatusNotFound, response)
+}
+
+func BuildUnauthorizedResponse(ctx *gin.Context, err error) {
+	response := BuildFailedResponse(FAILED_PROCESS, err.Error(), EmptyObj{}, DATA)
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, response)
+}
+
 func CheckError(err error, ctx *gin.Context) bool {
 	if err != nil {
 		BuildUnProcessableEntity(ctx, err)
@@ -0,0 +1,145 @@
+package helper
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var VALIDATION_FAILED = "Validation failed, please check the errors."
+
+// FieldError single field level validation failure
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// RequestValidator request validation with the custom rules registered, the
+// category rule accepts the categories it was built with
+type RequestValidator struct {
+	validate   *validator.Validate
+	categories []string
+}
+
+func NewRequestValidator(categories []string) *RequestValidator {
+	rv := &RequestValidator{categories: categories}
+	rv.validate = newValidator(rv.IsAllowedCategory)
+	return rv
+}
+
+func newValidator(isAllowedCategory func(string) bool) *validator.Validate {
+	v := validator.New()
+
+	// report json field names instead of go struct names
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return fld.Name
+		}
+		return name
+	})
+
+	_ = v.RegisterValidation("objectid", func(fl validator.FieldLevel) bool {
+		return primitive.IsValidObjectID(fl.Field().String())
+	})
+
+	_ = v.RegisterValidation("category", func(fl validator.FieldLevel) bool {
+		return isAllowedCategory(fl.Field().String())
+	})
+
+	_ = v.RegisterValidation("return_reason", func(fl validator.FieldLevel) bool {
+		for _, reason := range RETURN_REASONS {
+			if reason == fl.Field().String() {
+				return true
+			}
+		}
+		return false
+	})
+
+	return v
+}
+
+func (rv *RequestValidator) IsAllowedCategory(category string) bool {
+	for _, allowed := range rv.categories {
+		if strings.EqualFold(allowed, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStruct validate a struct and collect the failures per field
+func (rv *RequestValidator) ValidateStruct(obj interface{}) ([]FieldError, error) {
+	err := rv.validate.Struct(obj)
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+
+	fieldErrors := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: rv.validationMessage(fe),
+		})
+	}
+
+	return fieldErrors, nil
+}
+
+func (rv *RequestValidator) validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fe.Field() + " is required"
+	case "objectid":
+		return fe.Field() + " must be a valid 24 character hex id"
+	case "category":
+		return fe.Field() + " must be one of " + strings.Join(rv.categories, ", ")
+	case "return_reason":
+		return fe.Field() + " must be one of " + strings.Join(RETURN_REASONS, ", ")
+	case "max":
+		return fe.Field() + " must be at most " + fe.Param() + " characters"
+	case "gt":
+		return fe.Field() + " must be greater than " + fe.Param()
+	case "gte":
+		return fe.Field() + " must be greater than or equal to " + fe.Param()
+	case "lte":
+		return fe.Field() + " must be less than or equal to " + fe.Param()
+	case "oneof":
+		return fe.Field() + " must be one of " + fe.Param()
+	}
+	return fe.Field() + " failed on the " + fe.Tag() + " rule"
+}
+
+func BuildValidationFailedResponse(fieldErrors []FieldError) map[string]interface{} {
+	response := BuildFailedResponse(FAILED_PROCESS, VALIDATION_FAILED, EmptyObj{}, DATA)
+	response["errors"] = fieldErrors
+	return response
+}
+
+// CheckValidation validate request body, abort with field errors when invalid
+func (rv *RequestValidator) CheckValidation(obj interface{}, ctx *gin.Context) bool {
+	fieldErrors, err := rv.ValidateStruct(obj)
+
+	if CheckError(err, ctx) {
+		return true
+	}
+
+	if len(fieldErrors) > 0 {
+		ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, BuildValidationFailedResponse(fieldErrors))
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aniket0951/order-services/repositories"
+)
+
+// Periodic app worker calling run straight away and then on every interval
+// until ctx is cancelled, a failed run is logged and retried on the next tick
+type Periodic struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	logger   *slog.Logger
+
+	leases   repositories.LeaseRepository
+	owner    string
+	leaseTTL time.Duration
+}
+
+func NewPeriodic(name string, interval time.Duration, run func(ctx context.Context) error, logger *slog.Logger) *Periodic {
+	return &Periodic{
+		name:     name,
+		interval: interval,
+		run:      run,
+		logger:   logger,
+	}
+}
+
+// WithLease only run while owner holds the lease named after the job, so
+// across replicas a single one does the work. The lease is renewed every
+// interval and handed over once it lapses, ttl must outlast the interval
+func (p *Periodic) WithLease(leases repositories.LeaseRepository, owner string, ttl time.Duration) *Periodic {
+	p.leases = leases
+	p.owner = owner
+	p.leaseTTL = ttl
+	return p
+}
+
+func (p *Periodic) Name() string {
+	return p.name
+}
+
+func (p *Periodic) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	defer p.release()
+
+	for {
+		if p.holdsLease(ctx) {
+			if err := p.runLeased(ctx); err != nil && ctx.Err() == nil {
+				p.logger.ErrorContext(ctx, "periodic job failed", "worker", p.name, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// a run may not outlive the lease it was started under, once the ttl lapses
+// another replica can take the lease over and start the same work
+func (p *Periodic) runLeased(ctx context.Context) error {
+	if p.leases == nil {
+		return p.run(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.leaseTTL)
+	defer cancel()
+	return p.run(ctx)
+}
+
+func (p *Periodic) holdsLease(ctx context.Context) bool {
+	if p.leases == nil {
+		return true
+	}
+
+	held, err := p.leases.AcquireLease(ctx, p.name, p.owner, p.leaseTTL)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.ErrorContext(ctx, "failed to acquire job lease", "worker", p.name, "owner", p.owner, "error", err)
+		}
+		return false
+	}
+	return held
+}
+
+// let another replica take over straight away instead of after the ttl
+func (p *Periodic) release() {
+	if p.leases == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.leases.ReleaseLease(ctx, p.name, p.owner); err != nil {
+		p.logger.WarnContext(ctx, "failed to release job lease", "worker", p.name, "owner", p.owner, "error", err)
+	}
+}
+
+// InstanceID lease owner name of this process, the host name plus a random suffix
+func InstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "order-service"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
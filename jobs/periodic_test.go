@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/repositories"
+)
+
+func TestPeriodicWithLeaseRunsOnOneReplica(t *testing.T) {
+	leases := repositories.NewMemoryLeaseRepository()
+	var runs [2]atomic.Int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	replicas := make([]*Periodic, 2)
+	for i := range replicas {
+		i := i
+		replicas[i] = NewPeriodic("job", 10*time.Millisecond, func(ctx context.Context) error {
+			runs[i].Add(1)
+			return nil
+		}, logging.Discard()).WithLease(leases, InstanceID(), time.Minute)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = replicas[i].Run(ctx)
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	first, second := runs[0].Load(), runs[1].Load()
+	if (first == 0) == (second == 0) {
+		t.Fatalf("runs = %d and %d, want exactly one replica running the job", first, second)
+	}
+
+	// the holder released the lease on the way out, another replica takes over at once
+	standby := NewPeriodic("job", time.Hour, func(ctx context.Context) error {
+		cancel()
+		return nil
+	}, logging.Discard()).WithLease(leases, InstanceID(), time.Minute)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = standby.Run(ctx)
+	if ctx.Err() != context.Canceled {
+		t.Fatal("standby did not get the released lease")
+	}
+}
+
+func TestPeriodicRunEndsWithTheLease(t *testing.T) {
+	leases := repositories.NewMemoryLeaseRepository()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ended := make(chan error, 1)
+	job := NewPeriodic("job", time.Hour, func(runCtx context.Context) error {
+		<-runCtx.Done()
+		ended <- runCtx.Err()
+		cancel()
+		return runCtx.Err()
+	}, logging.Discard()).WithLease(leases, InstanceID(), 50*time.Millisecond)
+
+	_ = job.Run(ctx)
+	if err := <-ended; err != context.DeadlineExceeded {
+		t.Fatalf("run ended with %v, want the lease ttl to cut it off", err)
+	}
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "order_service"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	OrderStatusTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_status_transitions_total",
+		Help:      "Order status changes, from is empty for newly created orders.",
+	}, []string{"from", "to"})
+
+	CartSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cart_size_lines",
+		Help:      "Number of lines in a user's cart after it changed.",
+		Buckets:   []float64{1, 2, 3, 5, 8, 13, 21},
+	}, []string{"operation"})
+
+	RepositoryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_operation_duration_seconds",
+		Help:      "Repository call latency by collection, method and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"collection", "method", "outcome"})
+
+	InventoryRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "inventory_requests_total",
+		Help:      "Product service calls by operation and outcome.",
+	}, []string{"operation", "outcome"})
+
+	InventoryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "inventory_request_duration_seconds",
+		Help:      "Product service call latency by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	PaymentAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_attempts_total",
+		Help:      "Payment attempts by gateway and status, counted when created and when settled.",
+	}, []string{"gateway", "status"})
+
+	RefundAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refund_attempts_total",
+		Help:      "Refund calls to the gateway by outcome, retry when another attempt was scheduled.",
+	}, []string{"gateway", "outcome"})
+
+	AutoCancelledOrders = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auto_cancelled_orders_total",
+		Help:      "Orders cancelled by the scheduler for overstaying their status sla, by that status.",
+	}, []string{"status"})
+
+	ExpiredCartLines = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expired_cart_lines_total",
+		Help:      "Cart lines removed by the sweeper after their ttl.",
+	})
+
+	AbandonedCarts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "abandoned_carts_total",
+		Help:      "Carts reported abandoned, each abandonment is counted once.",
+	})
+
+	ReturnTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "return_transitions_total",
+		Help:      "Return requests entering a status.",
+	}, []string{"status"})
+)
+
+// CartLinesCollector lines currently sitting in all carts, read through source on
+// every scrape and given at most timeout to answer
+type CartLinesCollector struct {
+	desc    *prometheus.Desc
+	source  func(ctx context.Context) (int64, error)
+	timeout time.Duration
+}
+
+func NewCartLinesCollector(source func(ctx context.Context) (int64, error), timeout time.Duration) *CartLinesCollector {
+	return &CartLinesCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "cart_lines"),
+			"Lines currently sitting in all carts, read from the repository on scrape, -1 when the read failed.", nil, nil),
+		source:  source,
+		timeout: timeout,
+	}
+}
+
+func (c *CartLinesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *CartLinesCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	value := -1.0
+	if count, err := c.source(ctx); err == nil {
+		value = float64(count)
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, value)
+}
+
+// Outcome label value for an error
+func Outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// ObserveRepository record a repository call, use as defer metrics.ObserveRepository(...)(&err)
+func ObserveRepository(collection, method string) func(err *error) {
+	start := time.Now()
+	return func(err *error) {
+		var callErr error
+		if err != nil {
+			callErr = *err
+		}
+		RepositoryDuration.WithLabelValues(collection, method, Outcome(callErr)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveInventory record an outbound product service call
+func ObserveInventory(operation string, start time.Time, err error) {
+	InventoryRequests.WithLabelValues(operation, Outcome(err)).Inc()
+	InventoryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
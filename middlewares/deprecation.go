@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated mark responses of a route group as deprecated (RFC 9745) with a
+// Sunset date (RFC 8594) and a link to the successor api
+func Deprecated(deprecatedAt, sunsetAt time.Time, successor string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunset := sunsetAt.UTC().Format(http.TimeFormat)
+	link := "<" + successor + `>; rel="successor-version"`
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		ctx.Header("Sunset", sunset)
+		ctx.Header("Link", link)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics record request count and latency per route template
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.HTTPRequests.WithLabelValues(ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(ctx.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Lease named lock held by one service instance until it expires
+type Lease struct {
+	Name      string             `json:"name" bson:"_id"`
+	Owner     string             `json:"owner" bson:"owner"`
+	ExpiresAt primitive.DateTime `json:"expires_at" bson:"expires_at"`
+	UpdatedAt primitive.DateTime `json:"updated_at" bson:"updated_at"`
+}
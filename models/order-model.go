@@ -19,6 +19,20 @@ type Orders struct {
 	OrderStatus      string             `json:"order_status" bson:"order_status"`
 	CreatedAt        primitive.DateTime `json:"created_at" bson:"created_at"`
 	UpdatedAt        primitive.DateTime `json:"updated_at" bson:"updated_at"`
+	// units sent out so far, once dispatched the units left are backordered
+	ShippedQuantity     int64 `json:"shipped_quantity" bson:"shipped_quantity"`
+	BackorderedQuantity int64 `json:"backordered_quantity" bson:"backordered_quantity"`
+	// units cancelled out of the order, they are no longer part of Quantity and TotalPrice
+	CancelledQuantity int64 `json:"cancelled_quantity" bson:"cancelled_quantity"`
+	// the units of the cancelled order went back to stock, a resumed cancellation skips the restock
+	Restocked bool `json:"restocked,omitempty" bson:"restocked,omitempty"`
+	// set on a freshly placed order whose units the product service did not take off stock, never stored
+	StockNotUpdated bool `json:"stock_not_updated,omitempty" bson:"-"`
+}
+
+// UnshippedQuantity units that can still be shipped or cancelled
+func (order Orders) UnshippedQuantity() int64 {
+	return order.Quantity - order.ShippedQuantity
 }
 
 func (order *Orders) SetPlaceOrder(moduleData dto.CreateOrderDTO, tag string) Orders {
@@ -47,6 +61,20 @@ type OrderCarts struct {
 	UserId    primitive.ObjectID `json:"user_id" bson:"user_id"`
 	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
 	UpdatedAt primitive.DateTime `json:"updated_at" bson:"updated_at"`
+	// when the cart this line sits in was reported abandoned
+	AbandonedAt primitive.DateTime `json:"abandoned_at,omitempty" bson:"abandoned_at,omitempty"`
+}
+
+// AbandonedCart a user's cart nobody touched for a while, Value sums the
+// total price of its CART orders
+type AbandonedCart struct {
+	UserId         primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	OrderIds       []primitive.ObjectID `json:"order_ids" bson:"order_ids"`
+	Lines          int64                `json:"lines" bson:"lines"`
+	Value          float64              `json:"value" bson:"value"`
+	LastActivityAt primitive.DateTime   `json:"last_activity_at" bson:"last_activity_at"`
+	// every line was already reported, no further event is due until the cart changes
+	Notified bool `json:"notified" bson:"notified"`
 }
 
 type OrderTrack struct {
@@ -55,11 +83,32 @@ type OrderTrack struct {
 	OrderStatus string             `json:"order_status" bson:"order_status"`
 	CreatedAt   primitive.DateTime `json:"created_at" bson:"created_at"`
 	UpdatedAt   primitive.DateTime `json:"updated_at" bson:"updated_at"`
+	// set when the service changed the status on its own, like system:auto-cancel
+	Actor string `json:"actor,omitempty" bson:"actor,omitempty"`
+	// set when the entry moved part of the order's units, like 2 units CANCELLED
+	UnitStatus string `json:"unit_status,omitempty" bson:"unit_status,omitempty"`
+	Units      int64  `json:"units,omitempty" bson:"units,omitempty"`
+	// set when the entry records an edit of the order before dispatch
+	Edit *OrderEdit `json:"edit,omitempty" bson:"edit,omitempty"`
+	// set when the entry records a refund that failed for good or was queued again
+	RefundId     *primitive.ObjectID `json:"refund_id,omitempty" bson:"refund_id,omitempty"`
+	RefundStatus string              `json:"refund_status,omitempty" bson:"refund_status,omitempty"`
+}
+
+// OrderEdit quantity, variant and price of the order before and after an edit
+type OrderEdit struct {
+	FromQuantity         int64              `json:"from_quantity" bson:"from_quantity"`
+	ToQuantity           int64              `json:"to_quantity" bson:"to_quantity"`
+	FromProductSellingID primitive.ObjectID `json:"from_prod_selling_id" bson:"from_prod_selling_id"`
+	ToProductSellingID   primitive.ObjectID `json:"to_prod_selling_id" bson:"to_prod_selling_id"`
+	FromTotalPrice       float64            `json:"from_total_price" bson:"from_total_price"`
+	ToTotalPrice         float64            `json:"to_total_price" bson:"to_total_price"`
 }
 
 func (track *OrderTrack) SetOrderTrack(orderID primitive.ObjectID, status string) OrderTrack {
 	newOrderTrack := OrderTrack{}
 
+	newOrderTrack.Id = primitive.NewObjectID()
 	newOrderTrack.OrderId = orderID
 	newOrderTrack.OrderStatus = status
 	newOrderTrack.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaymentAttempt one try at paying an order through a gateway intent
+type PaymentAttempt struct {
+	Id            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OrderId       primitive.ObjectID `json:"order_id" bson:"order_id"`
+	UserId        primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Gateway       string             `json:"gateway" bson:"gateway"`
+	IntentId      string             `json:"intent_id" bson:"intent_id"`
+	ClientSecret  string             `json:"client_secret,omitempty" bson:"-"`
+	Amount        float64            `json:"amount" bson:"amount"`
+	Currency      string             `json:"currency" bson:"currency"`
+	Status        string             `json:"status" bson:"status"`
+	FailureReason string             `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	CreatedAt     primitive.DateTime `json:"created_at" bson:"created_at"`
+	UpdatedAt     primitive.DateTime `json:"updated_at" bson:"updated_at"`
+}
+
+func (payment *PaymentAttempt) SetPaymentAttempt(order Orders, gateway, intentId, currency, status string) PaymentAttempt {
+	newPayment := PaymentAttempt{}
+
+	newPayment.Id = primitive.NewObjectID()
+	newPayment.OrderId = order.Id
+	newPayment.UserId = order.UserId
+	newPayment.Gateway = gateway
+	newPayment.IntentId = intentId
+	newPayment.Amount = order.TotalPrice
+	newPayment.Currency = currency
+	newPayment.Status = status
+	newPayment.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	newPayment.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return newPayment
+}
+
+// Refund money returned on a succeeded payment attempt, processed through the
+// gateway until it settles or runs out of attempts
+type Refund struct {
+	Id              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OrderId         primitive.ObjectID `json:"order_id" bson:"order_id"`
+	UserId          primitive.ObjectID `json:"user_id" bson:"user_id"`
+	PaymentId       primitive.ObjectID `json:"payment_id" bson:"payment_id"`
+	Gateway         string             `json:"gateway" bson:"gateway"`
+	IntentId        string             `json:"intent_id" bson:"intent_id"`
+	GatewayRefundId string             `json:"gateway_refund_id,omitempty" bson:"gateway_refund_id,omitempty"`
+	Kind            string             `json:"kind" bson:"kind"`
+	Amount          float64            `json:"amount" bson:"amount"`
+	Currency        string             `json:"currency" bson:"currency"`
+	Reason          string             `json:"reason" bson:"reason"`
+	Status          string             `json:"status" bson:"status"`
+	Attempts        int                `json:"attempts" bson:"attempts"`
+	LastError       string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt   primitive.DateTime `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt       primitive.DateTime `json:"created_at" bson:"created_at"`
+	UpdatedAt       primitive.DateTime `json:"updated_at" bson:"updated_at"`
+	// Key one refund at most is stored per key, left empty by refunds that may repeat
+	Key string `json:"-" bson:"key,omitempty"`
+}
+
+func (refund *Refund) SetRefund(payment PaymentAttempt, kind string, amount float64, reason, status string) Refund {
+	newRefund := Refund{}
+
+	newRefund.Id = primitive.NewObjectID()
+	newRefund.OrderId = payment.OrderId
+	newRefund.UserId = payment.UserId
+	newRefund.PaymentId = payment.Id
+	newRefund.Gateway = payment.Gateway
+	newRefund.IntentId = payment.IntentId
+	newRefund.Kind = kind
+	newRefund.Amount = amount
+	newRefund.Currency = payment.Currency
+	newRefund.Reason = reason
+	newRefund.Status = status
+	newRefund.NextAttemptAt = primitive.NewDateTimeFromTime(time.Now())
+	newRefund.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	newRefund.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return newRefund
+}
@@ -0,0 +1,22 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Reorder what buying a previous order again placed or added to the cart,
+// Unavailable lists the units that could not be bought again
+type Reorder struct {
+	SourceOrderId primitive.ObjectID `json:"source_order_id"`
+	Mode          string             `json:"mode"`
+	Orders        []Orders           `json:"orders"`
+	Unavailable   []UnavailableItem  `json:"unavailable"`
+	// the order was placed but the product service did not take its units off stock
+	StockNotUpdated bool `json:"stock_not_updated,omitempty"`
+}
+
+// UnavailableItem units of a previous order that can not be bought again and why
+type UnavailableItem struct {
+	ProductId        primitive.ObjectID `json:"prod_id"`
+	ProductSellingID primitive.ObjectID `json:"prod_selling_id"`
+	Quantity         int64              `json:"quantity"`
+	Reason           string             `json:"reason"`
+}
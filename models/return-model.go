@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReturnRequest return of some units of a completed order, moved along by
+// operators from approval through pickup and inspection to the refund
+type ReturnRequest struct {
+	Id         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	OrderId    primitive.ObjectID  `json:"order_id" bson:"order_id"`
+	UserId     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ProductId  primitive.ObjectID  `json:"prod_id" bson:"prod_id"`
+	Quantity   int64               `json:"quantity" bson:"quantity"`
+	Reason     string              `json:"reason" bson:"reason"`
+	Comment    string              `json:"comment,omitempty" bson:"comment,omitempty"`
+	Status     string              `json:"status" bson:"status"`
+	PickupAt   primitive.DateTime  `json:"pickup_at,omitempty" bson:"pickup_at,omitempty"`
+	Inspection *ReturnInspection   `json:"inspection,omitempty" bson:"inspection,omitempty"`
+	RefundId   *primitive.ObjectID `json:"refund_id,omitempty" bson:"refund_id,omitempty"`
+	Timeline   []ReturnEvent       `json:"timeline" bson:"timeline"`
+	CreatedAt  primitive.DateTime  `json:"created_at" bson:"created_at"`
+	UpdatedAt  primitive.DateTime  `json:"updated_at" bson:"updated_at"`
+}
+
+// ReturnInspection what the warehouse found in the returned parcel
+type ReturnInspection struct {
+	Passed bool `json:"passed" bson:"passed"`
+	// units fit to be sold again, they go back to stock
+	RestockQuantity int64  `json:"restock_quantity" bson:"restock_quantity"`
+	Notes           string `json:"notes,omitempty" bson:"notes,omitempty"`
+}
+
+// ReturnEvent one step of the return timeline, Actor is the user or operator behind it
+type ReturnEvent struct {
+	Step      string             `json:"step" bson:"step"`
+	Actor     string             `json:"actor" bson:"actor"`
+	Note      string             `json:"note,omitempty" bson:"note,omitempty"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+}
+
+func (ret *ReturnRequest) SetReturnRequest(order Orders, quantity int64, reason, comment, status string) ReturnRequest {
+	newReturn := ReturnRequest{}
+
+	newReturn.Id = primitive.NewObjectID()
+	newReturn.OrderId = order.Id
+	newReturn.UserId = order.UserId
+	newReturn.ProductId = order.ProductId
+	newReturn.Quantity = quantity
+	newReturn.Reason = reason
+	newReturn.Comment = comment
+	newReturn.Status = status
+	newReturn.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	newReturn.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return newReturn
+}
+
+// AddEvent append a step to the timeline and bump UpdatedAt
+func (ret *ReturnRequest) AddEvent(step, actor, note string) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	ret.Timeline = append(ret.Timeline, ReturnEvent{Step: step, Actor: actor, Note: note, CreatedAt: now})
+	ret.UpdatedAt = now
+}
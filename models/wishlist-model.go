@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WishlistItem a product the user saved for later, one item per user and selling variant
+type WishlistItem struct {
+	Id               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserId           primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ProductId        primitive.ObjectID `json:"prod_id" bson:"prod_id"`
+	ProductSellingID primitive.ObjectID `json:"prod_selling_id" bson:"prod_selling_id"`
+	Category         string             `json:"category" bson:"category"`
+	Quantity         int64              `json:"quantity" bson:"quantity"`
+	// unit price when the item was saved, a later save takes a new snapshot
+	SavedPrice int64              `json:"saved_price" bson:"saved_price"`
+	SavedAt    primitive.DateTime `json:"saved_at" bson:"saved_at"`
+	// cart lines whose units were added to the item, a line saved again is not counted twice
+	CartLineIds []primitive.ObjectID `json:"-" bson:"cart_line_ids"`
+
+	// filled in from the product service when the wishlist is read, never stored
+	CurrentPrice int64 `json:"current_price,omitempty" bson:"-"`
+	PriceDrop    int64 `json:"price_drop,omitempty" bson:"-"`
+	Discontinued bool  `json:"discontinued,omitempty" bson:"-"`
+}
+
+// SetWishlistItem snapshot a cart line at the given unit price
+func (item *WishlistItem) SetWishlistItem(order Orders, unitPrice int64) WishlistItem {
+	newItem := WishlistItem{}
+
+	newItem.Id = primitive.NewObjectID()
+	newItem.UserId = order.UserId
+	newItem.ProductId = order.ProductId
+	newItem.ProductSellingID = order.ProductSellingID
+	newItem.Category = order.Category
+	newItem.Quantity = order.Quantity
+	newItem.SavedPrice = unitPrice
+	newItem.SavedAt = primitive.NewDateTimeFromTime(time.Now())
+	newItem.CartLineIds = []primitive.ObjectID{order.Id}
+
+	return newItem
+}
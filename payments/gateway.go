@@ -0,0 +1,63 @@
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+// SIGNATURE_HEADER request header carrying the callback signature
+const SIGNATURE_HEADER = "X-Payment-Signature"
+
+// callback outcomes reported by a gateway
+const (
+	CALLBACK_SUCCEEDED = "succeeded"
+	CALLBACK_FAILED    = "failed"
+)
+
+// ErrInvalidSignature callback not signed by the gateway
+var ErrInvalidSignature = errors.New("invalid payment callback signature")
+
+// PaymentGateway payment provider the order service takes money through
+type PaymentGateway interface {
+	Name() string
+	// CreateIntent start collecting the amount, the customer completes it with the provider
+	CreateIntent(ctx context.Context, req IntentRequest) (Intent, error)
+	// VerifyCallback authenticate and decode a callback sent by the provider
+	VerifyCallback(payload []byte, signature string) (CallbackEvent, error)
+	// Refund return part or all of a paid intent, an error means it may be retried
+	Refund(ctx context.Context, req RefundRequest) (RefundResult, error)
+}
+
+type IntentRequest struct {
+	OrderId  string
+	Amount   float64
+	Currency string
+	// repeated requests with the same key return the same intent
+	IdempotencyKey string
+}
+
+type Intent struct {
+	Id string
+	// url or token the client needs to complete the payment with the provider
+	ClientSecret string
+}
+
+// CallbackEvent final outcome of an intent
+type CallbackEvent struct {
+	IntentId      string `json:"intent_id"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+type RefundRequest struct {
+	IntentId string
+	Amount   float64
+	Currency string
+	Reason   string
+	// repeated requests with the same key refund only once
+	IdempotencyKey string
+}
+
+type RefundResult struct {
+	Id string
+}
@@ -0,0 +1,114 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockGateway local stand-in for a payment provider. It hands out intents
+// and refunds immediately and expects callbacks signed with the shared
+// secret, which tests and developers produce with Sign
+type MockGateway struct {
+	secret []byte
+
+	mu             sync.Mutex
+	intents        map[string]Intent
+	refunds        map[string]RefundResult
+	refundFailures int
+}
+
+func NewMockGateway(secret string) *MockGateway {
+	return &MockGateway{
+		secret:  []byte(secret),
+		intents: map[string]Intent{},
+		refunds: map[string]RefundResult{},
+	}
+}
+
+func (g *MockGateway) Name() string {
+	return "mock"
+}
+
+func (g *MockGateway) CreateIntent(ctx context.Context, req IntentRequest) (Intent, error) {
+	if req.Amount <= 0 {
+		return Intent{}, fmt.Errorf("amount must be positive, got %v", req.Amount)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if intent, ok := g.intents[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return intent, nil
+	}
+
+	id := "pi_mock_" + primitive.NewObjectID().Hex()
+	intent := Intent{Id: id, ClientSecret: id + "_secret"}
+	if req.IdempotencyKey != "" {
+		g.intents[req.IdempotencyKey] = intent
+	}
+	return intent, nil
+}
+
+// Refund settles straight away, FailRefunds makes the next calls fail first
+func (g *MockGateway) Refund(ctx context.Context, req RefundRequest) (RefundResult, error) {
+	if req.Amount <= 0 || req.IntentId == "" {
+		return RefundResult{}, fmt.Errorf("refund needs an intent and a positive amount, got %q %v", req.IntentId, req.Amount)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.refundFailures > 0 {
+		g.refundFailures--
+		return RefundResult{}, errors.New("mock gateway unavailable")
+	}
+
+	if refund, ok := g.refunds[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return refund, nil
+	}
+
+	refund := RefundResult{Id: "re_mock_" + primitive.NewObjectID().Hex()}
+	if req.IdempotencyKey != "" {
+		g.refunds[req.IdempotencyKey] = refund
+	}
+	return refund, nil
+}
+
+// FailRefunds fail the next n refund calls, to exercise retries
+func (g *MockGateway) FailRefunds(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refundFailures = n
+}
+
+// Sign signature header value for a callback payload
+func (g *MockGateway) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g *MockGateway) VerifyCallback(payload []byte, signature string) (CallbackEvent, error) {
+	if !strings.HasPrefix(signature, "sha256=") || !hmac.Equal([]byte(signature), []byte(g.Sign(payload))) {
+		return CallbackEvent{}, ErrInvalidSignature
+	}
+
+	var event CallbackEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return CallbackEvent{}, fmt.Errorf("decode payment callback: %w", err)
+	}
+
+	if event.IntentId == "" || (event.Status != CALLBACK_SUCCEEDED && event.Status != CALLBACK_FAILED) {
+		return CallbackEvent{}, fmt.Errorf("payment callback needs an intent_id and a status of %s or %s", CALLBACK_SUCCEEDED, CALLBACK_FAILED)
+	}
+	return event, nil
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryLeaseRepository in-process LeaseRepository, only meaningful within one process
+type memoryLeaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]models.Lease
+}
+
+func NewMemoryLeaseRepository() LeaseRepository {
+	return &memoryLeaseRepository{
+		leases: map[string]models.Lease{},
+	}
+}
+
+func (db *memoryLeaseRepository) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	if lease, ok := db.leases[name]; ok && lease.Owner != owner && lease.ExpiresAt.Time().After(now) {
+		return false, nil
+	}
+
+	db.leases[name] = models.Lease{
+		Name:      name,
+		Owner:     owner,
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+		UpdatedAt: primitive.NewDateTimeFromTime(now),
+	}
+	return true, nil
+}
+
+func (db *memoryLeaseRepository) ReleaseLease(ctx context.Context, name, owner string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if lease, ok := db.leases[name]; ok && lease.Owner == owner {
+		delete(db.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/aniket0951/order-services/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *leaseRepository) Init(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	return operationContext(ctx, db.timeouts, method)
+}
+
+// the upsert only matches a lease that is ours or has expired, when someone
+// else holds it the insert collides on _id and the lease is not acquired
+func (db *leaseRepository) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (acquired bool, err error) {
+	defer metrics.ObserveRepository(db.leasesCollection.Name(), "AcquireLease")(&err)
+	now := time.Now()
+
+	filter := bson.D{
+		bson.E{Key: "_id", Value: name},
+		bson.E{Key: "$or", Value: bson.A{
+			bson.D{bson.E{Key: "owner", Value: owner}},
+			bson.D{bson.E{Key: "expires_at", Value: bson.D{bson.E{Key: "$lte", Value: primitive.NewDateTimeFromTime(now)}}}},
+		}},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "owner", Value: owner},
+			bson.E{Key: "expires_at", Value: primitive.NewDateTimeFromTime(now.Add(ttl))},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(now)},
+		}},
+	}
+
+	ctx, cancel := db.Init(ctx, "AcquireLease")
+	defer cancel()
+
+	_, err = db.leasesCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *leaseRepository) ReleaseLease(ctx context.Context, name, owner string) (err error) {
+	defer metrics.ObserveRepository(db.leasesCollection.Name(), "ReleaseLease")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: name},
+		bson.E{Key: "owner", Value: owner},
+	}
+
+	ctx, cancel := db.Init(ctx, "ReleaseLease")
+	defer cancel()
+
+	_, err = db.leasesCollection.DeleteOne(ctx, filter)
+	return err
+}
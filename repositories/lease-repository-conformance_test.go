@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/logging"
+)
+
+func TestMemoryLeaseRepository(t *testing.T) {
+	runLeaseRepositoryConformance(t, func(t *testing.T) LeaseRepository {
+		return NewMemoryLeaseRepository()
+	})
+}
+
+func TestMongoLeaseRepository(t *testing.T) {
+	skipWithoutMongo(t)
+
+	runLeaseRepositoryConformance(t, func(t *testing.T) LeaseRepository {
+		client, cfg := connectTestMongo(t)
+		return NewLeaseRepository(client, cfg, logging.Discard())
+	})
+}
+
+func runLeaseRepositoryConformance(t *testing.T, newRepo func(t *testing.T) LeaseRepository) {
+	ctx := context.Background()
+
+	t.Run("one owner at a time", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if held, err := repo.AcquireLease(ctx, "job", "replica-a", time.Minute); err != nil || !held {
+			t.Fatalf("first acquire = %v, %v", held, err)
+		}
+		if held, err := repo.AcquireLease(ctx, "job", "replica-b", time.Minute); err != nil || held {
+			t.Fatalf("acquire of a held lease = %v, %v", held, err)
+		}
+		if held, err := repo.AcquireLease(ctx, "job", "replica-a", time.Minute); err != nil || !held {
+			t.Fatalf("renew by the owner = %v, %v", held, err)
+		}
+		if held, err := repo.AcquireLease(ctx, "other-job", "replica-b", time.Minute); err != nil || !held {
+			t.Fatalf("leases are per name, got %v, %v", held, err)
+		}
+
+		// only the owner can release
+		if err := repo.ReleaseLease(ctx, "job", "replica-b"); err != nil {
+			t.Fatal(err)
+		}
+		if held, _ := repo.AcquireLease(ctx, "job", "replica-b", time.Minute); held {
+			t.Fatal("lease released by someone who does not hold it")
+		}
+
+		if err := repo.ReleaseLease(ctx, "job", "replica-a"); err != nil {
+			t.Fatalf("ReleaseLease: %v", err)
+		}
+		if held, err := repo.AcquireLease(ctx, "job", "replica-b", time.Minute); err != nil || !held {
+			t.Fatalf("acquire after release = %v, %v", held, err)
+		}
+	})
+
+	t.Run("expired lease is taken over", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if held, _ := repo.AcquireLease(ctx, "job", "replica-a", 50*time.Millisecond); !held {
+			t.Fatal("first acquire failed")
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		if held, err := repo.AcquireLease(ctx, "job", "replica-b", time.Minute); err != nil || !held {
+			t.Fatalf("acquire of an expired lease = %v, %v", held, err)
+		}
+		if held, _ := repo.AcquireLease(ctx, "job", "replica-a", time.Minute); held {
+			t.Fatal("previous owner got the lease back while it is held")
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aniket0951/order-services/config"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LeaseRepository named leases so a job runs on one replica at a time
+type LeaseRepository interface {
+	// AcquireLease take the lease, or extend it when owner already holds it, until now+ttl.
+	// False when another owner holds an unexpired lease
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLease give the lease up early, a no-op unless owner holds it
+	ReleaseLease(ctx context.Context, name, owner string) error
+}
+
+type leaseRepository struct {
+	leasesCollection *mongo.Collection
+	logger           *slog.Logger
+	timeouts         config.MongoConfig
+}
+
+func NewLeaseRepository(client *mongo.Client, cfg *config.Config, logger *slog.Logger) LeaseRepository {
+	return &leaseRepository{
+		logger:           logger,
+		timeouts:         cfg.Mongo,
+		leasesCollection: config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Leases),
+	}
+}
@@ -0,0 +1,496 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryOrderRepository in-process OrderRepository for tests and local development,
+// it mirrors the mongo implementation including its not-found behaviour
+type memoryOrderRepository struct {
+	mu      sync.RWMutex
+	orders  map[primitive.ObjectID]models.Orders
+	carts   []models.OrderCarts
+	tracks  []models.OrderTrack
+	history []models.OrderHistory
+}
+
+func NewMemoryOrderRepository() OrderRepository {
+	return &memoryOrderRepository{
+		orders: map[primitive.ObjectID]models.Orders{},
+	}
+}
+
+var errDuplicateKey = errors.New("duplicate key error")
+
+func (db *memoryOrderRepository) PlaceSingleOrder(ctx context.Context, order models.Orders) (*mongo.InsertOneResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if order.Id.IsZero() {
+		order.Id = primitive.NewObjectID()
+	}
+
+	if _, ok := db.orders[order.Id]; ok {
+		return nil, errDuplicateKey
+	}
+
+	db.orders[order.Id] = order
+	return &mongo.InsertOneResult{InsertedID: order.Id}, nil
+}
+
+func (db *memoryOrderRepository) AddToCartOrder(ctx context.Context, order models.OrderCarts) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if order.Id.IsZero() {
+		order.Id = primitive.NewObjectID()
+	}
+
+	for _, cart := range db.carts {
+		if cart.Id == order.Id {
+			return errDuplicateKey
+		}
+	}
+
+	db.carts = append(db.carts, order)
+	return nil
+}
+
+func (db *memoryOrderRepository) CheckDuplicateOrderToAddCart(ctx context.Context, orderId, userId primitive.ObjectID) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, cart := range db.carts {
+		if cart.OrderId == orderId && cart.UserId == userId {
+			return errors.New("order already in cart")
+		}
+	}
+	return nil
+}
+
+// remove the first cart line of the order, like DeleteOne
+func (db *memoryOrderRepository) DeleteCartOrder(ctx context.Context, orderId primitive.ObjectID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, cart := range db.carts {
+		if cart.OrderId == orderId {
+			db.carts = append(db.carts[:i], db.carts[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (db *memoryOrderRepository) DeleteOrder(ctx context.Context, orderId primitive.ObjectID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.orders, orderId)
+	return nil
+}
+
+func (db *memoryOrderRepository) UpdateOrderQuantityAndPrice(ctx context.Context, order models.Orders) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.orders[order.Id]
+	if !ok {
+		return ErrOrderNotFound
+	}
+
+	existing.Quantity = order.Quantity
+	existing.TotalPrice = order.TotalPrice
+	db.orders[order.Id] = existing
+	return nil
+}
+
+func (db *memoryOrderRepository) UpdateOrderUnits(ctx context.Context, order, from models.Orders) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.orders[order.Id]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if existing.OrderStatus != from.OrderStatus || existing.Quantity != from.Quantity || existing.ShippedQuantity != from.ShippedQuantity {
+		return ErrOrderChanged
+	}
+
+	existing.OrderStatus = order.OrderStatus
+	existing.Quantity = order.Quantity
+	existing.TotalPrice = order.TotalPrice
+	existing.ShippedQuantity = order.ShippedQuantity
+	existing.BackorderedQuantity = order.BackorderedQuantity
+	existing.CancelledQuantity = order.CancelledQuantity
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	db.orders[order.Id] = existing
+	return nil
+}
+
+func (db *memoryOrderRepository) UpdateOrderItem(ctx context.Context, order, from models.Orders) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.orders[order.Id]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if existing.OrderStatus != from.OrderStatus || existing.ProductSellingID != from.ProductSellingID || existing.Quantity != from.Quantity {
+		return ErrOrderChanged
+	}
+
+	existing.ProductSellingID = order.ProductSellingID
+	existing.Quantity = order.Quantity
+	existing.TotalPrice = order.TotalPrice
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	db.orders[order.Id] = existing
+	return nil
+}
+
+func (db *memoryOrderRepository) UserCartItem(ctx context.Context, userId primitive.ObjectID) ([]models.OrderCarts, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	orderCarts := []models.OrderCarts{}
+	for _, cart := range db.carts {
+		if cart.UserId == userId {
+			orderCarts = append(orderCarts, cart)
+		}
+	}
+	return orderCarts, nil
+}
+
+func (db *memoryOrderRepository) CountCartItems(ctx context.Context) (int64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return int64(len(db.carts)), nil
+}
+
+func (db *memoryOrderRepository) GetStaleCartLines(ctx context.Context, before time.Time, limit int64) ([]models.OrderCarts, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	cutoff := primitive.NewDateTimeFromTime(before)
+	lines := []models.OrderCarts{}
+	for _, cart := range db.carts {
+		if cart.UpdatedAt < cutoff {
+			lines = append(lines, cart)
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].UpdatedAt < lines[j].UpdatedAt
+	})
+	if int64(len(lines)) > limit {
+		lines = lines[:limit]
+	}
+	return lines, nil
+}
+
+// lines whose order is gone are left out, like the $lookup and $unwind of the mongo pipeline
+func (db *memoryOrderRepository) GetAbandonedCarts(ctx context.Context, idleSince time.Time, minValue float64, unreported bool, limit int64) ([]models.AbandonedCart, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	byUser := map[primitive.ObjectID]*models.AbandonedCart{}
+	var users []primitive.ObjectID
+	for _, cart := range db.carts {
+		order, ok := db.orders[cart.OrderId]
+		if !ok {
+			continue
+		}
+
+		abandoned, ok := byUser[cart.UserId]
+		if !ok {
+			abandoned = &models.AbandonedCart{UserId: cart.UserId, Notified: true}
+			byUser[cart.UserId] = abandoned
+			users = append(users, cart.UserId)
+		}
+		abandoned.OrderIds = append(abandoned.OrderIds, cart.OrderId)
+		abandoned.Lines++
+		abandoned.Value += order.TotalPrice
+		if cart.UpdatedAt > abandoned.LastActivityAt {
+			abandoned.LastActivityAt = cart.UpdatedAt
+		}
+		if cart.AbandonedAt == 0 {
+			abandoned.Notified = false
+		}
+	}
+
+	cutoff := primitive.NewDateTimeFromTime(idleSince)
+	carts := []models.AbandonedCart{}
+	for _, userId := range users {
+		abandoned := byUser[userId]
+		if unreported && abandoned.Notified {
+			continue
+		}
+		if abandoned.LastActivityAt < cutoff && abandoned.Value >= minValue {
+			carts = append(carts, *abandoned)
+		}
+	}
+
+	sort.SliceStable(carts, func(i, j int) bool {
+		return carts[i].LastActivityAt < carts[j].LastActivityAt
+	})
+	if int64(len(carts)) > limit {
+		carts = carts[:limit]
+	}
+	return carts, nil
+}
+
+func (db *memoryOrderRepository) MarkCartAbandoned(ctx context.Context, userId primitive.ObjectID, at time.Time) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	marked := false
+	for i, cart := range db.carts {
+		if cart.UserId == userId && cart.AbandonedAt == 0 {
+			db.carts[i].AbandonedAt = primitive.NewDateTimeFromTime(at)
+			marked = true
+		}
+	}
+	return marked, nil
+}
+
+func (db *memoryOrderRepository) UpdateOrderStatus(ctx context.Context, status string, orderId primitive.ObjectID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	order, ok := db.orders[orderId]
+	if !ok {
+		return ErrOrderNotFound
+	}
+
+	order.OrderStatus = status
+	order.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	db.orders[orderId] = order
+	return nil
+}
+
+func (db *memoryOrderRepository) ChangeOrderStatus(ctx context.Context, orderId primitive.ObjectID, from, status string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	order, ok := db.orders[orderId]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if order.OrderStatus != from {
+		return ErrOrderChanged
+	}
+
+	order.OrderStatus = status
+	order.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	db.orders[orderId] = order
+	return nil
+}
+
+func (db *memoryOrderRepository) MarkOrderRestocked(ctx context.Context, orderId primitive.ObjectID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	order, ok := db.orders[orderId]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if order.Restocked {
+		return ErrOrderChanged
+	}
+
+	order.Restocked = true
+	order.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	db.orders[orderId] = order
+	return nil
+}
+
+func (db *memoryOrderRepository) CreateOrderTrack(ctx context.Context, orderTrack models.OrderTrack) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if orderTrack.Id.IsZero() {
+		orderTrack.Id = primitive.NewObjectID()
+	}
+
+	db.tracks = append(db.tracks, orderTrack)
+	return nil
+}
+
+func (db *memoryOrderRepository) CreateOrderHistory(ctx context.Context, orderData models.OrderHistory) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if orderData.Id.IsZero() {
+		orderData.Id = primitive.NewObjectID()
+	}
+
+	db.history = append(db.history, orderData)
+	return nil
+}
+
+func (db *memoryOrderRepository) GetOrderHistory(ctx context.Context, orderId primitive.ObjectID) (models.OrderHistory, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, history := range db.history {
+		if history.Order.Id == orderId {
+			return history, nil
+		}
+	}
+	return models.OrderHistory{}, ErrOrderNotFound
+}
+
+func (db *memoryOrderRepository) GetAllOrderTrack(ctx context.Context, orderId primitive.ObjectID) ([]models.OrderTrack, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	trackData := []models.OrderTrack{}
+	for _, track := range db.tracks {
+		if track.OrderId == orderId {
+			trackData = append(trackData, track)
+		}
+	}
+	return trackData, nil
+}
+
+func (db *memoryOrderRepository) GetOrderTracksAfter(ctx context.Context, orderIds []primitive.ObjectID, afterId primitive.ObjectID) ([]models.OrderTrack, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	wanted := make(map[primitive.ObjectID]bool, len(orderIds))
+	for _, id := range orderIds {
+		wanted[id] = true
+	}
+
+	trackData := []models.OrderTrack{}
+	for _, track := range db.tracks {
+		if wanted[track.OrderId] && bytes.Compare(track.Id[:], afterId[:]) > 0 {
+			trackData = append(trackData, track)
+		}
+	}
+
+	sort.Slice(trackData, func(i, j int) bool {
+		return bytes.Compare(trackData[i].Id[:], trackData[j].Id[:]) < 0
+	})
+	return trackData, nil
+}
+
+func (db *memoryOrderRepository) GetOrderById(ctx context.Context, orderId primitive.ObjectID) (models.Orders, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	order, ok := db.orders[orderId]
+	if !ok {
+		return models.Orders{}, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (db *memoryOrderRepository) GetOrdersByUser(ctx context.Context, userId primitive.ObjectID, status string) ([]models.Orders, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	orders := []models.Orders{}
+	for _, order := range db.orders {
+		if order.UserId == userId && (status == "" || order.OrderStatus == status) {
+			orders = append(orders, order)
+		}
+	}
+
+	// newest first, object ids grow with creation time
+	sort.Slice(orders, func(i, j int) bool {
+		return bytes.Compare(orders[i].Id[:], orders[j].Id[:]) > 0
+	})
+	return orders, nil
+}
+
+func (db *memoryOrderRepository) CountUserOrders(ctx context.Context, userId primitive.ObjectID, statuses []string) (int64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var count int64
+	for _, order := range db.orders {
+		if order.UserId == userId && containsStatus(statuses, order.OrderStatus) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *memoryOrderRepository) SumUserProductUnits(ctx context.Context, userId, productId primitive.ObjectID, since time.Time, excluded []string) (int64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	cutoff := primitive.NewDateTimeFromTime(since)
+	var units int64
+	for _, order := range db.orders {
+		if order.UserId == userId && order.ProductId == productId && order.CreatedAt >= cutoff && !containsStatus(excluded, order.OrderStatus) {
+			units += order.Quantity
+		}
+	}
+	for _, history := range db.history {
+		if order := history.Order; order.UserId == userId && order.ProductId == productId && order.CreatedAt >= cutoff {
+			units += order.Quantity
+		}
+	}
+	return units, nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *memoryOrderRepository) GetStaleOrders(ctx context.Context, status string, before time.Time, limit int64) ([]models.Orders, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	cutoff := primitive.NewDateTimeFromTime(before)
+	orders := []models.Orders{}
+	for _, order := range db.orders {
+		if order.OrderStatus == status && order.UpdatedAt < cutoff {
+			orders = append(orders, order)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].UpdatedAt < orders[j].UpdatedAt
+	})
+	if int64(len(orders)) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (db *memoryOrderRepository) DeleteOrderFromTrack(ctx context.Context, orderId []primitive.ObjectID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	remove := map[primitive.ObjectID]bool{}
+	for _, id := range orderId {
+		remove[id] = true
+	}
+
+	kept := db.tracks[:0]
+	for _, track := range db.tracks {
+		if !remove[track.OrderId] {
+			kept = append(kept, track)
+		}
+	}
+	db.tracks = kept
+	return nil
+}
@@ -3,40 +3,58 @@ package repositories
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/metrics"
 	"github.com/aniket0951/order-services/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (db *orderRepository) Init() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.TODO(), 5*time.Second)
+// Init derive the operation context from the caller's, bounded by the configured timeout for the method
+func (db *orderRepository) Init(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	return operationContext(ctx, db.timeouts, method)
+}
+
+func operationContext(ctx context.Context, timeouts config.MongoConfig, method string) (context.Context, context.CancelFunc) {
+	timeout := timeouts.OperationTimeout
+	if override, ok := timeouts.OperationTimeouts[method]; ok && override > 0 {
+		timeout = override
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // place a single order
-func (db *orderRepository) PlaceSingleOrder(order models.Orders) (*mongo.InsertOneResult, error) {
-	ctx, cancel := db.Init()
+func (db *orderRepository) PlaceSingleOrder(ctx context.Context, order models.Orders) (res *mongo.InsertOneResult, err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "PlaceSingleOrder")(&err)
+	ctx, cancel := db.Init(ctx, "PlaceSingleOrder")
 	defer cancel()
 
-	res, err := db.ordersCollection.InsertOne(ctx, order)
-	fmt.Println("inserted id from repo : ", res.InsertedID)
-	return res, err
+	res, err = db.ordersCollection.InsertOne(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	db.logger.DebugContext(ctx, "order inserted", "order_id", order.Id.Hex(), "user_id", order.UserId.Hex())
+	return res, nil
 }
 
-func (db *orderRepository) AddToCartOrder(order models.OrderCarts) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) AddToCartOrder(ctx context.Context, order models.OrderCarts) (err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "AddToCartOrder")(&err)
+	ctx, cancel := db.Init(ctx, "AddToCartOrder")
 	defer cancel()
 
-	_, err := db.orderCartCollection.InsertOne(ctx, order)
+	_, err = db.orderCartCollection.InsertOne(ctx, order)
 	return err
 }
 
 // validate order can not be in cart already
-func (db *orderRepository) CheckDuplicateOrderToAddCart(orderId, userId primitive.ObjectID) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) CheckDuplicateOrderToAddCart(ctx context.Context, orderId, userId primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "CheckDuplicateOrderToAddCart")(&err)
+	ctx, cancel := db.Init(ctx, "CheckDuplicateOrderToAddCart")
 	defer cancel()
 
 	filter := bson.D{
@@ -55,33 +73,36 @@ func (db *orderRepository) CheckDuplicateOrderToAddCart(orderId, userId primitiv
 }
 
 // remove oder from cart
-func (db *orderRepository) DeleteCartOrder(orderId primitive.ObjectID) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) DeleteCartOrder(ctx context.Context, orderId primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "DeleteCartOrder")(&err)
+	ctx, cancel := db.Init(ctx, "DeleteCartOrder")
 	defer cancel()
 
 	filter := bson.D{
 		bson.E{Key: "order_id", Value: orderId},
 	}
 
-	_, err := db.orderCartCollection.DeleteOne(ctx, filter)
+	_, err = db.orderCartCollection.DeleteOne(ctx, filter)
 	return err
 }
 
 // delete original order from main collection
-func (db *orderRepository) DeleteOrder(orderId primitive.ObjectID) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) DeleteOrder(ctx context.Context, orderId primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "DeleteOrder")(&err)
+	ctx, cancel := db.Init(ctx, "DeleteOrder")
 	defer cancel()
 
 	filter := bson.D{
 		bson.E{Key: "_id", Value: orderId},
 	}
-	_, err := db.ordersCollection.DeleteOne(ctx, filter)
+	_, err = db.ordersCollection.DeleteOne(ctx, filter)
 	return err
 }
 
 // user can update order before placed like to decrease quantity or change color
-func (db *orderRepository) UpdateOrderQuantityAndPrice(order models.Orders) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) UpdateOrderQuantityAndPrice(ctx context.Context, order models.Orders) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "UpdateOrderQuantityAndPrice")(&err)
+	ctx, cancel := db.Init(ctx, "UpdateOrderQuantityAndPrice")
 	defer cancel()
 
 	filter := bson.D{
@@ -97,19 +118,104 @@ func (db *orderRepository) UpdateOrderQuantityAndPrice(order models.Orders) erro
 
 	res, err := db.ordersCollection.UpdateOne(ctx, filter, update)
 
+	if err != nil {
+		return err
+	}
+
 	if res.MatchedCount == 0 {
-		return errors.New("order not found to update")
+		return ErrOrderNotFound
 	}
-	return err
+	return nil
+}
+
+func (db *orderRepository) UpdateOrderUnits(ctx context.Context, order, from models.Orders) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "UpdateOrderUnits")(&err)
+	ctx, cancel := db.Init(ctx, "UpdateOrderUnits")
+	defer cancel()
+
+	// orders stored before units were tracked have no shipped_quantity yet
+	var shipped interface{} = from.ShippedQuantity
+	if from.ShippedQuantity == 0 {
+		shipped = bson.D{bson.E{Key: "$in", Value: bson.A{0, nil}}}
+	}
+
+	filter := bson.D{
+		bson.E{Key: "_id", Value: order.Id},
+		bson.E{Key: "order_status", Value: from.OrderStatus},
+		bson.E{Key: "quantity", Value: from.Quantity},
+		bson.E{Key: "shipped_quantity", Value: shipped},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "order_status", Value: order.OrderStatus},
+			bson.E{Key: "quantity", Value: order.Quantity},
+			bson.E{Key: "total_price", Value: order.TotalPrice},
+			bson.E{Key: "shipped_quantity", Value: order.ShippedQuantity},
+			bson.E{Key: "backordered_quantity", Value: order.BackorderedQuantity},
+			bson.E{Key: "cancelled_quantity", Value: order.CancelledQuantity},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+		}},
+	}
+
+	res, err := db.ordersCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, err := db.GetOrderById(ctx, order.Id); err != nil {
+			return err
+		}
+		return ErrOrderChanged
+	}
+	return nil
 }
 
-func (db *orderRepository) UserCartItem(userId primitive.ObjectID) ([]models.OrderCarts, error) {
+func (db *orderRepository) UpdateOrderItem(ctx context.Context, order, from models.Orders) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "UpdateOrderItem")(&err)
+	ctx, cancel := db.Init(ctx, "UpdateOrderItem")
+	defer cancel()
+
+	filter := bson.D{
+		bson.E{Key: "_id", Value: order.Id},
+		bson.E{Key: "order_status", Value: from.OrderStatus},
+		bson.E{Key: "prod_selling_id", Value: from.ProductSellingID},
+		bson.E{Key: "quantity", Value: from.Quantity},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "prod_selling_id", Value: order.ProductSellingID},
+			bson.E{Key: "quantity", Value: order.Quantity},
+			bson.E{Key: "total_price", Value: order.TotalPrice},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+		}},
+	}
+
+	res, err := db.ordersCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, err := db.GetOrderById(ctx, order.Id); err != nil {
+			return err
+		}
+		return ErrOrderChanged
+	}
+	return nil
+}
+
+// every line in the user's cart, cart lines carry no order status so the
+// user alone selects them
+func (db *orderRepository) UserCartItem(ctx context.Context, userId primitive.ObjectID) (orderCarts []models.OrderCarts, err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "UserCartItem")(&err)
 	filter := bson.D{
-		bson.E{Key: "order_status", Value: "CART"},
 		bson.E{Key: "user_id", Value: userId},
 	}
 
-	ctx, cancel := db.Init()
+	ctx, cancel := db.Init(ctx, "UserCartItem")
 	defer cancel()
 
 	cursor, curErr := db.orderCartCollection.Find(ctx, filter)
@@ -118,18 +224,133 @@ func (db *orderRepository) UserCartItem(userId primitive.ObjectID) ([]models.Ord
 		return nil, curErr
 	}
 
-	var orderCarts []models.OrderCarts
-
-	if err := cursor.All(context.TODO(), &orderCarts); err != nil {
+	if err = cursor.All(ctx, &orderCarts); err != nil {
 		return nil, err
 	}
 
 	return orderCarts, nil
 }
 
+// count every line currently in a cart, across users
+func (db *orderRepository) CountCartItems(ctx context.Context) (count int64, err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "CountCartItems")(&err)
+	ctx, cancel := db.Init(ctx, "CountCartItems")
+	defer cancel()
+
+	return db.orderCartCollection.EstimatedDocumentCount(ctx)
+}
+
+func (db *orderRepository) GetStaleCartLines(ctx context.Context, before time.Time, limit int64) (lines []models.OrderCarts, err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "GetStaleCartLines")(&err)
+	filter := bson.D{
+		bson.E{Key: "updated_at", Value: bson.D{bson.E{Key: "$lt", Value: primitive.NewDateTimeFromTime(before)}}},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetStaleCartLines")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "updated_at", Value: 1}}).SetLimit(limit)
+	cursor, curErr := db.orderCartCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	lines = []models.OrderCarts{}
+	if err = cursor.All(ctx, &lines); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// group the cart lines per user and price them from their orders
+func (db *orderRepository) GetAbandonedCarts(ctx context.Context, idleSince time.Time, minValue float64, unreported bool, limit int64) (carts []models.AbandonedCart, err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "GetAbandonedCarts")(&err)
+	match := bson.D{
+		bson.E{Key: "last_activity_at", Value: bson.D{bson.E{Key: "$lt", Value: primitive.NewDateTimeFromTime(idleSince)}}},
+		bson.E{Key: "value", Value: bson.D{bson.E{Key: "$gte", Value: minValue}}},
+	}
+	if unreported {
+		match = append(match, bson.E{Key: "unreported", Value: bson.D{bson.E{Key: "$gt", Value: 0}}})
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{bson.E{Key: "$lookup", Value: bson.D{
+			bson.E{Key: "from", Value: db.ordersCollection.Name()},
+			bson.E{Key: "localField", Value: "order_id"},
+			bson.E{Key: "foreignField", Value: "_id"},
+			bson.E{Key: "as", Value: "order"},
+		}}},
+		bson.D{bson.E{Key: "$unwind", Value: "$order"}},
+		bson.D{bson.E{Key: "$group", Value: bson.D{
+			bson.E{Key: "_id", Value: "$user_id"},
+			bson.E{Key: "order_ids", Value: bson.D{bson.E{Key: "$push", Value: "$order_id"}}},
+			bson.E{Key: "lines", Value: bson.D{bson.E{Key: "$sum", Value: 1}}},
+			bson.E{Key: "value", Value: bson.D{bson.E{Key: "$sum", Value: "$order.total_price"}}},
+			bson.E{Key: "last_activity_at", Value: bson.D{bson.E{Key: "$max", Value: "$updated_at"}}},
+			bson.E{Key: "unreported", Value: bson.D{bson.E{Key: "$sum", Value: bson.D{bson.E{Key: "$cond", Value: bson.A{
+				bson.D{bson.E{Key: "$ifNull", Value: bson.A{"$abandoned_at", false}}}, 0, 1,
+			}}}}}},
+		}}},
+		bson.D{bson.E{Key: "$match", Value: match}},
+		bson.D{bson.E{Key: "$sort", Value: bson.D{bson.E{Key: "last_activity_at", Value: 1}}}},
+		bson.D{bson.E{Key: "$limit", Value: limit}},
+		bson.D{bson.E{Key: "$project", Value: bson.D{
+			bson.E{Key: "_id", Value: 0},
+			bson.E{Key: "user_id", Value: "$_id"},
+			bson.E{Key: "order_ids", Value: 1},
+			bson.E{Key: "lines", Value: 1},
+			bson.E{Key: "value", Value: 1},
+			bson.E{Key: "last_activity_at", Value: 1},
+			bson.E{Key: "notified", Value: bson.D{bson.E{Key: "$eq", Value: bson.A{"$unreported", 0}}}},
+		}}},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetAbandonedCarts")
+	defer cancel()
+
+	cursor, curErr := db.orderCartCollection.Aggregate(ctx, pipeline)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	carts = []models.AbandonedCart{}
+	if err = cursor.All(ctx, &carts); err != nil {
+		return nil, err
+	}
+
+	return carts, nil
+}
+
+func (db *orderRepository) MarkCartAbandoned(ctx context.Context, userId primitive.ObjectID, at time.Time) (marked bool, err error) {
+	defer metrics.ObserveRepository(db.orderCartCollection.Name(), "MarkCartAbandoned")(&err)
+	ctx, cancel := db.Init(ctx, "MarkCartAbandoned")
+	defer cancel()
+
+	filter := bson.D{
+		bson.E{Key: "user_id", Value: userId},
+		bson.E{Key: "abandoned_at", Value: bson.D{bson.E{Key: "$exists", Value: false}}},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "abandoned_at", Value: primitive.NewDateTimeFromTime(at)},
+		}},
+	}
+
+	res, err := db.orderCartCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
 // update order status live in orders collection
-func (db *orderRepository) UpdateOrderStatus(status string, orderId primitive.ObjectID) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) UpdateOrderStatus(ctx context.Context, status string, orderId primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "UpdateOrderStatus")(&err)
+	ctx, cancel := db.Init(ctx, "UpdateOrderStatus")
 	defer cancel()
 
 	filter := bson.D{
@@ -139,78 +360,316 @@ func (db *orderRepository) UpdateOrderStatus(status string, orderId primitive.Ob
 	update := bson.D{
 		bson.E{Key: "$set", Value: bson.D{
 			bson.E{Key: "order_status", Value: status},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
 		}},
 	}
 
-	_, err := db.ordersCollection.UpdateOne(ctx, filter, update)
-	return err
+	res, err := db.ordersCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+func (db *orderRepository) ChangeOrderStatus(ctx context.Context, orderId primitive.ObjectID, from, status string) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "ChangeOrderStatus")(&err)
+	ctx, cancel := db.Init(ctx, "ChangeOrderStatus")
+	defer cancel()
+
+	filter := bson.D{
+		bson.E{Key: "_id", Value: orderId},
+		bson.E{Key: "order_status", Value: from},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "order_status", Value: status},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+		}},
+	}
+
+	res, err := db.ordersCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, err := db.GetOrderById(ctx, orderId); err != nil {
+			return err
+		}
+		return ErrOrderChanged
+	}
+	return nil
+}
+
+func (db *orderRepository) MarkOrderRestocked(ctx context.Context, orderId primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "MarkOrderRestocked")(&err)
+	ctx, cancel := db.Init(ctx, "MarkOrderRestocked")
+	defer cancel()
+
+	filter := bson.D{
+		bson.E{Key: "_id", Value: orderId},
+		bson.E{Key: "restocked", Value: bson.D{bson.E{Key: "$ne", Value: true}}},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "restocked", Value: true},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+		}},
+	}
+
+	res, err := db.ordersCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, err := db.GetOrderById(ctx, orderId); err != nil {
+			return err
+		}
+		return ErrOrderChanged
+	}
+	return nil
 }
 
 // create order track
-func (db *orderRepository) CreateOrderTrack(orderTrack models.OrderTrack) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) CreateOrderTrack(ctx context.Context, orderTrack models.OrderTrack) (err error) {
+	defer metrics.ObserveRepository(db.orderTrackCollection.Name(), "CreateOrderTrack")(&err)
+	ctx, cancel := db.Init(ctx, "CreateOrderTrack")
 	defer cancel()
 
-	_, err := db.orderTrackCollection.InsertOne(ctx, orderTrack)
+	_, err = db.orderTrackCollection.InsertOne(ctx, orderTrack)
 	return err
 }
 
-func (db *orderRepository) CreateOrderHistory(orderData models.OrderHistory) error {
-	ctx, cancel := db.Init()
+func (db *orderRepository) CreateOrderHistory(ctx context.Context, orderData models.OrderHistory) (err error) {
+	defer metrics.ObserveRepository(db.orderHistoryCollection.Name(), "CreateOrderHistory")(&err)
+	ctx, cancel := db.Init(ctx, "CreateOrderHistory")
 	defer cancel()
 
-	_, err := db.orderHistoryCollection.InsertOne(ctx, orderData)
+	_, err = db.orderHistoryCollection.InsertOne(ctx, orderData)
 	return err
 }
 
-func (db *orderRepository) GetAllOrderTrack(orderId primitive.ObjectID) ([]models.OrderTrack, error) {
+// archived order by the id it had while live
+func (db *orderRepository) GetOrderHistory(ctx context.Context, orderId primitive.ObjectID) (history models.OrderHistory, err error) {
+	defer metrics.ObserveRepository(db.orderHistoryCollection.Name(), "GetOrderHistory")(&err)
+	filter := bson.D{
+		bson.E{Key: "order._id", Value: orderId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetOrderHistory")
+	defer cancel()
+
+	err = db.orderHistoryCollection.FindOne(ctx, filter).Decode(&history)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return history, ErrOrderNotFound
+	}
+	return history, err
+}
+
+func (db *orderRepository) GetAllOrderTrack(ctx context.Context, orderId primitive.ObjectID) (trackData []models.OrderTrack, err error) {
+	defer metrics.ObserveRepository(db.orderTrackCollection.Name(), "GetAllOrderTrack")(&err)
 	filter := bson.D{
 		bson.E{Key: "order_id", Value: orderId},
 	}
 
-	ctx, cancel := db.Init()
+	ctx, cancel := db.Init(ctx, "GetAllOrderTrack")
 	defer cancel()
 
-	cursor, curErr := db.orderTrackCollection.Find(ctx, filter)
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}})
+	cursor, curErr := db.orderTrackCollection.Find(ctx, filter, opts)
 
 	if curErr != nil {
 		return nil, curErr
 	}
 
-	var trackData []models.OrderTrack
+	if err = cursor.All(ctx, &trackData); err != nil {
+		return nil, err
+	}
+
+	return trackData, nil
+}
+
+// track entries of the orders created after afterId, oldest first
+func (db *orderRepository) GetOrderTracksAfter(ctx context.Context, orderIds []primitive.ObjectID, afterId primitive.ObjectID) (trackData []models.OrderTrack, err error) {
+	defer metrics.ObserveRepository(db.orderTrackCollection.Name(), "GetOrderTracksAfter")(&err)
+	filter := bson.D{
+		bson.E{Key: "order_id", Value: bson.M{"$in": orderIds}},
+		bson.E{Key: "_id", Value: bson.M{"$gt": afterId}},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetOrderTracksAfter")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}})
+	cursor, curErr := db.orderTrackCollection.Find(ctx, filter, opts)
 
-	if err := cursor.All(context.TODO(), &trackData); err != nil {
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	trackData = []models.OrderTrack{}
+	if err = cursor.All(ctx, &trackData); err != nil {
 		return nil, err
 	}
 
 	return trackData, nil
 }
 
-func (db *orderRepository) GetOrderById(orderId primitive.ObjectID) (models.Orders, error) {
+func (db *orderRepository) GetOrderById(ctx context.Context, orderId primitive.ObjectID) (order models.Orders, err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "GetOrderById")(&err)
 	filter := bson.D{
 		bson.E{Key: "_id", Value: orderId},
 	}
 
-	ctx, cancel := db.Init()
+	ctx, cancel := db.Init(ctx, "GetOrderById")
 	defer cancel()
 
-	var order models.Orders
-
-	err := db.ordersCollection.FindOne(ctx, filter).Decode(&order)
+	err = db.ordersCollection.FindOne(ctx, filter).Decode(&order)
 
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return order, ErrOrderNotFound
+	}
 	return order, err
 }
 
-func (db *orderRepository) DeleteOrderFromTrack(orderId []primitive.ObjectID) error {
+// live orders of the user, newest first, optionally narrowed to one status
+func (db *orderRepository) GetOrdersByUser(ctx context.Context, userId primitive.ObjectID, status string) (orders []models.Orders, err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "GetOrdersByUser")(&err)
+	filter := bson.D{
+		bson.E{Key: "user_id", Value: userId},
+	}
+	if status != "" {
+		filter = append(filter, bson.E{Key: "order_status", Value: status})
+	}
+
+	ctx, cancel := db.Init(ctx, "GetOrdersByUser")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: -1}})
+	cursor, curErr := db.ordersCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	orders = []models.Orders{}
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (db *orderRepository) CountUserOrders(ctx context.Context, userId primitive.ObjectID, statuses []string) (count int64, err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "CountUserOrders")(&err)
+	filter := bson.D{
+		bson.E{Key: "user_id", Value: userId},
+		bson.E{Key: "order_status", Value: bson.D{bson.E{Key: "$in", Value: statuses}}},
+	}
+
+	ctx, cancel := db.Init(ctx, "CountUserOrders")
+	defer cancel()
+
+	return db.ordersCollection.CountDocuments(ctx, filter)
+}
+
+func (db *orderRepository) SumUserProductUnits(ctx context.Context, userId, productId primitive.ObjectID, since time.Time, excluded []string) (units int64, err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "SumUserProductUnits")(&err)
+	ctx, cancel := db.Init(ctx, "SumUserProductUnits")
+	defer cancel()
+
+	created := bson.D{bson.E{Key: "$gte", Value: primitive.NewDateTimeFromTime(since)}}
+
+	live, err := sumQuantity(ctx, db.ordersCollection, bson.D{
+		bson.E{Key: "user_id", Value: userId},
+		bson.E{Key: "prod_id", Value: productId},
+		bson.E{Key: "order_status", Value: bson.D{bson.E{Key: "$nin", Value: excluded}}},
+		bson.E{Key: "created_at", Value: created},
+	}, "$quantity")
+	if err != nil {
+		return 0, err
+	}
+
+	archived, err := sumQuantity(ctx, db.orderHistoryCollection, bson.D{
+		bson.E{Key: "order.user_id", Value: userId},
+		bson.E{Key: "order.prod_id", Value: productId},
+		bson.E{Key: "order.created_at", Value: created},
+	}, "$order.quantity")
+	if err != nil {
+		return 0, err
+	}
+
+	return live + archived, nil
+}
+
+// total of field over the documents matching filter
+func sumQuantity(ctx context.Context, coll *mongo.Collection, filter bson.D, field string) (int64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{bson.E{Key: "$match", Value: filter}},
+		bson.D{bson.E{Key: "$group", Value: bson.D{
+			bson.E{Key: "_id", Value: nil},
+			bson.E{Key: "units", Value: bson.D{bson.E{Key: "$sum", Value: field}}},
+		}}},
+	}
+
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	var totals []struct {
+		Units int64 `bson:"units"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil || len(totals) == 0 {
+		return 0, err
+	}
+	return totals[0].Units, nil
+}
+
+func (db *orderRepository) GetStaleOrders(ctx context.Context, status string, before time.Time, limit int64) (orders []models.Orders, err error) {
+	defer metrics.ObserveRepository(db.ordersCollection.Name(), "GetStaleOrders")(&err)
+	filter := bson.D{
+		bson.E{Key: "order_status", Value: status},
+		bson.E{Key: "updated_at", Value: bson.D{bson.E{Key: "$lt", Value: primitive.NewDateTimeFromTime(before)}}},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetStaleOrders")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "updated_at", Value: 1}}).SetLimit(limit)
+	cursor, curErr := db.ordersCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	orders = []models.Orders{}
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (db *orderRepository) DeleteOrderFromTrack(ctx context.Context, orderId []primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.orderTrackCollection.Name(), "DeleteOrderFromTrack")(&err)
 	filter := bson.D{
 		bson.E{Key: "order_id", Value: bson.D{
 			bson.E{Key: "$in", Value: orderId},
 		}},
 	}
 
-	ctx, cancel := db.Init()
+	ctx, cancel := db.Init(ctx, "DeleteOrderFromTrack")
 	defer cancel()
 
-	_, err := db.orderTrackCollection.DeleteMany(ctx, filter)
+	_, err = db.orderTrackCollection.DeleteMany(ctx, filter)
 	return err
 }
@@ -0,0 +1,597 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMemoryOrderRepository(t *testing.T) {
+	runOrderRepositoryConformance(t, func(t *testing.T) OrderRepository {
+		return NewMemoryOrderRepository()
+	})
+}
+
+// runs against a real server only when MONGO_TEST_URI is set, each test gets its own database
+func TestMongoOrderRepository(t *testing.T) {
+	skipWithoutMongo(t)
+
+	runOrderRepositoryConformance(t, func(t *testing.T) OrderRepository {
+		client, cfg := connectTestMongo(t)
+		return NewOrderRepository(client, cfg, logging.Discard())
+	})
+}
+
+func skipWithoutMongo(t *testing.T) {
+	if os.Getenv("MONGO_TEST_URI") == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+}
+
+// client on a fresh database dropped when the test ends
+func connectTestMongo(t *testing.T) (*mongo.Client, *config.Config) {
+	cfg := config.Default()
+	cfg.Mongo.URI = os.Getenv("MONGO_TEST_URI")
+	cfg.Mongo.Database = "order_service_test_" + primitive.NewObjectID().Hex()
+
+	client, err := config.ConnectDB(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Database(cfg.Mongo.Database).Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+	return client, cfg
+}
+
+func newTestOrder(status string) models.Orders {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	return models.Orders{
+		Id:               primitive.NewObjectID(),
+		ProductSellingID: primitive.NewObjectID(),
+		ProductId:        primitive.NewObjectID(),
+		Quantity:         2,
+		Category:         "BOOKS",
+		TotalPrice:       200,
+		UserId:           primitive.NewObjectID(),
+		OrderStatus:      status,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+}
+
+// runOrderRepositoryConformance behaviour every OrderRepository implementation must share
+func runOrderRepositoryConformance(t *testing.T, newRepo func(t *testing.T) OrderRepository) {
+	ctx := context.Background()
+
+	t.Run("place and get order", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("PLACED")
+
+		res, err := repo.PlaceSingleOrder(ctx, order)
+		if err != nil {
+			t.Fatalf("PlaceSingleOrder: %v", err)
+		}
+		if res.InsertedID != order.Id {
+			t.Fatalf("inserted id = %v, want %v", res.InsertedID, order.Id)
+		}
+
+		got, err := repo.GetOrderById(ctx, order.Id)
+		if err != nil {
+			t.Fatalf("GetOrderById: %v", err)
+		}
+		if got != order {
+			t.Fatalf("GetOrderById = %+v, want %+v", got, order)
+		}
+
+		if _, err := repo.PlaceSingleOrder(ctx, order); err == nil {
+			t.Fatal("placing the same order id twice should fail")
+		}
+	})
+
+	t.Run("missing order is not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+
+		if _, err := repo.GetOrderById(ctx, missing); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("GetOrderById err = %v, want ErrOrderNotFound", err)
+		}
+		if err := repo.UpdateOrderStatus(ctx, "DISPATCHED", missing); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("UpdateOrderStatus err = %v, want ErrOrderNotFound", err)
+		}
+		if err := repo.UpdateOrderQuantityAndPrice(ctx, models.Orders{Id: missing}); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("UpdateOrderQuantityAndPrice err = %v, want ErrOrderNotFound", err)
+		}
+		if _, err := repo.GetOrderHistory(ctx, missing); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("GetOrderHistory err = %v, want ErrOrderNotFound", err)
+		}
+		if err := repo.DeleteOrder(ctx, missing); err != nil {
+			t.Fatalf("DeleteOrder of a missing order should be a no-op, got %v", err)
+		}
+		if err := repo.DeleteCartOrder(ctx, missing); err != nil {
+			t.Fatalf("DeleteCartOrder of a missing order should be a no-op, got %v", err)
+		}
+	})
+
+	t.Run("update status quantity and price", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("PLACED")
+		if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.UpdateOrderStatus(ctx, "DISPATCHED", order.Id); err != nil {
+			t.Fatalf("UpdateOrderStatus: %v", err)
+		}
+
+		order.Quantity = 5
+		order.TotalPrice = 500
+		if err := repo.UpdateOrderQuantityAndPrice(ctx, order); err != nil {
+			t.Fatalf("UpdateOrderQuantityAndPrice: %v", err)
+		}
+
+		got, err := repo.GetOrderById(ctx, order.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.OrderStatus != "DISPATCHED" || got.Quantity != 5 || got.TotalPrice != 500 {
+			t.Fatalf("order after updates = %+v", got)
+		}
+		if got.UpdatedAt < order.UpdatedAt {
+			t.Fatalf("status change must bump updated_at, got %v before %v", got.UpdatedAt.Time(), order.UpdatedAt.Time())
+		}
+	})
+
+	t.Run("update units", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("PLACED")
+		if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+
+		shipped := order
+		shipped.OrderStatus = "DISPATCHED"
+		shipped.ShippedQuantity = 1
+		shipped.BackorderedQuantity = 1
+		if err := repo.UpdateOrderUnits(ctx, shipped, order); err != nil {
+			t.Fatalf("UpdateOrderUnits: %v", err)
+		}
+		// a second writer still holding the old order loses
+		if err := repo.UpdateOrderUnits(ctx, shipped, order); !errors.Is(err, ErrOrderChanged) {
+			t.Fatalf("stale UpdateOrderUnits err = %v, want ErrOrderChanged", err)
+		}
+
+		cancelled := shipped
+		cancelled.Quantity = 1
+		cancelled.TotalPrice = 100
+		cancelled.BackorderedQuantity = 0
+		cancelled.CancelledQuantity = 1
+		if err := repo.UpdateOrderUnits(ctx, cancelled, shipped); err != nil {
+			t.Fatalf("UpdateOrderUnits: %v", err)
+		}
+
+		got, err := repo.GetOrderById(ctx, order.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.OrderStatus != "DISPATCHED" || got.Quantity != 1 || got.TotalPrice != 100 || got.ShippedQuantity != 1 ||
+			got.BackorderedQuantity != 0 || got.CancelledQuantity != 1 {
+			t.Fatalf("order after unit updates = %+v", got)
+		}
+
+		missing := newTestOrder("PLACED")
+		if err := repo.UpdateOrderUnits(ctx, missing, missing); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("missing order err = %v, want ErrOrderNotFound", err)
+		}
+	})
+
+	t.Run("change status", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("PLACED")
+		if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.ChangeOrderStatus(ctx, order.Id, "PLACED", "CANCELLED"); err != nil {
+			t.Fatalf("ChangeOrderStatus: %v", err)
+		}
+		// a second writer still holding the placed order loses
+		if err := repo.ChangeOrderStatus(ctx, order.Id, "PLACED", "REFUND_PENDING"); !errors.Is(err, ErrOrderChanged) {
+			t.Fatalf("stale ChangeOrderStatus err = %v, want ErrOrderChanged", err)
+		}
+		if got, _ := repo.GetOrderById(ctx, order.Id); got.OrderStatus != "CANCELLED" {
+			t.Fatalf("status after stale change = %q", got.OrderStatus)
+		}
+
+		if err := repo.ChangeOrderStatus(ctx, primitive.NewObjectID(), "PLACED", "CANCELLED"); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("missing order err = %v, want ErrOrderNotFound", err)
+		}
+	})
+
+	t.Run("mark restocked once", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("REFUND_PENDING")
+		if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.MarkOrderRestocked(ctx, order.Id); err != nil {
+			t.Fatalf("MarkOrderRestocked: %v", err)
+		}
+		if err := repo.MarkOrderRestocked(ctx, order.Id); !errors.Is(err, ErrOrderChanged) {
+			t.Fatalf("second MarkOrderRestocked err = %v, want ErrOrderChanged", err)
+		}
+		if got, _ := repo.GetOrderById(ctx, order.Id); !got.Restocked {
+			t.Fatalf("order not flagged restocked: %+v", got)
+		}
+
+		if err := repo.MarkOrderRestocked(ctx, primitive.NewObjectID()); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("missing order err = %v, want ErrOrderNotFound", err)
+		}
+	})
+
+	t.Run("update item", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("PLACED")
+		if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+
+		edited := order
+		edited.ProductSellingID = primitive.NewObjectID()
+		edited.Quantity = 3
+		edited.TotalPrice = 330
+		if err := repo.UpdateOrderItem(ctx, edited, order); err != nil {
+			t.Fatalf("UpdateOrderItem: %v", err)
+		}
+		if err := repo.UpdateOrderItem(ctx, edited, order); !errors.Is(err, ErrOrderChanged) {
+			t.Fatalf("stale UpdateOrderItem err = %v, want ErrOrderChanged", err)
+		}
+
+		got, err := repo.GetOrderById(ctx, order.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ProductSellingID != edited.ProductSellingID || got.Quantity != 3 || got.TotalPrice != 330 || got.OrderStatus != "PLACED" {
+			t.Fatalf("order after edit = %+v", got)
+		}
+
+		missing := newTestOrder("PLACED")
+		if err := repo.UpdateOrderItem(ctx, missing, missing); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("missing order err = %v, want ErrOrderNotFound", err)
+		}
+	})
+
+	t.Run("delete order", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("PLACED")
+		if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.DeleteOrder(ctx, order.Id); err != nil {
+			t.Fatalf("DeleteOrder: %v", err)
+		}
+		if _, err := repo.GetOrderById(ctx, order.Id); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("deleted order err = %v, want ErrOrderNotFound", err)
+		}
+	})
+
+	t.Run("cart lines", func(t *testing.T) {
+		repo := newRepo(t)
+		userId := primitive.NewObjectID()
+		otherUser := primitive.NewObjectID()
+		first, second := primitive.NewObjectID(), primitive.NewObjectID()
+		now := primitive.NewDateTimeFromTime(time.Now())
+
+		for _, line := range []models.OrderCarts{
+			{OrderId: first, UserId: userId, CreatedAt: now, UpdatedAt: now},
+			{OrderId: second, UserId: userId, CreatedAt: now, UpdatedAt: now},
+			{OrderId: primitive.NewObjectID(), UserId: otherUser, CreatedAt: now, UpdatedAt: now},
+		} {
+			if err := repo.AddToCartOrder(ctx, line); err != nil {
+				t.Fatalf("AddToCartOrder: %v", err)
+			}
+		}
+
+		items, err := repo.UserCartItem(ctx, userId)
+		if err != nil {
+			t.Fatalf("UserCartItem: %v", err)
+		}
+		if len(items) != 2 {
+			t.Fatalf("UserCartItem returned %d lines, want 2", len(items))
+		}
+		for _, item := range items {
+			if item.Id.IsZero() {
+				t.Fatal("cart line should get an id")
+			}
+		}
+
+		if count, err := repo.CountCartItems(ctx); err != nil || count != 3 {
+			t.Fatalf("CountCartItems = %d, %v, want 3", count, err)
+		}
+
+		if err := repo.CheckDuplicateOrderToAddCart(ctx, first, userId); err == nil {
+			t.Fatal("CheckDuplicateOrderToAddCart should reject an order already in the cart")
+		}
+		if err := repo.CheckDuplicateOrderToAddCart(ctx, first, otherUser); err != nil {
+			t.Fatalf("CheckDuplicateOrderToAddCart for another user: %v", err)
+		}
+
+		if err := repo.DeleteCartOrder(ctx, first); err != nil {
+			t.Fatalf("DeleteCartOrder: %v", err)
+		}
+		items, _ = repo.UserCartItem(ctx, userId)
+		if len(items) != 1 || items[0].OrderId != second {
+			t.Fatalf("cart after delete = %+v", items)
+		}
+
+		if items, _ := repo.UserCartItem(ctx, primitive.NewObjectID()); len(items) != 0 {
+			t.Fatalf("empty cart returned %d lines", len(items))
+		}
+	})
+
+	t.Run("cart lines of a user without an order status", func(t *testing.T) {
+		repo := newRepo(t)
+		userId := primitive.NewObjectID()
+		now := primitive.NewDateTimeFromTime(time.Now())
+
+		// stored the way AddToCart stores them, nothing but ids and timestamps
+		line := models.OrderCarts{OrderId: primitive.NewObjectID(), UserId: userId, CreatedAt: now, UpdatedAt: now}
+		if err := repo.AddToCartOrder(ctx, line); err != nil {
+			t.Fatal(err)
+		}
+
+		items, err := repo.UserCartItem(ctx, userId)
+		if err != nil || len(items) != 1 || items[0].OrderId != line.OrderId {
+			t.Fatalf("UserCartItem = %+v, %v", items, err)
+		}
+	})
+
+	t.Run("order track", func(t *testing.T) {
+		repo := newRepo(t)
+		orderId, otherOrder := primitive.NewObjectID(), primitive.NewObjectID()
+
+		for _, track := range []models.OrderTrack{
+			new(models.OrderTrack).SetOrderTrack(orderId, "PLACED"),
+			new(models.OrderTrack).SetOrderTrack(orderId, "DISPATCHED"),
+			new(models.OrderTrack).SetOrderTrack(otherOrder, "PLACED"),
+		} {
+			if err := repo.CreateOrderTrack(ctx, track); err != nil {
+				t.Fatalf("CreateOrderTrack: %v", err)
+			}
+		}
+
+		tracks, err := repo.GetAllOrderTrack(ctx, orderId)
+		if err != nil {
+			t.Fatalf("GetAllOrderTrack: %v", err)
+		}
+		if len(tracks) != 2 || tracks[0].OrderStatus != "PLACED" || tracks[1].OrderStatus != "DISPATCHED" {
+			t.Fatalf("GetAllOrderTrack = %+v", tracks)
+		}
+
+		after, err := repo.GetOrderTracksAfter(ctx, []primitive.ObjectID{orderId, otherOrder}, tracks[0].Id)
+		if err != nil {
+			t.Fatalf("GetOrderTracksAfter: %v", err)
+		}
+		if len(after) != 2 || after[0].Id != tracks[1].Id || after[1].OrderId != otherOrder {
+			t.Fatalf("GetOrderTracksAfter = %+v", after)
+		}
+		if after, _ := repo.GetOrderTracksAfter(ctx, []primitive.ObjectID{orderId}, primitive.NilObjectID); len(after) != 2 {
+			t.Fatalf("GetOrderTracksAfter from the start = %+v", after)
+		}
+
+		if err := repo.DeleteOrderFromTrack(ctx, []primitive.ObjectID{orderId}); err != nil {
+			t.Fatalf("DeleteOrderFromTrack: %v", err)
+		}
+		if tracks, _ := repo.GetAllOrderTrack(ctx, orderId); len(tracks) != 0 {
+			t.Fatalf("track after delete = %+v", tracks)
+		}
+		if tracks, _ := repo.GetAllOrderTrack(ctx, otherOrder); len(tracks) != 1 {
+			t.Fatalf("other order track should be kept, got %+v", tracks)
+		}
+	})
+
+	t.Run("orders by user", func(t *testing.T) {
+		repo := newRepo(t)
+		older := newTestOrder("PLACED")
+		newer := newTestOrder("DISPATCHED")
+		newer.UserId = older.UserId
+		other := newTestOrder("PLACED")
+
+		for _, order := range []models.Orders{older, newer, other} {
+			if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		orders, err := repo.GetOrdersByUser(ctx, older.UserId, "")
+		if err != nil {
+			t.Fatalf("GetOrdersByUser: %v", err)
+		}
+		if len(orders) != 2 || orders[0].Id != newer.Id || orders[1].Id != older.Id {
+			t.Fatalf("GetOrdersByUser = %+v, want newest first", orders)
+		}
+
+		orders, _ = repo.GetOrdersByUser(ctx, older.UserId, "PLACED")
+		if len(orders) != 1 || orders[0].Id != older.Id {
+			t.Fatalf("GetOrdersByUser with status = %+v", orders)
+		}
+
+		if orders, _ := repo.GetOrdersByUser(ctx, primitive.NewObjectID(), ""); len(orders) != 0 {
+			t.Fatalf("unknown user orders = %+v", orders)
+		}
+	})
+
+	t.Run("user order counts", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+		userId, productId := primitive.NewObjectID(), primitive.NewObjectID()
+		open := []string{"PENDING_PAYMENT", "PLACED", "DISPATCHED"}
+		uncounted := []string{"CART", "CANCELLED", "REFUND_PENDING", "REFUNDED"}
+
+		// quantity 2 each: placed today, dispatched today, completed today,
+		// cancelled today, refunded today, placed two days ago, an archived one
+		// and another user's order
+		newUserOrder := func(status string, age time.Duration) models.Orders {
+			order := newTestOrder(status)
+			order.UserId, order.ProductId = userId, productId
+			order.CreatedAt = primitive.NewDateTimeFromTime(now.Add(-age))
+			return order
+		}
+		other := newTestOrder("PLACED")
+		other.ProductId = productId
+		for _, order := range []models.Orders{
+			newUserOrder("PLACED", time.Hour),
+			newUserOrder("DISPATCHED", time.Hour),
+			newUserOrder("COMPLETED", time.Hour),
+			newUserOrder("CANCELLED", time.Hour),
+			newUserOrder("REFUNDED", time.Hour),
+			newUserOrder("PLACED", 48*time.Hour),
+			other,
+		} {
+			if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.CreateOrderHistory(ctx, new(models.OrderHistory).SetOrderHistory(newUserOrder("COMPLETED", 2*time.Hour), nil)); err != nil {
+			t.Fatal(err)
+		}
+
+		if count, err := repo.CountUserOrders(ctx, userId, open); err != nil || count != 3 {
+			t.Fatalf("CountUserOrders = %d, %v", count, err)
+		}
+
+		units, err := repo.SumUserProductUnits(ctx, userId, productId, now.Add(-24*time.Hour), uncounted)
+		if err != nil || units != 8 {
+			t.Fatalf("SumUserProductUnits = %d, %v", units, err)
+		}
+		if units, _ := repo.SumUserProductUnits(ctx, userId, primitive.NewObjectID(), now.Add(-24*time.Hour), uncounted); units != 0 {
+			t.Fatalf("SumUserProductUnits of another product = %d", units)
+		}
+	})
+
+	t.Run("stale orders", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+
+		oldest, older, fresh, otherStatus := newTestOrder("PLACED"), newTestOrder("PLACED"), newTestOrder("PLACED"), newTestOrder("DISPATCHED")
+		oldest.UpdatedAt = primitive.NewDateTimeFromTime(now.Add(-3 * time.Hour))
+		older.UpdatedAt = primitive.NewDateTimeFromTime(now.Add(-2 * time.Hour))
+		otherStatus.UpdatedAt = oldest.UpdatedAt
+
+		for _, order := range []models.Orders{older, fresh, oldest, otherStatus} {
+			if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stale, err := repo.GetStaleOrders(ctx, "PLACED", now.Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatalf("GetStaleOrders: %v", err)
+		}
+		if len(stale) != 2 || stale[0].Id != oldest.Id || stale[1].Id != older.Id {
+			t.Fatalf("GetStaleOrders = %+v", stale)
+		}
+
+		if stale, _ := repo.GetStaleOrders(ctx, "PLACED", now.Add(-time.Hour), 1); len(stale) != 1 || stale[0].Id != oldest.Id {
+			t.Fatalf("limited GetStaleOrders = %+v", stale)
+		}
+	})
+
+	t.Run("stale and abandoned carts", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+
+		// idle is a user whose two lines were last touched 2 and 3 hours ago,
+		// active added a line a minute ago, cheap left a cart worth 10
+		idle, active, cheap := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		addLine := func(userId primitive.ObjectID, price float64, age time.Duration) models.OrderCarts {
+			t.Helper()
+			order := newTestOrder("CART")
+			order.UserId = userId
+			order.TotalPrice = price
+			if _, err := repo.PlaceSingleOrder(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+			at := primitive.NewDateTimeFromTime(now.Add(-age))
+			line := models.OrderCarts{Id: primitive.NewObjectID(), OrderId: order.Id, UserId: userId, CreatedAt: at, UpdatedAt: at}
+			if err := repo.AddToCartOrder(ctx, line); err != nil {
+				t.Fatal(err)
+			}
+			return line
+		}
+
+		oldest := addLine(idle, 100, 3*time.Hour)
+		older := addLine(idle, 50, 2*time.Hour)
+		addLine(active, 500, 5*time.Hour)
+		addLine(active, 500, time.Minute)
+		addLine(cheap, 10, 4*time.Hour)
+
+		stale, err := repo.GetStaleCartLines(ctx, now.Add(-150*time.Minute), 10)
+		if err != nil {
+			t.Fatalf("GetStaleCartLines: %v", err)
+		}
+		if len(stale) != 3 || stale[0].UserId != active || stale[2].Id != oldest.Id {
+			t.Fatalf("GetStaleCartLines = %+v", stale)
+		}
+		if stale, _ := repo.GetStaleCartLines(ctx, now.Add(-150*time.Minute), 1); len(stale) != 1 {
+			t.Fatalf("limited GetStaleCartLines = %+v", stale)
+		}
+
+		carts, err := repo.GetAbandonedCarts(ctx, now.Add(-time.Hour), 20, false, 10)
+		if err != nil {
+			t.Fatalf("GetAbandonedCarts: %v", err)
+		}
+		if len(carts) != 1 || carts[0].UserId != idle || carts[0].Lines != 2 || carts[0].Value != 150 ||
+			carts[0].LastActivityAt != older.UpdatedAt || carts[0].Notified || len(carts[0].OrderIds) != 2 {
+			t.Fatalf("GetAbandonedCarts = %+v", carts)
+		}
+		if carts, _ := repo.GetAbandonedCarts(ctx, now.Add(-time.Hour), 0, false, 10); len(carts) != 2 || carts[0].UserId != cheap {
+			t.Fatalf("GetAbandonedCarts without a minimum = %+v", carts)
+		}
+
+		if marked, err := repo.MarkCartAbandoned(ctx, idle, now); err != nil || !marked {
+			t.Fatalf("MarkCartAbandoned = %v, %v", marked, err)
+		}
+		if marked, err := repo.MarkCartAbandoned(ctx, idle, now); err != nil || marked {
+			t.Fatalf("second MarkCartAbandoned = %v, %v", marked, err)
+		}
+		if carts, _ := repo.GetAbandonedCarts(ctx, now.Add(-time.Hour), 20, false, 10); len(carts) != 1 || !carts[0].Notified {
+			t.Fatalf("GetAbandonedCarts after marking = %+v", carts)
+		}
+		if carts, _ := repo.GetAbandonedCarts(ctx, now.Add(-time.Hour), 0, true, 10); len(carts) != 1 || carts[0].UserId != cheap {
+			t.Fatalf("unreported GetAbandonedCarts = %+v", carts)
+		}
+		if lines, _ := repo.UserCartItem(ctx, idle); len(lines) != 2 || lines[0].AbandonedAt == 0 {
+			t.Fatalf("UserCartItem after marking = %+v", lines)
+		}
+	})
+
+	t.Run("order history", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("COMPLETED")
+		tracks := []models.OrderTrack{new(models.OrderTrack).SetOrderTrack(order.Id, "COMPLETED")}
+
+		if err := repo.CreateOrderHistory(ctx, new(models.OrderHistory).SetOrderHistory(order, tracks)); err != nil {
+			t.Fatalf("CreateOrderHistory: %v", err)
+		}
+
+		history, err := repo.GetOrderHistory(ctx, order.Id)
+		if err != nil {
+			t.Fatalf("GetOrderHistory: %v", err)
+		}
+		if history.Order != order || len(history.OrderTrack) != 1 {
+			t.Fatalf("GetOrderHistory = %+v", history)
+		}
+	})
+}
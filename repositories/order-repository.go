@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
-	"os"
+	"errors"
+	"log/slog"
+	"time"
 
 	"github.com/aniket0951/order-services/config"
 	"github.com/aniket0951/order-services/models"
@@ -10,30 +12,56 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var (
-	ordersConnection       = config.GetCollection(config.DB, os.Getenv("ORDERS"))
-	orderCartConnection    = config.GetCollection(config.DB, os.Getenv("ORDER_CART"))
-	orderTrackConnection   = config.GetCollection(config.DB, os.Getenv("ORDER_TRACK"))
-	orderHistoryConnection = config.GetCollection(config.DB, os.Getenv("ORDER_HISTORY"))
-)
+// ErrOrderNotFound returned by every implementation when the order does not exist
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrOrderChanged the order was changed by someone else since it was read
+var ErrOrderChanged = errors.New("order was changed concurrently, reload it and try again")
 
 type OrderRepository interface {
-	Init() (context.Context, context.CancelFunc)
-	PlaceSingleOrder(order models.Orders) (*mongo.InsertOneResult, error)
-	AddToCartOrder(order models.OrderCarts) error
-	CheckDuplicateOrderToAddCart(orderId, userId primitive.ObjectID) error
-	DeleteCartOrder(orderId primitive.ObjectID) error
-	DeleteOrder(orderId primitive.ObjectID) error
-	UpdateOrderQuantityAndPrice(order models.Orders) error
-
-	UserCartItem(userId primitive.ObjectID) ([]models.OrderCarts, error)
-	UpdateOrderStatus(status string, orderId primitive.ObjectID) error
-	CreateOrderTrack(orderTrack models.OrderTrack) error
-	CreateOrderHistory(orderData models.OrderHistory) error
-
-	GetAllOrderTrack(orderId primitive.ObjectID) ([]models.OrderTrack, error)
-	GetOrderById(orderId primitive.ObjectID) (models.Orders, error)
-	DeleteOrderFromTrack(orderId []primitive.ObjectID) error
+	PlaceSingleOrder(ctx context.Context, order models.Orders) (*mongo.InsertOneResult, error)
+	AddToCartOrder(ctx context.Context, order models.OrderCarts) error
+	CheckDuplicateOrderToAddCart(ctx context.Context, orderId, userId primitive.ObjectID) error
+	DeleteCartOrder(ctx context.Context, orderId primitive.ObjectID) error
+	DeleteOrder(ctx context.Context, orderId primitive.ObjectID) error
+	UpdateOrderQuantityAndPrice(ctx context.Context, order models.Orders) error
+	// UpdateOrderUnits store the status, unit counts and total price of order as long
+	// as the stored order still has the status, quantity and shipped units of from
+	UpdateOrderUnits(ctx context.Context, order, from models.Orders) error
+	// UpdateOrderItem store the variant, quantity and total price of order as long
+	// as the stored order still has the status, variant and quantity of from
+	UpdateOrderItem(ctx context.Context, order, from models.Orders) error
+
+	UserCartItem(ctx context.Context, userId primitive.ObjectID) ([]models.OrderCarts, error)
+	CountCartItems(ctx context.Context) (int64, error)
+	// GetStaleCartLines cart lines not updated since before, oldest first
+	GetStaleCartLines(ctx context.Context, before time.Time, limit int64) ([]models.OrderCarts, error)
+	// GetAbandonedCarts carts whose last change is before idleSince and whose value
+	// is at least minValue, longest idle first. unreported skips notified carts
+	GetAbandonedCarts(ctx context.Context, idleSince time.Time, minValue float64, unreported bool, limit int64) ([]models.AbandonedCart, error)
+	// MarkCartAbandoned stamp the user's unreported cart lines, false when there were none
+	MarkCartAbandoned(ctx context.Context, userId primitive.ObjectID, at time.Time) (bool, error)
+	UpdateOrderStatus(ctx context.Context, status string, orderId primitive.ObjectID) error
+	// ChangeOrderStatus store status as long as the stored order is still in from
+	ChangeOrderStatus(ctx context.Context, orderId primitive.ObjectID, from, status string) error
+	// MarkOrderRestocked flag the units of the order as back in stock, ErrOrderChanged
+	// when they already were
+	MarkOrderRestocked(ctx context.Context, orderId primitive.ObjectID) error
+	CreateOrderTrack(ctx context.Context, orderTrack models.OrderTrack) error
+	CreateOrderHistory(ctx context.Context, orderData models.OrderHistory) error
+	GetOrderHistory(ctx context.Context, orderId primitive.ObjectID) (models.OrderHistory, error)
+
+	GetAllOrderTrack(ctx context.Context, orderId primitive.ObjectID) ([]models.OrderTrack, error)
+	GetOrderTracksAfter(ctx context.Context, orderIds []primitive.ObjectID, afterId primitive.ObjectID) ([]models.OrderTrack, error)
+	GetOrderById(ctx context.Context, orderId primitive.ObjectID) (models.Orders, error)
+	GetOrdersByUser(ctx context.Context, userId primitive.ObjectID, status string) ([]models.Orders, error)
+	CountUserOrders(ctx context.Context, userId primitive.ObjectID, statuses []string) (int64, error)
+	// SumUserProductUnits quantity of the product over the user's orders created
+	// since, live orders in none of excluded plus the archived ones
+	SumUserProductUnits(ctx context.Context, userId, productId primitive.ObjectID, since time.Time, excluded []string) (int64, error)
+	// GetStaleOrders orders in status not updated since before, longest waiting first
+	GetStaleOrders(ctx context.Context, status string, before time.Time, limit int64) ([]models.Orders, error)
+	DeleteOrderFromTrack(ctx context.Context, orderId []primitive.ObjectID) error
 }
 
 type orderRepository struct {
@@ -41,13 +69,17 @@ type orderRepository struct {
 	orderCartCollection    *mongo.Collection
 	orderTrackCollection   *mongo.Collection
 	orderHistoryCollection *mongo.Collection
+	logger                 *slog.Logger
+	timeouts               config.MongoConfig
 }
 
-func NewOrderRepository() OrderRepository {
+func NewOrderRepository(client *mongo.Client, cfg *config.Config, logger *slog.Logger) OrderRepository {
 	return &orderRepository{
-		ordersCollection:       ordersConnection,
-		orderCartCollection:    orderCartConnection,
-		orderTrackCollection:   orderTrackConnection,
-		orderHistoryCollection: orderHistoryConnection,
+		logger:                 logger,
+		timeouts:               cfg.Mongo,
+		ordersCollection:       config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Orders),
+		orderCartCollection:    config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.OrderCart),
+		orderTrackCollection:   config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.OrderTrack),
+		orderHistoryCollection: config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.OrderHistory),
 	}
 }
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryPaymentRepository in-process PaymentRepository, mirrors the mongo implementation
+type memoryPaymentRepository struct {
+	mu       sync.RWMutex
+	payments []models.PaymentAttempt
+	refunds  []models.Refund
+}
+
+func NewMemoryPaymentRepository() PaymentRepository {
+	return &memoryPaymentRepository{}
+}
+
+func (db *memoryPaymentRepository) CreatePaymentAttempt(ctx context.Context, payment models.PaymentAttempt) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if payment.Id.IsZero() {
+		payment.Id = primitive.NewObjectID()
+	}
+
+	for _, existing := range db.payments {
+		if existing.Id == payment.Id {
+			return errDuplicateKey
+		}
+	}
+
+	payment.ClientSecret = ""
+	db.payments = append(db.payments, payment)
+	return nil
+}
+
+func (db *memoryPaymentRepository) GetPaymentByIntent(ctx context.Context, gateway, intentId string) (models.PaymentAttempt, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, payment := range db.payments {
+		if payment.Gateway == gateway && payment.IntentId == intentId {
+			return payment, nil
+		}
+	}
+	return models.PaymentAttempt{}, ErrPaymentNotFound
+}
+
+func (db *memoryPaymentRepository) GetOrderPayments(ctx context.Context, orderId primitive.ObjectID) ([]models.PaymentAttempt, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	payments := []models.PaymentAttempt{}
+	for _, payment := range db.payments {
+		if payment.OrderId == orderId {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (db *memoryPaymentRepository) SettlePayment(ctx context.Context, paymentId primitive.ObjectID, status, failureReason string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, payment := range db.payments {
+		if payment.Id != paymentId || payment.Status != helper.PAYMENT_PENDING {
+			continue
+		}
+
+		db.payments[i].Status = status
+		db.payments[i].UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+		if failureReason != "" {
+			db.payments[i].FailureReason = failureReason
+		}
+		return nil
+	}
+	return ErrPaymentNotFound
+}
+
+func (db *memoryPaymentRepository) CreateRefund(ctx context.Context, refund models.Refund) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if refund.Id.IsZero() {
+		refund.Id = primitive.NewObjectID()
+	}
+
+	for _, existing := range db.refunds {
+		if existing.Id == refund.Id {
+			return errDuplicateKey
+		}
+		if refund.Key != "" && existing.Key == refund.Key {
+			return ErrRefundExists
+		}
+	}
+
+	db.refunds = append(db.refunds, refund)
+	return nil
+}
+
+func (db *memoryPaymentRepository) GetOrderRefunds(ctx context.Context, orderId primitive.ObjectID) ([]models.Refund, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	refunds := []models.Refund{}
+	for _, refund := range db.refunds {
+		if refund.OrderId == orderId {
+			refunds = append(refunds, refund)
+		}
+	}
+	return refunds, nil
+}
+
+func (db *memoryPaymentRepository) GetDueRefunds(ctx context.Context, now time.Time, limit int64) ([]models.Refund, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	due := primitive.NewDateTimeFromTime(now)
+	refunds := []models.Refund{}
+	for _, refund := range db.refunds {
+		if int64(len(refunds)) == limit {
+			break
+		}
+		if refund.Status == helper.PAYMENT_PENDING && refund.NextAttemptAt <= due {
+			refunds = append(refunds, refund)
+		}
+	}
+	return refunds, nil
+}
+
+func (db *memoryPaymentRepository) SaveRefundAttempt(ctx context.Context, refund models.Refund) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, existing := range db.refunds {
+		if existing.Id != refund.Id || existing.Status != helper.PAYMENT_PENDING {
+			continue
+		}
+
+		db.refunds[i].Status = refund.Status
+		db.refunds[i].Attempts = refund.Attempts
+		db.refunds[i].GatewayRefundId = refund.GatewayRefundId
+		db.refunds[i].LastError = refund.LastError
+		db.refunds[i].NextAttemptAt = refund.NextAttemptAt
+		db.refunds[i].UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+		return nil
+	}
+	return ErrRefundNotFound
+}
+
+func (db *memoryPaymentRepository) GetRefundById(ctx context.Context, refundId primitive.ObjectID) (models.Refund, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, refund := range db.refunds {
+		if refund.Id == refundId {
+			return refund, nil
+		}
+	}
+	return models.Refund{}, ErrRefundNotFound
+}
+
+func (db *memoryPaymentRepository) RequeueRefund(ctx context.Context, refundId primitive.ObjectID, nextAttemptAt time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, existing := range db.refunds {
+		if existing.Id != refundId || existing.Status != helper.PAYMENT_FAILED {
+			continue
+		}
+
+		db.refunds[i].Status = helper.PAYMENT_PENDING
+		db.refunds[i].Attempts = 0
+		db.refunds[i].NextAttemptAt = primitive.NewDateTimeFromTime(nextAttemptAt)
+		db.refunds[i].UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+		return nil
+	}
+	return ErrRefundNotFound
+}
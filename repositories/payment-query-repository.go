@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *paymentRepository) Init(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	return operationContext(ctx, db.timeouts, method)
+}
+
+func (db *paymentRepository) CreatePaymentAttempt(ctx context.Context, payment models.PaymentAttempt) (err error) {
+	defer metrics.ObserveRepository(db.paymentsCollection.Name(), "CreatePaymentAttempt")(&err)
+	ctx, cancel := db.Init(ctx, "CreatePaymentAttempt")
+	defer cancel()
+
+	_, err = db.paymentsCollection.InsertOne(ctx, payment)
+	return err
+}
+
+func (db *paymentRepository) GetPaymentByIntent(ctx context.Context, gateway, intentId string) (payment models.PaymentAttempt, err error) {
+	defer metrics.ObserveRepository(db.paymentsCollection.Name(), "GetPaymentByIntent")(&err)
+	filter := bson.D{
+		bson.E{Key: "gateway", Value: gateway},
+		bson.E{Key: "intent_id", Value: intentId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetPaymentByIntent")
+	defer cancel()
+
+	err = db.paymentsCollection.FindOne(ctx, filter).Decode(&payment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.PaymentAttempt{}, ErrPaymentNotFound
+	}
+	return payment, err
+}
+
+// every attempt made for the order, oldest first
+func (db *paymentRepository) GetOrderPayments(ctx context.Context, orderId primitive.ObjectID) (payments []models.PaymentAttempt, err error) {
+	defer metrics.ObserveRepository(db.paymentsCollection.Name(), "GetOrderPayments")(&err)
+	filter := bson.D{
+		bson.E{Key: "order_id", Value: orderId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetOrderPayments")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}})
+	cursor, curErr := db.paymentsCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	payments = []models.PaymentAttempt{}
+	if err = cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+func (db *paymentRepository) SettlePayment(ctx context.Context, paymentId primitive.ObjectID, status, failureReason string) (err error) {
+	defer metrics.ObserveRepository(db.paymentsCollection.Name(), "SettlePayment")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: paymentId},
+		bson.E{Key: "status", Value: helper.PAYMENT_PENDING},
+	}
+
+	fields := bson.D{
+		bson.E{Key: "status", Value: status},
+		bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+	}
+	if failureReason != "" {
+		fields = append(fields, bson.E{Key: "failure_reason", Value: failureReason})
+	}
+
+	ctx, cancel := db.Init(ctx, "SettlePayment")
+	defer cancel()
+
+	res, err := db.paymentsCollection.UpdateOne(ctx, filter, bson.D{bson.E{Key: "$set", Value: fields}})
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrPaymentNotFound
+	}
+	return nil
+}
+
+func (db *paymentRepository) CreateRefund(ctx context.Context, refund models.Refund) (err error) {
+	defer metrics.ObserveRepository(db.refundsCollection.Name(), "CreateRefund")(&err)
+	ctx, cancel := db.Init(ctx, "CreateRefund")
+	defer cancel()
+
+	_, err = db.refundsCollection.InsertOne(ctx, refund)
+	if mongo.IsDuplicateKeyError(err) && refund.Key != "" {
+		return ErrRefundExists
+	}
+	return err
+}
+
+// every refund of the order, oldest first
+func (db *paymentRepository) GetOrderRefunds(ctx context.Context, orderId primitive.ObjectID) (refunds []models.Refund, err error) {
+	defer metrics.ObserveRepository(db.refundsCollection.Name(), "GetOrderRefunds")(&err)
+	filter := bson.D{
+		bson.E{Key: "order_id", Value: orderId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetOrderRefunds")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}})
+	cursor, curErr := db.refundsCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	refunds = []models.Refund{}
+	if err = cursor.All(ctx, &refunds); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+func (db *paymentRepository) GetDueRefunds(ctx context.Context, now time.Time, limit int64) (refunds []models.Refund, err error) {
+	defer metrics.ObserveRepository(db.refundsCollection.Name(), "GetDueRefunds")(&err)
+	filter := bson.D{
+		bson.E{Key: "status", Value: helper.PAYMENT_PENDING},
+		bson.E{Key: "next_attempt_at", Value: bson.D{bson.E{Key: "$lte", Value: primitive.NewDateTimeFromTime(now)}}},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetDueRefunds")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, curErr := db.refundsCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	refunds = []models.Refund{}
+	if err = cursor.All(ctx, &refunds); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+func (db *paymentRepository) SaveRefundAttempt(ctx context.Context, refund models.Refund) (err error) {
+	defer metrics.ObserveRepository(db.refundsCollection.Name(), "SaveRefundAttempt")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: refund.Id},
+		bson.E{Key: "status", Value: helper.PAYMENT_PENDING},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "status", Value: refund.Status},
+			bson.E{Key: "attempts", Value: refund.Attempts},
+			bson.E{Key: "gateway_refund_id", Value: refund.GatewayRefundId},
+			bson.E{Key: "last_error", Value: refund.LastError},
+			bson.E{Key: "next_attempt_at", Value: refund.NextAttemptAt},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+		}},
+	}
+
+	ctx, cancel := db.Init(ctx, "SaveRefundAttempt")
+	defer cancel()
+
+	res, err := db.refundsCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrRefundNotFound
+	}
+	return nil
+}
+
+func (db *paymentRepository) GetRefundById(ctx context.Context, refundId primitive.ObjectID) (refund models.Refund, err error) {
+	defer metrics.ObserveRepository(db.refundsCollection.Name(), "GetRefundById")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: refundId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetRefundById")
+	defer cancel()
+
+	err = db.refundsCollection.FindOne(ctx, filter).Decode(&refund)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Refund{}, ErrRefundNotFound
+	}
+	return refund, err
+}
+
+func (db *paymentRepository) RequeueRefund(ctx context.Context, refundId primitive.ObjectID, nextAttemptAt time.Time) (err error) {
+	defer metrics.ObserveRepository(db.refundsCollection.Name(), "RequeueRefund")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: refundId},
+		bson.E{Key: "status", Value: helper.PAYMENT_FAILED},
+	}
+
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "status", Value: helper.PAYMENT_PENDING},
+			bson.E{Key: "attempts", Value: 0},
+			bson.E{Key: "next_attempt_at", Value: primitive.NewDateTimeFromTime(nextAttemptAt)},
+			bson.E{Key: "updated_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+		}},
+	}
+
+	ctx, cancel := db.Init(ctx, "RequeueRefund")
+	defer cancel()
+
+	res, err := db.refundsCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrRefundNotFound
+	}
+	return nil
+}
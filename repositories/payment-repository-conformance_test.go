@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryPaymentRepository(t *testing.T) {
+	runPaymentRepositoryConformance(t, func(t *testing.T) PaymentRepository {
+		return NewMemoryPaymentRepository()
+	})
+}
+
+func TestMongoPaymentRepository(t *testing.T) {
+	skipWithoutMongo(t)
+
+	runPaymentRepositoryConformance(t, func(t *testing.T) PaymentRepository {
+		client, cfg := connectTestMongo(t)
+		if err := CreatePaymentIndexes(context.Background(), client, cfg); err != nil {
+			t.Fatal(err)
+		}
+		return NewPaymentRepository(client, cfg, logging.Discard())
+	})
+}
+
+func runPaymentRepositoryConformance(t *testing.T, newRepo func(t *testing.T) PaymentRepository) {
+	ctx := context.Background()
+
+	t.Run("create and look up attempts", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder(helper.PENDING_PAYMENT)
+
+		first := new(models.PaymentAttempt).SetPaymentAttempt(order, "mock", "pi_1", "INR", helper.PAYMENT_FAILED)
+		second := new(models.PaymentAttempt).SetPaymentAttempt(order, "mock", "pi_2", "INR", helper.PAYMENT_PENDING)
+		other := new(models.PaymentAttempt).SetPaymentAttempt(newTestOrder(helper.PENDING_PAYMENT), "mock", "pi_3", "INR", helper.PAYMENT_PENDING)
+
+		for _, payment := range []models.PaymentAttempt{first, second, other} {
+			if err := repo.CreatePaymentAttempt(ctx, payment); err != nil {
+				t.Fatalf("CreatePaymentAttempt: %v", err)
+			}
+		}
+
+		payments, err := repo.GetOrderPayments(ctx, order.Id)
+		if err != nil {
+			t.Fatalf("GetOrderPayments: %v", err)
+		}
+		if len(payments) != 2 || payments[0].Id != first.Id || payments[1].Id != second.Id {
+			t.Fatalf("GetOrderPayments = %+v", payments)
+		}
+		if payments[0].Amount != order.TotalPrice || payments[0].UserId != order.UserId {
+			t.Fatalf("stored attempt = %+v", payments[0])
+		}
+
+		found, err := repo.GetPaymentByIntent(ctx, "mock", "pi_2")
+		if err != nil || found.Id != second.Id {
+			t.Fatalf("GetPaymentByIntent = %+v, %v", found, err)
+		}
+
+		if _, err := repo.GetPaymentByIntent(ctx, "other", "pi_2"); !errors.Is(err, ErrPaymentNotFound) {
+			t.Fatalf("intent of another gateway err = %v", err)
+		}
+
+		if payments, _ := repo.GetOrderPayments(ctx, primitive.NewObjectID()); len(payments) != 0 {
+			t.Fatalf("unknown order payments = %+v", payments)
+		}
+	})
+
+	t.Run("settle payment", func(t *testing.T) {
+		repo := newRepo(t)
+		payment := new(models.PaymentAttempt).SetPaymentAttempt(newTestOrder(helper.PENDING_PAYMENT), "mock", "pi_1", "INR", helper.PAYMENT_PENDING)
+		if err := repo.CreatePaymentAttempt(ctx, payment); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.SettlePayment(ctx, payment.Id, helper.PAYMENT_FAILED, "card declined"); err != nil {
+			t.Fatalf("SettlePayment: %v", err)
+		}
+
+		settled, _ := repo.GetPaymentByIntent(ctx, "mock", "pi_1")
+		if settled.Status != helper.PAYMENT_FAILED || settled.FailureReason != "card declined" {
+			t.Fatalf("settled = %+v", settled)
+		}
+
+		// only pending attempts settle, a second callback changes nothing
+		if err := repo.SettlePayment(ctx, payment.Id, helper.PAYMENT_SUCCEEDED, ""); !errors.Is(err, ErrPaymentNotFound) {
+			t.Fatalf("settle twice err = %v", err)
+		}
+		if err := repo.SettlePayment(ctx, primitive.NewObjectID(), helper.PAYMENT_SUCCEEDED, ""); !errors.Is(err, ErrPaymentNotFound) {
+			t.Fatalf("settle unknown err = %v", err)
+		}
+	})
+	t.Run("refunds", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder(helper.REFUND_PENDING)
+		payment := new(models.PaymentAttempt).SetPaymentAttempt(order, "mock", "pi_1", "INR", helper.PAYMENT_SUCCEEDED)
+
+		due := new(models.Refund).SetRefund(payment, helper.REFUND_PARTIAL, 50, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_PENDING)
+		later := new(models.Refund).SetRefund(payment, helper.REFUND_PARTIAL, 150, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_PENDING)
+		later.NextAttemptAt = primitive.NewDateTimeFromTime(time.Now().Add(time.Hour))
+		settled := new(models.Refund).SetRefund(payment, helper.REFUND_FULL, 200, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_SUCCEEDED)
+		other := new(models.Refund).SetRefund(new(models.PaymentAttempt).SetPaymentAttempt(newTestOrder(helper.REFUND_PENDING), "mock", "pi_2", "INR", helper.PAYMENT_SUCCEEDED), helper.REFUND_FULL, 200, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_PENDING)
+
+		for _, refund := range []models.Refund{due, later, settled, other} {
+			if err := repo.CreateRefund(ctx, refund); err != nil {
+				t.Fatalf("CreateRefund: %v", err)
+			}
+		}
+
+		refunds, err := repo.GetOrderRefunds(ctx, order.Id)
+		if err != nil || len(refunds) != 3 || refunds[0].Id != due.Id || refunds[2].Id != settled.Id {
+			t.Fatalf("GetOrderRefunds = %+v, %v", refunds, err)
+		}
+		if refunds[0].PaymentId != payment.Id || refunds[0].IntentId != "pi_1" || refunds[0].Amount != 50 {
+			t.Fatalf("stored refund = %+v", refunds[0])
+		}
+
+		dueNow, err := repo.GetDueRefunds(ctx, time.Now(), 10)
+		if err != nil || len(dueNow) != 2 || dueNow[0].Id != due.Id || dueNow[1].Id != other.Id {
+			t.Fatalf("GetDueRefunds = %+v, %v", dueNow, err)
+		}
+		if limited, _ := repo.GetDueRefunds(ctx, time.Now(), 1); len(limited) != 1 || limited[0].Id != due.Id {
+			t.Fatalf("limited GetDueRefunds = %+v", limited)
+		}
+
+		due.Attempts = 1
+		due.LastError = "gateway unavailable"
+		due.NextAttemptAt = primitive.NewDateTimeFromTime(time.Now().Add(time.Hour))
+		if err := repo.SaveRefundAttempt(ctx, due); err != nil {
+			t.Fatalf("SaveRefundAttempt: %v", err)
+		}
+		if dueNow, _ := repo.GetDueRefunds(ctx, time.Now(), 10); len(dueNow) != 1 || dueNow[0].Id != other.Id {
+			t.Fatalf("rescheduled refund still due: %+v", dueNow)
+		}
+
+		due.Status = helper.PAYMENT_SUCCEEDED
+		due.Attempts = 2
+		due.GatewayRefundId = "re_1"
+		due.LastError = ""
+		if err := repo.SaveRefundAttempt(ctx, due); err != nil {
+			t.Fatalf("SaveRefundAttempt: %v", err)
+		}
+		refunds, _ = repo.GetOrderRefunds(ctx, order.Id)
+		if refunds[0].Status != helper.PAYMENT_SUCCEEDED || refunds[0].Attempts != 2 || refunds[0].GatewayRefundId != "re_1" || refunds[0].LastError != "" {
+			t.Fatalf("saved refund = %+v", refunds[0])
+		}
+
+		// settled refunds are not written again
+		if err := repo.SaveRefundAttempt(ctx, due); !errors.Is(err, ErrRefundNotFound) {
+			t.Fatalf("save settled refund err = %v", err)
+		}
+	})
+
+	t.Run("one refund per key", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder(helper.REFUND_PENDING)
+		payment := new(models.PaymentAttempt).SetPaymentAttempt(order, "mock", "pi_1", "INR", helper.PAYMENT_SUCCEEDED)
+
+		first := new(models.Refund).SetRefund(payment, helper.REFUND_FULL, payment.Amount, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_PENDING)
+		first.Key = "cancel:" + order.Id.Hex()
+		second := new(models.Refund).SetRefund(payment, helper.REFUND_FULL, payment.Amount, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_PENDING)
+		second.Key = first.Key
+		if err := repo.CreateRefund(ctx, first); err != nil {
+			t.Fatalf("CreateRefund: %v", err)
+		}
+		if err := repo.CreateRefund(ctx, second); !errors.Is(err, ErrRefundExists) {
+			t.Fatalf("second refund with the same key err = %v, want ErrRefundExists", err)
+		}
+
+		// refunds without a key may repeat
+		for i := 0; i < 2; i++ {
+			partial := new(models.Refund).SetRefund(payment, helper.REFUND_PARTIAL, 10, helper.REFUND_REASON_EDITED, helper.PAYMENT_PENDING)
+			if err := repo.CreateRefund(ctx, partial); err != nil {
+				t.Fatalf("CreateRefund without key: %v", err)
+			}
+		}
+
+		if refunds, err := repo.GetOrderRefunds(ctx, order.Id); err != nil || len(refunds) != 3 {
+			t.Fatalf("GetOrderRefunds = %+v, %v", refunds, err)
+		}
+	})
+
+	t.Run("requeue failed refund", func(t *testing.T) {
+		repo := newRepo(t)
+		payment := new(models.PaymentAttempt).SetPaymentAttempt(newTestOrder("REFUND_PENDING"), "mock", "pi_1", "INR", helper.PAYMENT_SUCCEEDED)
+		refund := new(models.Refund).SetRefund(payment, helper.REFUND_FULL, payment.Amount, helper.REFUND_REASON_CANCELLED, helper.PAYMENT_PENDING)
+		if err := repo.CreateRefund(ctx, refund); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.RequeueRefund(ctx, refund.Id, time.Now()); !errors.Is(err, ErrRefundNotFound) {
+			t.Fatalf("requeue of a pending refund err = %v", err)
+		}
+
+		refund.Status = helper.PAYMENT_FAILED
+		refund.Attempts = 5
+		refund.LastError = "gateway down"
+		if err := repo.SaveRefundAttempt(ctx, refund); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.RequeueRefund(ctx, refund.Id, time.Now()); err != nil {
+			t.Fatalf("RequeueRefund: %v", err)
+		}
+		got, err := repo.GetRefundById(ctx, refund.Id)
+		if err != nil || got.Status != helper.PAYMENT_PENDING || got.Attempts != 0 || got.LastError != "gateway down" {
+			t.Fatalf("requeued refund = %+v, %v", got, err)
+		}
+		if due, _ := repo.GetDueRefunds(ctx, time.Now(), 10); len(due) != 1 || due[0].Id != refund.Id {
+			t.Fatalf("requeued refund is not due: %+v", due)
+		}
+
+		if _, err := repo.GetRefundById(ctx, primitive.NewObjectID()); !errors.Is(err, ErrRefundNotFound) {
+			t.Fatalf("missing refund err = %v", err)
+		}
+	})
+}
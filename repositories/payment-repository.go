@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrPaymentNotFound no payment attempt matches, or it has already settled
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// ErrRefundNotFound no refund matches, or it is not in the status the write expects
+var ErrRefundNotFound = errors.New("refund not found")
+
+// ErrRefundExists a refund with the same key was already recorded
+var ErrRefundExists = errors.New("refund already requested")
+
+type PaymentRepository interface {
+	CreatePaymentAttempt(ctx context.Context, payment models.PaymentAttempt) error
+	GetPaymentByIntent(ctx context.Context, gateway, intentId string) (models.PaymentAttempt, error)
+	GetOrderPayments(ctx context.Context, orderId primitive.ObjectID) ([]models.PaymentAttempt, error)
+	// SettlePayment move a pending attempt to its final status
+	SettlePayment(ctx context.Context, paymentId primitive.ObjectID, status, failureReason string) error
+
+	// CreateRefund store a pending refund, ErrRefundExists when its key is taken
+	CreateRefund(ctx context.Context, refund models.Refund) error
+	GetOrderRefunds(ctx context.Context, orderId primitive.ObjectID) ([]models.Refund, error)
+	// GetDueRefunds pending refunds whose next attempt is due by now, oldest first
+	GetDueRefunds(ctx context.Context, now time.Time, limit int64) ([]models.Refund, error)
+	// SaveRefundAttempt store the outcome of a gateway call on a pending refund
+	SaveRefundAttempt(ctx context.Context, refund models.Refund) error
+	GetRefundById(ctx context.Context, refundId primitive.ObjectID) (models.Refund, error)
+	// RequeueRefund move a failed refund back to pending with its attempts reset,
+	// due at nextAttemptAt
+	RequeueRefund(ctx context.Context, refundId primitive.ObjectID, nextAttemptAt time.Time) error
+}
+
+type paymentRepository struct {
+	paymentsCollection *mongo.Collection
+	refundsCollection  *mongo.Collection
+	logger             *slog.Logger
+	timeouts           config.MongoConfig
+}
+
+func NewPaymentRepository(client *mongo.Client, cfg *config.Config, logger *slog.Logger) PaymentRepository {
+	return &paymentRepository{
+		logger:             logger,
+		timeouts:           cfg.Mongo,
+		paymentsCollection: config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Payments),
+		refundsCollection:  config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Refunds),
+	}
+}
+
+// CreatePaymentIndexes one refund per key, refunds without a key are not indexed
+func CreatePaymentIndexes(ctx context.Context, client *mongo.Client, cfg *config.Config) error {
+	ctx, cancel := operationContext(ctx, cfg.Mongo, "CreatePaymentIndexes")
+	defer cancel()
+
+	_, err := config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Refunds).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.D{bson.E{Key: "key", Value: bson.D{bson.E{Key: "$type", Value: "string"}}}}),
+	})
+	return err
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryReturnRepository in-process ReturnRepository for tests and local development
+type memoryReturnRepository struct {
+	mu      sync.RWMutex
+	returns []models.ReturnRequest
+}
+
+func NewMemoryReturnRepository() ReturnRepository {
+	return &memoryReturnRepository{}
+}
+
+// copy the timeline so callers never share a backing array with the store
+func cloneReturn(ret models.ReturnRequest) models.ReturnRequest {
+	ret.Timeline = append([]models.ReturnEvent(nil), ret.Timeline...)
+	if ret.Inspection != nil {
+		inspection := *ret.Inspection
+		ret.Inspection = &inspection
+	}
+	return ret
+}
+
+func (db *memoryReturnRepository) CreateReturn(ctx context.Context, ret models.ReturnRequest) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if ret.Id.IsZero() {
+		ret.Id = primitive.NewObjectID()
+	}
+
+	for _, existing := range db.returns {
+		if existing.Id == ret.Id {
+			return errDuplicateKey
+		}
+	}
+
+	db.returns = append(db.returns, cloneReturn(ret))
+	return nil
+}
+
+func (db *memoryReturnRepository) GetReturnById(ctx context.Context, returnId primitive.ObjectID) (models.ReturnRequest, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, ret := range db.returns {
+		if ret.Id == returnId {
+			return cloneReturn(ret), nil
+		}
+	}
+	return models.ReturnRequest{}, ErrReturnNotFound
+}
+
+func (db *memoryReturnRepository) GetOrderReturns(ctx context.Context, orderId primitive.ObjectID) ([]models.ReturnRequest, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	returns := []models.ReturnRequest{}
+	for _, ret := range db.returns {
+		if ret.OrderId == orderId {
+			returns = append(returns, cloneReturn(ret))
+		}
+	}
+	return returns, nil
+}
+
+func (db *memoryReturnRepository) SaveReturn(ctx context.Context, ret models.ReturnRequest, fromStatus string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, existing := range db.returns {
+		if existing.Id != ret.Id {
+			continue
+		}
+		if existing.Status != fromStatus {
+			return ErrReturnChanged
+		}
+		db.returns[i] = cloneReturn(ret)
+		return nil
+	}
+	return ErrReturnNotFound
+}
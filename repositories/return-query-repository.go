@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *returnRepository) Init(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	return operationContext(ctx, db.timeouts, method)
+}
+
+func (db *returnRepository) CreateReturn(ctx context.Context, ret models.ReturnRequest) (err error) {
+	defer metrics.ObserveRepository(db.returnsCollection.Name(), "CreateReturn")(&err)
+	ctx, cancel := db.Init(ctx, "CreateReturn")
+	defer cancel()
+
+	_, err = db.returnsCollection.InsertOne(ctx, ret)
+	return err
+}
+
+func (db *returnRepository) GetReturnById(ctx context.Context, returnId primitive.ObjectID) (ret models.ReturnRequest, err error) {
+	defer metrics.ObserveRepository(db.returnsCollection.Name(), "GetReturnById")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: returnId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetReturnById")
+	defer cancel()
+
+	err = db.returnsCollection.FindOne(ctx, filter).Decode(&ret)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.ReturnRequest{}, ErrReturnNotFound
+	}
+	return ret, err
+}
+
+func (db *returnRepository) GetOrderReturns(ctx context.Context, orderId primitive.ObjectID) (returns []models.ReturnRequest, err error) {
+	defer metrics.ObserveRepository(db.returnsCollection.Name(), "GetOrderReturns")(&err)
+	filter := bson.D{
+		bson.E{Key: "order_id", Value: orderId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetOrderReturns")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}})
+	cursor, curErr := db.returnsCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	returns = []models.ReturnRequest{}
+	if err = cursor.All(ctx, &returns); err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+func (db *returnRepository) SaveReturn(ctx context.Context, ret models.ReturnRequest, fromStatus string) (err error) {
+	defer metrics.ObserveRepository(db.returnsCollection.Name(), "SaveReturn")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: ret.Id},
+		bson.E{Key: "status", Value: fromStatus},
+	}
+
+	ctx, cancel := db.Init(ctx, "SaveReturn")
+	defer cancel()
+
+	res, err := db.returnsCollection.ReplaceOne(ctx, filter, ret)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, getErr := db.GetReturnById(ctx, ret.Id); getErr != nil {
+			return getErr
+		}
+		return ErrReturnChanged
+	}
+	return nil
+}
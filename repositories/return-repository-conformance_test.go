@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryReturnRepository(t *testing.T) {
+	runReturnRepositoryConformance(t, func(t *testing.T) ReturnRepository {
+		return NewMemoryReturnRepository()
+	})
+}
+
+func TestMongoReturnRepository(t *testing.T) {
+	skipWithoutMongo(t)
+
+	runReturnRepositoryConformance(t, func(t *testing.T) ReturnRepository {
+		client, cfg := connectTestMongo(t)
+		return NewReturnRepository(client, cfg, logging.Discard())
+	})
+}
+
+// runReturnRepositoryConformance behaviour every ReturnRepository implementation must share
+func runReturnRepositoryConformance(t *testing.T, newRepo func(t *testing.T) ReturnRepository) {
+	ctx := context.Background()
+
+	t.Run("create get and list", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("COMPLETED")
+
+		first := new(models.ReturnRequest).SetReturnRequest(order, 1, "DAMAGED", "box was crushed", "REQUESTED")
+		first.AddEvent("REQUESTED", order.UserId.Hex(), "")
+		second := new(models.ReturnRequest).SetReturnRequest(order, 1, "OTHER", "", "REQUESTED")
+		for _, ret := range []models.ReturnRequest{first, second} {
+			if err := repo.CreateReturn(ctx, ret); err != nil {
+				t.Fatalf("CreateReturn: %v", err)
+			}
+		}
+
+		got, err := repo.GetReturnById(ctx, first.Id)
+		if err != nil {
+			t.Fatalf("GetReturnById: %v", err)
+		}
+		if got.OrderId != order.Id || got.Reason != "DAMAGED" || got.Comment != "box was crushed" || len(got.Timeline) != 1 || got.Inspection != nil {
+			t.Fatalf("GetReturnById = %+v", got)
+		}
+
+		returns, err := repo.GetOrderReturns(ctx, order.Id)
+		if err != nil || len(returns) != 2 || returns[0].Id != first.Id || returns[1].Id != second.Id {
+			t.Fatalf("GetOrderReturns = %+v, %v", returns, err)
+		}
+		if returns, _ := repo.GetOrderReturns(ctx, primitive.NewObjectID()); len(returns) != 0 {
+			t.Fatalf("returns of another order = %+v", returns)
+		}
+
+		if _, err := repo.GetReturnById(ctx, primitive.NewObjectID()); !errors.Is(err, ErrReturnNotFound) {
+			t.Fatalf("missing return err = %v", err)
+		}
+	})
+
+	t.Run("save only from the expected status", func(t *testing.T) {
+		repo := newRepo(t)
+		ret := new(models.ReturnRequest).SetReturnRequest(newTestOrder("COMPLETED"), 2, "DAMAGED", "", "REQUESTED")
+		if err := repo.CreateReturn(ctx, ret); err != nil {
+			t.Fatal(err)
+		}
+
+		ret.Status = "APPROVED"
+		ret.Inspection = &models.ReturnInspection{Passed: true, RestockQuantity: 1}
+		ret.AddEvent("APPROVED", "operator", "looks fine")
+		if err := repo.SaveReturn(ctx, ret, "REQUESTED"); err != nil {
+			t.Fatalf("SaveReturn: %v", err)
+		}
+
+		got, _ := repo.GetReturnById(ctx, ret.Id)
+		if got.Status != "APPROVED" || len(got.Timeline) != 1 || got.Timeline[0].Note != "looks fine" || got.Inspection == nil || got.Inspection.RestockQuantity != 1 {
+			t.Fatalf("saved return = %+v", got)
+		}
+
+		// a second writer still expecting REQUESTED loses
+		ret.Status = "REJECTED"
+		if err := repo.SaveReturn(ctx, ret, "REQUESTED"); !errors.Is(err, ErrReturnChanged) {
+			t.Fatalf("stale SaveReturn err = %v", err)
+		}
+
+		missing := new(models.ReturnRequest).SetReturnRequest(newTestOrder("COMPLETED"), 1, "OTHER", "", "APPROVED")
+		if err := repo.SaveReturn(ctx, missing, "REQUESTED"); !errors.Is(err, ErrReturnNotFound) {
+			t.Fatalf("SaveReturn of a missing return err = %v", err)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrReturnNotFound returned by every implementation when the return does not exist
+var ErrReturnNotFound = errors.New("return not found")
+
+// ErrReturnChanged the return moved on since it was read, reload and try again
+var ErrReturnChanged = errors.New("return was changed concurrently")
+
+type ReturnRepository interface {
+	CreateReturn(ctx context.Context, ret models.ReturnRequest) error
+	GetReturnById(ctx context.Context, returnId primitive.ObjectID) (models.ReturnRequest, error)
+	// GetOrderReturns every return of the order, oldest first
+	GetOrderReturns(ctx context.Context, orderId primitive.ObjectID) ([]models.ReturnRequest, error)
+	// SaveReturn replace the stored return, only while it is still in fromStatus
+	SaveReturn(ctx context.Context, ret models.ReturnRequest, fromStatus string) error
+}
+
+type returnRepository struct {
+	returnsCollection *mongo.Collection
+	logger            *slog.Logger
+	timeouts          config.MongoConfig
+}
+
+func NewReturnRepository(client *mongo.Client, cfg *config.Config, logger *slog.Logger) ReturnRepository {
+	return &returnRepository{
+		logger:            logger,
+		timeouts:          cfg.Mongo,
+		returnsCollection: config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Returns),
+	}
+}
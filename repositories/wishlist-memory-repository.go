@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryWishlistRepository in-process WishlistRepository for tests and local development
+type memoryWishlistRepository struct {
+	mu    sync.RWMutex
+	items []models.WishlistItem
+}
+
+func NewMemoryWishlistRepository() WishlistRepository {
+	return &memoryWishlistRepository{}
+}
+
+func (db *memoryWishlistRepository) SaveWishlistItem(ctx context.Context, item models.WishlistItem) (models.WishlistItem, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, existing := range db.items {
+		if existing.UserId != item.UserId || existing.ProductSellingID != item.ProductSellingID {
+			continue
+		}
+		if holdsCartLine(existing, item.CartLineIds) {
+			return existing, nil
+		}
+
+		saved := item
+		saved.Id = existing.Id
+		saved.Quantity = existing.Quantity + item.Quantity
+		saved.CartLineIds = append(append([]primitive.ObjectID{}, existing.CartLineIds...), item.CartLineIds...)
+		db.items[i] = saved
+		return saved, nil
+	}
+
+	if item.Id.IsZero() {
+		item.Id = primitive.NewObjectID()
+	}
+	db.items = append(db.items, item)
+	return item, nil
+}
+
+func (db *memoryWishlistRepository) GetWishlistItem(ctx context.Context, itemId primitive.ObjectID) (models.WishlistItem, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, item := range db.items {
+		if item.Id == itemId {
+			return item, nil
+		}
+	}
+	return models.WishlistItem{}, ErrWishlistItemNotFound
+}
+
+func (db *memoryWishlistRepository) GetUserWishlist(ctx context.Context, userId primitive.ObjectID) ([]models.WishlistItem, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	items := []models.WishlistItem{}
+	for _, item := range db.items {
+		if item.UserId == userId {
+			items = append(items, item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].SavedAt != items[j].SavedAt {
+			return items[i].SavedAt > items[j].SavedAt
+		}
+		return items[i].Id.Hex() > items[j].Id.Hex()
+	})
+	return items, nil
+}
+
+func (db *memoryWishlistRepository) DeleteWishlistItem(ctx context.Context, itemId primitive.ObjectID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, item := range db.items {
+		if item.Id == itemId {
+			db.items = append(db.items[:i], db.items[i+1:]...)
+			return nil
+		}
+	}
+	return ErrWishlistItemNotFound
+}
+
+func (db *memoryWishlistRepository) ClaimWishlistItem(ctx context.Context, item models.WishlistItem) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, stored := range db.items {
+		if stored.Id != item.Id {
+			continue
+		}
+		if stored.UserId != item.UserId {
+			return ErrWishlistItemNotFound
+		}
+		if stored.Quantity != item.Quantity {
+			return ErrWishlistItemChanged
+		}
+
+		db.items = append(db.items[:i], db.items[i+1:]...)
+		return nil
+	}
+	return ErrWishlistItemNotFound
+}
+
+// whether the item already holds one of the cart lines
+func holdsCartLine(item models.WishlistItem, lineIds []primitive.ObjectID) bool {
+	for _, held := range item.CartLineIds {
+		for _, lineId := range lineIds {
+			if held == lineId {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *wishlistRepository) Init(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	return operationContext(ctx, db.timeouts, method)
+}
+
+// the upsert only matches the user's item of the variant that does not hold the
+// cart line yet. When the item holds it the insert collides on the unique index,
+// as it does when a concurrent save inserted the item first, and the update is
+// tried again against the stored item
+func (db *wishlistRepository) SaveWishlistItem(ctx context.Context, item models.WishlistItem) (saved models.WishlistItem, err error) {
+	defer metrics.ObserveRepository(db.wishlistCollection.Name(), "SaveWishlistItem")(&err)
+	if item.Id.IsZero() {
+		item.Id = primitive.NewObjectID()
+	}
+
+	filter := bson.D{
+		bson.E{Key: "user_id", Value: item.UserId},
+		bson.E{Key: "prod_selling_id", Value: item.ProductSellingID},
+		bson.E{Key: "cart_line_ids", Value: bson.D{bson.E{Key: "$nin", Value: item.CartLineIds}}},
+	}
+	variant := filter[:2]
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: "prod_id", Value: item.ProductId},
+			bson.E{Key: "category", Value: item.Category},
+			bson.E{Key: "saved_price", Value: item.SavedPrice},
+			bson.E{Key: "saved_at", Value: item.SavedAt},
+		}},
+		bson.E{Key: "$inc", Value: bson.D{
+			bson.E{Key: "quantity", Value: item.Quantity},
+		}},
+		bson.E{Key: "$addToSet", Value: bson.D{
+			bson.E{Key: "cart_line_ids", Value: bson.D{bson.E{Key: "$each", Value: item.CartLineIds}}},
+		}},
+		bson.E{Key: "$setOnInsert", Value: bson.D{
+			bson.E{Key: "_id", Value: item.Id},
+		}},
+	}
+
+	ctx, cancel := db.Init(ctx, "SaveWishlistItem")
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err = db.wishlistCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved)
+	if !mongo.IsDuplicateKeyError(err) {
+		return saved, err
+	}
+
+	err = db.wishlistCollection.FindOneAndUpdate(ctx, filter, update, opts.SetUpsert(false)).Decode(&saved)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// the stored item holds the cart line already
+		err = db.wishlistCollection.FindOne(ctx, variant).Decode(&saved)
+	}
+	return saved, err
+}
+
+func (db *wishlistRepository) GetWishlistItem(ctx context.Context, itemId primitive.ObjectID) (item models.WishlistItem, err error) {
+	defer metrics.ObserveRepository(db.wishlistCollection.Name(), "GetWishlistItem")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: itemId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetWishlistItem")
+	defer cancel()
+
+	err = db.wishlistCollection.FindOne(ctx, filter).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.WishlistItem{}, ErrWishlistItemNotFound
+	}
+	return item, err
+}
+
+func (db *wishlistRepository) GetUserWishlist(ctx context.Context, userId primitive.ObjectID) (items []models.WishlistItem, err error) {
+	defer metrics.ObserveRepository(db.wishlistCollection.Name(), "GetUserWishlist")(&err)
+	filter := bson.D{
+		bson.E{Key: "user_id", Value: userId},
+	}
+
+	ctx, cancel := db.Init(ctx, "GetUserWishlist")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "saved_at", Value: -1}, bson.E{Key: "_id", Value: -1}})
+	cursor, curErr := db.wishlistCollection.Find(ctx, filter, opts)
+
+	if curErr != nil {
+		return nil, curErr
+	}
+
+	items = []models.WishlistItem{}
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (db *wishlistRepository) DeleteWishlistItem(ctx context.Context, itemId primitive.ObjectID) (err error) {
+	defer metrics.ObserveRepository(db.wishlistCollection.Name(), "DeleteWishlistItem")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: itemId},
+	}
+
+	ctx, cancel := db.Init(ctx, "DeleteWishlistItem")
+	defer cancel()
+
+	res, err := db.wishlistCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return ErrWishlistItemNotFound
+	}
+	return nil
+}
+
+func (db *wishlistRepository) ClaimWishlistItem(ctx context.Context, item models.WishlistItem) (err error) {
+	defer metrics.ObserveRepository(db.wishlistCollection.Name(), "ClaimWishlistItem")(&err)
+	filter := bson.D{
+		bson.E{Key: "_id", Value: item.Id},
+		bson.E{Key: "user_id", Value: item.UserId},
+		bson.E{Key: "quantity", Value: item.Quantity},
+	}
+
+	ctx, cancel := db.Init(ctx, "ClaimWishlistItem")
+	defer cancel()
+
+	res, err := db.wishlistCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		stored, err := db.GetWishlistItem(ctx, item.Id)
+		if err != nil {
+			return err
+		}
+		if stored.UserId != item.UserId {
+			return ErrWishlistItemNotFound
+		}
+		return ErrWishlistItemChanged
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryWishlistRepository(t *testing.T) {
+	runWishlistRepositoryConformance(t, func(t *testing.T) WishlistRepository {
+		return NewMemoryWishlistRepository()
+	})
+}
+
+func TestMongoWishlistRepository(t *testing.T) {
+	skipWithoutMongo(t)
+
+	runWishlistRepositoryConformance(t, func(t *testing.T) WishlistRepository {
+		client, cfg := connectTestMongo(t)
+		if err := CreateWishlistIndexes(context.Background(), client, cfg); err != nil {
+			t.Fatal(err)
+		}
+		return NewWishlistRepository(client, cfg, logging.Discard())
+	})
+}
+
+// runWishlistRepositoryConformance behaviour every WishlistRepository implementation must share
+func runWishlistRepositoryConformance(t *testing.T, newRepo func(t *testing.T) WishlistRepository) {
+	ctx := context.Background()
+
+	t.Run("save get and list", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("CART")
+
+		older := new(models.WishlistItem).SetWishlistItem(order, 100)
+		older.SavedAt = primitive.NewDateTimeFromTime(time.Now().Add(-time.Hour))
+		other := newTestOrder("CART")
+		other.UserId = order.UserId
+		newer := new(models.WishlistItem).SetWishlistItem(other, 80)
+		for _, item := range []models.WishlistItem{older, newer} {
+			if _, err := repo.SaveWishlistItem(ctx, item); err != nil {
+				t.Fatalf("SaveWishlistItem: %v", err)
+			}
+		}
+
+		got, err := repo.GetWishlistItem(ctx, older.Id)
+		if err != nil || got.ProductSellingID != order.ProductSellingID || got.Quantity != 2 || got.SavedPrice != 100 {
+			t.Fatalf("GetWishlistItem = %+v, %v", got, err)
+		}
+
+		items, err := repo.GetUserWishlist(ctx, order.UserId)
+		if err != nil || len(items) != 2 || items[0].Id != newer.Id || items[1].Id != older.Id {
+			t.Fatalf("GetUserWishlist = %+v, %v", items, err)
+		}
+		if items, _ := repo.GetUserWishlist(ctx, primitive.NewObjectID()); len(items) != 0 {
+			t.Fatalf("wishlist of another user = %+v", items)
+		}
+
+		if _, err := repo.GetWishlistItem(ctx, primitive.NewObjectID()); !errors.Is(err, ErrWishlistItemNotFound) {
+			t.Fatalf("missing item err = %v", err)
+		}
+	})
+
+	t.Run("saving a variant again adds its quantity", func(t *testing.T) {
+		repo := newRepo(t)
+		order := newTestOrder("CART")
+
+		first, err := repo.SaveWishlistItem(ctx, new(models.WishlistItem).SetWishlistItem(order, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// another cart line of the same variant
+		line := newTestOrder("CART")
+		line.UserId, line.ProductSellingID, line.Quantity = order.UserId, order.ProductSellingID, 5
+		again, err := repo.SaveWishlistItem(ctx, new(models.WishlistItem).SetWishlistItem(line, 90))
+		if err != nil || again.Id != first.Id || again.Quantity != 7 || again.SavedPrice != 90 {
+			t.Fatalf("second SaveWishlistItem = %+v, %v", again, err)
+		}
+
+		// a retry of a line the item holds is not counted twice
+		retried, err := repo.SaveWishlistItem(ctx, new(models.WishlistItem).SetWishlistItem(line, 90))
+		if err != nil || retried.Id != first.Id || retried.Quantity != 7 {
+			t.Fatalf("retried SaveWishlistItem = %+v, %v", retried, err)
+		}
+
+		if items, _ := repo.GetUserWishlist(ctx, order.UserId); len(items) != 1 || items[0].Quantity != 7 || items[0].SavedPrice != 90 {
+			t.Fatalf("wishlist = %+v", items)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		item, err := repo.SaveWishlistItem(ctx, new(models.WishlistItem).SetWishlistItem(newTestOrder("CART"), 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.DeleteWishlistItem(ctx, item.Id); err != nil {
+			t.Fatalf("DeleteWishlistItem: %v", err)
+		}
+		if _, err := repo.GetWishlistItem(ctx, item.Id); !errors.Is(err, ErrWishlistItemNotFound) {
+			t.Fatalf("deleted item err = %v", err)
+		}
+		if err := repo.DeleteWishlistItem(ctx, item.Id); !errors.Is(err, ErrWishlistItemNotFound) {
+			t.Fatalf("second DeleteWishlistItem err = %v", err)
+		}
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		repo := newRepo(t)
+		item, err := repo.SaveWishlistItem(ctx, new(models.WishlistItem).SetWishlistItem(newTestOrder("CART"), 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		other := item
+		other.UserId = primitive.NewObjectID()
+		if err := repo.ClaimWishlistItem(ctx, other); !errors.Is(err, ErrWishlistItemNotFound) {
+			t.Fatalf("another user's ClaimWishlistItem err = %v, want ErrWishlistItemNotFound", err)
+		}
+
+		stale := item
+		stale.Quantity--
+		if err := repo.ClaimWishlistItem(ctx, stale); !errors.Is(err, ErrWishlistItemChanged) {
+			t.Fatalf("stale ClaimWishlistItem err = %v, want ErrWishlistItemChanged", err)
+		}
+
+		if err := repo.ClaimWishlistItem(ctx, item); err != nil {
+			t.Fatalf("ClaimWishlistItem: %v", err)
+		}
+		if err := repo.ClaimWishlistItem(ctx, item); !errors.Is(err, ErrWishlistItemNotFound) {
+			t.Fatalf("second ClaimWishlistItem err = %v, want ErrWishlistItemNotFound", err)
+		}
+	})
+}
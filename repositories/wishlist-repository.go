@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrWishlistItemNotFound returned by every implementation when the item does not exist
+var ErrWishlistItemNotFound = errors.New("wishlist item not found")
+
+// ErrWishlistItemChanged the item was saved to by someone else since it was read
+var ErrWishlistItemChanged = errors.New("wishlist item was changed concurrently, reload it and try again")
+
+type WishlistRepository interface {
+	// SaveWishlistItem insert the item, or add its quantity to the user's item of
+	// the same selling variant keeping its id, and return what is stored. Saving
+	// a cart line the item already holds returns the item unchanged
+	SaveWishlistItem(ctx context.Context, item models.WishlistItem) (models.WishlistItem, error)
+	GetWishlistItem(ctx context.Context, itemId primitive.ObjectID) (models.WishlistItem, error)
+	// GetUserWishlist every item of the user, most recently saved first
+	GetUserWishlist(ctx context.Context, userId primitive.ObjectID) ([]models.WishlistItem, error)
+	DeleteWishlistItem(ctx context.Context, itemId primitive.ObjectID) error
+	// ClaimWishlistItem delete the item only while it is the user's and holds the
+	// quantity it was read with, of concurrent claims exactly one succeeds
+	ClaimWishlistItem(ctx context.Context, item models.WishlistItem) error
+}
+
+type wishlistRepository struct {
+	wishlistCollection *mongo.Collection
+	logger             *slog.Logger
+	timeouts           config.MongoConfig
+}
+
+func NewWishlistRepository(client *mongo.Client, cfg *config.Config, logger *slog.Logger) WishlistRepository {
+	return &wishlistRepository{
+		logger:             logger,
+		timeouts:           cfg.Mongo,
+		wishlistCollection: config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Wishlists),
+	}
+}
+
+// CreateWishlistIndexes one item per user and selling variant, concurrent saves
+// of the same variant collide on the index instead of creating a second item
+func CreateWishlistIndexes(ctx context.Context, client *mongo.Client, cfg *config.Config) error {
+	ctx, cancel := operationContext(ctx, cfg.Mongo, "CreateWishlistIndexes")
+	defer cancel()
+
+	_, err := config.GetCollection(client, cfg.Mongo.Database, cfg.Collections.Wishlists).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "user_id", Value: 1}, bson.E{Key: "prod_selling_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
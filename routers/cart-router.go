@@ -0,0 +1,12 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func CartRouter(router *gin.Engine, cartcontroller controllers.CartControllers) {
+	marketing := router.Group("/api/v2/marketing")
+
+	marketing.GET("/abandoned-carts", cartcontroller.ListAbandonedCarts)
+}
@@ -0,0 +1,14 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/aniket0951/order-services/docs"
+	"github.com/gin-gonic/gin"
+)
+
+func DocsRouter(router *gin.Engine) {
+	router.GET("/openapi.json", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", docs.OpenAPI)
+	})
+}
@@ -0,0 +1,12 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRouter(router *gin.Engine, healthcontroller controllers.HealthControllers) {
+	router.GET("/healthz", healthcontroller.Liveness)
+	router.GET("/readyz", healthcontroller.Readiness)
+	router.GET("/status", healthcontroller.Status)
+}
@@ -0,0 +1,14 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+// server-sent event streams, part of the v2 api
+func OrderEventsRouter(router *gin.Engine, eventscontroller controllers.OrderEventsControllers) {
+	v2 := router.Group("/api/v2")
+
+	v2.GET("/orders/:id/events", eventscontroller.StreamOrder)
+	v2.GET("/users/:userId/orders/events", eventscontroller.StreamUserOrders)
+}
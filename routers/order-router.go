@@ -1,20 +1,55 @@
 package routers
 
 import (
+	"log/slog"
+
+	"github.com/aniket0951/order-services/config"
 	"github.com/aniket0951/order-services/controllers"
-	"github.com/aniket0951/order-services/repositories"
-	"github.com/aniket0951/order-services/services"
+	"github.com/aniket0951/order-services/middlewares"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-var (
-	orderrepo       = repositories.NewOrderRepository()
-	orderservice    = services.NewOrderService(orderrepo)
-	ordercontroller = controllers.NewOrderControllers(orderservice)
-)
+// Controllers every http handler the router serves
+type Controllers struct {
+	Order       controllers.OrderControllers
+	OrderV2     controllers.OrderV2Controllers
+	OrderEvents controllers.OrderEventsControllers
+	Payment     controllers.PaymentControllers
+	Cart        controllers.CartControllers
+	Return      controllers.ReturnControllers
+	Wishlist    controllers.WishlistControllers
+	Health      controllers.HealthControllers
+}
+
+// NewRouter gin engine with every route registered against the given controllers,
+// /metrics serves the default registry plus gatherer
+func NewRouter(ctrls Controllers, gatherer prometheus.Gatherer, cfg *config.Config, logger *slog.Logger) *gin.Engine {
+	router := gin.New()
+
+	router.SetTrustedProxies(nil)
+	router.Use(gin.Recovery(), otelgin.Middleware(cfg.Tracing.ServiceName), middlewares.RequestID(), middlewares.AccessLog(logger), middlewares.Metrics())
+
+	router.Static("static", "static")
+
+	HealthRouter(router, ctrls.Health)
+	MetricsRouter(router, gatherer)
+	DocsRouter(router)
+	OrderRouter(router, ctrls.Order, middlewares.Deprecated(cfg.HTTP.LegacyDeprecatedAt, cfg.HTTP.LegacySunsetAt, "/api/v2/orders"))
+	OrderV2Router(router, ctrls.OrderV2)
+	OrderEventsRouter(router, ctrls.OrderEvents)
+	PaymentRouter(router, ctrls.Payment)
+	CartRouter(router, ctrls.Cart)
+	ReturnRouter(router, ctrls.Return)
+	WishlistRouter(router, ctrls.Wishlist)
+
+	return router
+}
 
-func OrderRouter(router *gin.Engine) {
-	orderRoutes := router.Group("/api/order")
+// legacy rpc style routes, superseded by /api/v2
+func OrderRouter(router *gin.Engine, ordercontroller controllers.OrderControllers, middleware ...gin.HandlerFunc) {
+	orderRoutes := router.Group("/api/order", middleware...)
 	{
 		orderRoutes.POST("/place-single-order", ordercontroller.PlaceSingleOrder)
 		orderRoutes.POST("/add-to-card", ordercontroller.AddToCart)
@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func OrderV2Router(router *gin.Engine, ordercontroller controllers.OrderV2Controllers) {
+	v2 := router.Group("/api/v2")
+
+	orderRoutes := v2.Group("/orders")
+	{
+		orderRoutes.POST("", ordercontroller.CreateOrder)
+		orderRoutes.GET("/:id", ordercontroller.GetOrder)
+		orderRoutes.PATCH("/:id", ordercontroller.EditOrder)
+		orderRoutes.POST("/:id/cancel", ordercontroller.CancelOrder)
+		orderRoutes.POST("/:id/shipments", ordercontroller.ShipOrder)
+		orderRoutes.POST("/:id/reorder", ordercontroller.Reorder)
+		orderRoutes.PUT("/:id/status", ordercontroller.UpdateOrderStatus)
+	}
+
+	cartRoutes := v2.Group("/carts/:userId/items")
+	{
+		cartRoutes.GET("", ordercontroller.ListCartItems)
+		cartRoutes.POST("", ordercontroller.AddCartItem)
+		cartRoutes.DELETE("/:orderId", ordercontroller.RemoveCartItem)
+	}
+}
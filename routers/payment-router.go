@@ -0,0 +1,16 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentRouter(router *gin.Engine, paymentcontroller controllers.PaymentControllers) {
+	v2 := router.Group("/api/v2")
+
+	v2.POST("/orders/:id/payments", paymentcontroller.Checkout)
+	v2.GET("/orders/:id/payments", paymentcontroller.ListPayments)
+	v2.GET("/orders/:id/refunds", paymentcontroller.ListRefunds)
+	v2.POST("/refunds/:refundId/retry", paymentcontroller.RetryRefund)
+	v2.POST("/payments/callback", paymentcontroller.Callback)
+}
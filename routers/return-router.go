@@ -0,0 +1,18 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func ReturnRouter(router *gin.Engine, returncontroller controllers.ReturnControllers) {
+	v2 := router.Group("/api/v2")
+
+	v2.POST("/orders/:id/returns", returncontroller.RequestReturn)
+	v2.GET("/orders/:id/returns", returncontroller.ListOrderReturns)
+	v2.GET("/returns/:returnId", returncontroller.GetReturn)
+	v2.POST("/returns/:returnId/review", returncontroller.ReviewReturn)
+	v2.POST("/returns/:returnId/pickup", returncontroller.SchedulePickup)
+	v2.POST("/returns/:returnId/inspection", returncontroller.InspectReturn)
+	v2.POST("/returns/:returnId/refund", returncontroller.RetryRefund)
+}
@@ -0,0 +1,15 @@
+package routers
+
+import (
+	"github.com/aniket0951/order-services/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func WishlistRouter(router *gin.Engine, wishlistcontroller controllers.WishlistControllers) {
+	wishlistRoutes := router.Group("/api/v2/wishlists/:userId/items")
+
+	wishlistRoutes.GET("", wishlistcontroller.ListWishlist)
+	wishlistRoutes.POST("", wishlistcontroller.SaveForLater)
+	wishlistRoutes.POST("/:itemId/move-to-cart", wishlistcontroller.MoveToCart)
+	wishlistRoutes.DELETE("/:itemId", wishlistcontroller.RemoveWishlistItem)
+}
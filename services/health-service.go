@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	STATUS_UP   = "UP"
+	STATUS_DOWN = "DOWN"
+)
+
+// worker states
+const (
+	WORKER_PENDING = "PENDING"
+	WORKER_RUNNING = "RUNNING"
+	WORKER_STOPPED = "STOPPED"
+	WORKER_FAILED  = "FAILED"
+)
+
+// Version and Commit are set at build time through -ldflags "-X"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// HealthCheck a single dependency probe
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type WorkerStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+type ServiceStatus struct {
+	Status       string             `json:"status"`
+	Build        BuildInfo          `json:"build"`
+	StartedAt    time.Time          `json:"started_at"`
+	Uptime       string             `json:"uptime"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+	Workers      []WorkerStatus     `json:"workers"`
+}
+
+type HealthService interface {
+	Ready(ctx context.Context) (bool, []DependencyStatus, []WorkerStatus)
+	Status(ctx context.Context) ServiceStatus
+}
+
+type healthService struct {
+	checks    []HealthCheck
+	workers   func() []WorkerStatus
+	timeout   time.Duration
+	startedAt time.Time
+}
+
+func NewHealthService(checks []HealthCheck, workers func() []WorkerStatus, timeout time.Duration) HealthService {
+	return &healthService{
+		checks:    checks,
+		workers:   workers,
+		timeout:   timeout,
+		startedAt: time.Now(),
+	}
+}
+
+// run every dependency check concurrently, each bounded by the check timeout
+func (ser *healthService) checkDependencies(ctx context.Context) []DependencyStatus {
+	statuses := make([]DependencyStatus, len(ser.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range ser.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, ser.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+
+			statuses[i] = DependencyStatus{
+				Name:      check.Name,
+				Status:    STATUS_UP,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				statuses[i].Status = STATUS_DOWN
+				statuses[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+func (ser *healthService) workerStatuses() []WorkerStatus {
+	if ser.workers == nil {
+		return []WorkerStatus{}
+	}
+	return ser.workers()
+}
+
+// Ready service can take traffic when every dependency is up and no worker has failed
+func (ser *healthService) Ready(ctx context.Context) (bool, []DependencyStatus, []WorkerStatus) {
+	dependencies := ser.checkDependencies(ctx)
+	workers := ser.workerStatuses()
+
+	ready := true
+	for _, dep := range dependencies {
+		if dep.Status != STATUS_UP {
+			ready = false
+		}
+	}
+	for _, w := range workers {
+		if w.State == WORKER_FAILED {
+			ready = false
+		}
+	}
+
+	return ready, dependencies, workers
+}
+
+func (ser *healthService) Status(ctx context.Context) ServiceStatus {
+	ready, dependencies, workers := ser.Ready(ctx)
+
+	status := STATUS_UP
+	if !ready {
+		status = STATUS_DOWN
+	}
+
+	return ServiceStatus{
+		Status:       status,
+		Build:        buildInfo(),
+		StartedAt:    ser.startedAt,
+		Uptime:       time.Since(ser.startedAt).Round(time.Second).String(),
+		Dependencies: dependencies,
+		Workers:      workers,
+	}
+}
+
+func buildInfo() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+
+	if info.Commit != "" {
+		return info
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/repositories"
+)
+
+// ErrLimitExceeded order or cart line rejected by the limits policy, the wrapping error names the limit
+var ErrLimitExceeded = errors.New("order limit exceeded")
+
+// OPEN_ORDER_STATUSES orders counted against the open order limit
+var OPEN_ORDER_STATUSES = []string{helper.PENDING_PAYMENT, helper.PLACED, helper.DISPATCHED}
+
+// UNCOUNTED_UNIT_STATUSES orders whose units do not count against the product
+// unit limit, every other order created in the window does, completed ones too
+var UNCOUNTED_UNIT_STATUSES = []string{helper.CART, helper.CANCELLED, helper.REFUND_PENDING, helper.REFUNDED}
+
+// OrderLimits quantity limits of the policy, 0 disables a limit. A product
+// entry, keyed by product id, overrides the category entry, which overrides MaxQuantity
+type OrderLimits struct {
+	MaxQuantity         int64
+	CategoryMaxQuantity map[string]int64
+	ProductMaxQuantity  map[string]int64
+	MaxOpenOrders       int64
+	MaxProductUnits     int64
+	ProductWindow       time.Duration
+}
+
+// LimitsPolicy decides whether a user may order the requested quantity
+type LimitsPolicy interface {
+	// CheckCartLine quantity limit of a single line
+	CheckCartLine(ctx context.Context, order dto.CreateOrderDTO) error
+	// CheckOrder quantity limit plus the user's open orders and recent units of the product
+	CheckOrder(ctx context.Context, order dto.CreateOrderDTO) error
+	// CheckProductUnits the user's recent units of the product plus order.Quantity more
+	CheckProductUnits(ctx context.Context, order dto.CreateOrderDTO) error
+}
+
+type limitsPolicy struct {
+	orderRepo repositories.OrderRepository
+	limits    OrderLimits
+}
+
+func NewLimitsPolicy(orderRepo repositories.OrderRepository, limits OrderLimits) LimitsPolicy {
+	return &limitsPolicy{
+		orderRepo: orderRepo,
+		limits:    limits,
+	}
+}
+
+func (p *limitsPolicy) CheckCartLine(ctx context.Context, order dto.CreateOrderDTO) error {
+	max, scope := p.maxQuantity(order)
+	if max > 0 && order.Quantity > max {
+		return fmt.Errorf("%w: quantity %d is over the maximum of %d per order%s", ErrLimitExceeded, order.Quantity, max, scope)
+	}
+	return nil
+}
+
+func (p *limitsPolicy) CheckOrder(ctx context.Context, order dto.CreateOrderDTO) error {
+	if err := p.CheckCartLine(ctx, order); err != nil {
+		return err
+	}
+
+	userId, err := helper.ValidatePrimitiveId(order.UserId)
+	if err != nil {
+		return err
+	}
+
+	if p.limits.MaxOpenOrders > 0 {
+		open, err := p.orderRepo.CountUserOrders(ctx, userId, OPEN_ORDER_STATUSES)
+		if err != nil {
+			return err
+		}
+		if open >= p.limits.MaxOpenOrders {
+			return fmt.Errorf("%w: the user already has %d open orders, the maximum is %d", ErrLimitExceeded, open, p.limits.MaxOpenOrders)
+		}
+	}
+
+	return p.CheckProductUnits(ctx, order)
+}
+
+func (p *limitsPolicy) CheckProductUnits(ctx context.Context, order dto.CreateOrderDTO) error {
+	if p.limits.MaxProductUnits <= 0 {
+		return nil
+	}
+
+	userId, err := helper.ValidatePrimitiveId(order.UserId)
+	if err != nil {
+		return err
+	}
+	productId, err := helper.ValidatePrimitiveId(order.ProductId)
+	if err != nil {
+		return err
+	}
+
+	units, err := p.orderRepo.SumUserProductUnits(ctx, userId, productId, time.Now().Add(-p.limits.ProductWindow), UNCOUNTED_UNIT_STATUSES)
+	if err != nil {
+		return err
+	}
+	if units+order.Quantity > p.limits.MaxProductUnits {
+		return fmt.Errorf("%w: the user ordered %d units of this product in the last %s, %d more would pass the maximum of %d",
+			ErrLimitExceeded, units, shortDuration(p.limits.ProductWindow), order.Quantity, p.limits.MaxProductUnits)
+	}
+	return nil
+}
+
+// most specific quantity limit for the order and a description of where it comes from
+func (p *limitsPolicy) maxQuantity(order dto.CreateOrderDTO) (int64, string) {
+	if max, ok := p.limits.ProductMaxQuantity[order.ProductId]; ok {
+		return max, " of product " + order.ProductId
+	}
+	for category, max := range p.limits.CategoryMaxQuantity {
+		if strings.EqualFold(category, order.Category) {
+			return max, " in " + strings.ToUpper(order.Category)
+		}
+	}
+	return p.limits.MaxQuantity, ""
+}
+
+// 24h rather than 24h0m0s
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/repositories"
+)
+
+func TestLimitsPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryOrderRepository()
+
+	limited := validOrderDTO()
+	policy := NewLimitsPolicy(repo, OrderLimits{
+		MaxQuantity:         10,
+		CategoryMaxQuantity: map[string]int64{"GROCERY": 20},
+		ProductMaxQuantity:  map[string]int64{limited.ProductId: 2},
+		MaxOpenOrders:       2,
+		MaxProductUnits:     8,
+		ProductWindow:       24 * time.Hour,
+	})
+
+	// the user of busy already has two open orders, the user of repeat bought
+	// 6 units of the product today over a dispatched and a completed order and
+	// has a cancelled order of 5 more
+	busy, repeat := validOrderDTO(), validOrderDTO()
+	store := func(order dto.CreateOrderDTO, status string) {
+		t.Helper()
+		if _, err := repo.PlaceSingleOrder(ctx, new(models.Orders).SetPlaceOrder(order, status)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store(busy, helper.PLACED)
+	store(busy, helper.PENDING_PAYMENT)
+	store(busy, helper.CANCELLED)
+	repeat.Quantity = 4
+	store(repeat, helper.DISPATCHED)
+	repeat.Quantity = 2
+	store(repeat, helper.COMPLETED)
+	repeat.Quantity = 5
+	store(repeat, helper.CANCELLED)
+
+	with := func(order dto.CreateOrderDTO, change func(*dto.CreateOrderDTO)) dto.CreateOrderDTO {
+		change(&order)
+		return order
+	}
+
+	tests := []struct {
+		name      string
+		order     dto.CreateOrderDTO
+		cartOnly  bool
+		wantError string
+	}{
+		{name: "at the global maximum", order: with(validOrderDTO(), func(o *dto.CreateOrderDTO) { o.Quantity = 10 }), cartOnly: true},
+		{name: "over the global maximum", order: with(validOrderDTO(), func(o *dto.CreateOrderDTO) { o.Quantity = 11 }), wantError: "quantity 11 is over the maximum of 10 per order"},
+		{name: "category override", order: with(validOrderDTO(), func(o *dto.CreateOrderDTO) { o.Category, o.Quantity = "grocery", 20 }), cartOnly: true},
+		{name: "over the category override", order: with(validOrderDTO(), func(o *dto.CreateOrderDTO) { o.Category, o.Quantity = "GROCERY", 21 }), wantError: "of 20 per order in GROCERY"},
+		{name: "product override wins", order: with(limited, func(o *dto.CreateOrderDTO) { o.Category = "GROCERY" }), wantError: "of 2 per order of product " + limited.ProductId},
+		{name: "cart line of a busy user", order: busy, cartOnly: true},
+		{name: "too many open orders", order: busy, wantError: "already has 2 open orders, the maximum is 2"},
+		{name: "within the product window", order: with(repeat, func(o *dto.CreateOrderDTO) { o.Quantity = 2 })},
+		{name: "over the product window", order: with(repeat, func(o *dto.CreateOrderDTO) { o.Quantity = 3 }), wantError: "ordered 6 units of this product in the last 24h, 3 more would pass the maximum of 8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := policy.CheckOrder
+			if tt.cartOnly {
+				check = policy.CheckCartLine
+			}
+
+			err := check(ctx, tt.order)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("err = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+
+	if err := NewLimitsPolicy(repo, OrderLimits{}).CheckOrder(ctx, with(busy, func(o *dto.CreateOrderDTO) { o.Quantity = 500 })); err != nil {
+		t.Fatalf("zero limits must allow everything, err = %v", err)
+	}
+}
+
+func TestOrderServiceAppliesLimits(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	order := validOrderDTO()
+	order.Quantity = 10
+	if _, err := f.service.PlaceSingleOrder(ctx, order); err != nil {
+		t.Fatalf("PlaceSingleOrder of the maximum: %v", err)
+	}
+	if _, err := f.service.AddToCart(ctx, order); err != nil {
+		t.Fatalf("AddToCart of the maximum: %v", err)
+	}
+
+	order.Quantity = 11
+	if _, err := f.service.PlaceSingleOrder(ctx, order); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("PlaceSingleOrder over the maximum = %v", err)
+	}
+	if _, err := f.service.AddToCart(ctx, order); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("AddToCart over the maximum = %v", err)
+	}
+	if calls := f.inventory.Calls(); len(calls) != 1 {
+		t.Fatalf("rejected orders must not touch stock, calls = %v", calls)
+	}
+}
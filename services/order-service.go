@@ -2,96 +2,213 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"log/slog"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/aniket0951/order-services/clients"
 	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/events"
 	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/metrics"
 	"github.com/aniket0951/order-services/models"
 	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type OrderService interface {
-	PlaceSingleOrder(order dto.CreateOrderDTO) error
-	AddToCart(order dto.CreateOrderDTO) error
-	RemoveItemFromCart(orderId string) error
-	UpdateOrderStatus(orderId, status string) error
-	CancelOrder(orderId string) error
+	PlaceSingleOrder(ctx context.Context, order dto.CreateOrderDTO) (models.Orders, error)
+	AddToCart(ctx context.Context, order dto.CreateOrderDTO) (models.Orders, error)
+	RemoveItemFromCart(ctx context.Context, orderId string) error
+	UpdateOrderStatus(ctx context.Context, orderId, status string) error
+	CancelOrder(ctx context.Context, orderId string) error
+	CancelOrderUnits(ctx context.Context, orderId string, quantity int64) (models.Orders, error)
+	ShipOrderUnits(ctx context.Context, orderId string, quantity int64) (models.Orders, error)
+	EditOrder(ctx context.Context, orderId string, edit dto.EditOrderDTO) (models.Orders, error)
+	Reorder(ctx context.Context, orderId, mode string) (models.Reorder, error)
+	CancelStaleOrders(ctx context.Context, slas map[string]time.Duration) (int, error)
+	SweepCarts(ctx context.Context, policy CartPolicy) (CartSweep, error)
+	GetAbandonedCarts(ctx context.Context, idle time.Duration, minValue float64, limit int64) ([]models.AbandonedCart, error)
+
+	GetOrder(ctx context.Context, orderId string) (models.Orders, error)
+	GetOrderTrack(ctx context.Context, orderId string) ([]models.OrderTrack, error)
+	GetOrderTrackSince(ctx context.Context, orderId, lastTrackId string) ([]models.OrderTrack, error)
+	GetUserOrderTrackSince(ctx context.Context, userId, lastTrackId string) ([]models.OrderTrack, error)
+	ListOrders(ctx context.Context, userId, status string) ([]models.Orders, error)
+	GetCartItems(ctx context.Context, userId string) ([]models.Orders, error)
+	RemoveItemFromUserCart(ctx context.Context, userId, orderId string) error
+
+	// TrackRefund record a refund that failed for good or was queued again on the order track
+	TrackRefund(ctx context.Context, refund models.Refund) error
+}
+
+// ErrNotInCart order exists but is not a cart line of the user
+var ErrNotInCart = errors.New("order is not in the user's cart")
+
+// ErrPaymentPending order can not be fulfilled before it is paid
+var ErrPaymentPending = errors.New("order is awaiting payment")
+
+// ErrOrderClosed order has been cancelled already
+var ErrOrderClosed = errors.New("order is already cancelled")
+
+// ErrUnitsUnavailable order has fewer units left to ship or cancel than requested
+var ErrUnitsUnavailable = errors.New("order does not have that many units left")
+
+// ErrBackordered order can not complete while some of its units wait to be shipped
+var ErrBackordered = errors.New("order has backordered units")
+
+// ErrPaymentInProgress order total can not change while a payment of it is pending
+var ErrPaymentInProgress = errors.New("a payment of the order is in progress")
+
+// ErrUnitsShipped order with shipped units can only have the units left to ship cancelled
+var ErrUnitsShipped = errors.New("order has shipped units, cancel the units left to ship instead")
+
+// ErrStatusTransition an order is dispatched only once placed and completed only once dispatched
+var ErrStatusTransition = errors.New("order can not move to this status from its current status")
+
+// ErrOrderDispatched order can not be edited or cancelled as a whole once it has been dispatched
+var ErrOrderDispatched = errors.New("order has been dispatched already")
+
+// ErrOtherProduct variant an order is edited to belongs to another product
+var ErrOtherProduct = errors.New("the variant is not sold for the ordered product")
+
+// ErrEditExceedsPayment edit would make a paid order cost more than was paid for it
+var ErrEditExceedsPayment = errors.New("the edited order costs more than was paid")
+
+// AUTO_CANCEL_BATCH stale orders cancelled per status on every scheduler run
+const AUTO_CANCEL_BATCH = 100
+
+// CART_SWEEP_BATCH abandoned carts reported and cart lines expired on every sweep
+const CART_SWEEP_BATCH = 100
+
+// CartPolicy when idle cart lines expire and when a cart counts as abandoned
+type CartPolicy struct {
+	TTL               time.Duration
+	AbandonedAfter    time.Duration
+	AbandonedMinValue float64
+}
+
+// CartSweep what a single sweep did
+type CartSweep struct {
+	Abandoned int
+	Expired   int
 }
 
 type orderService struct {
-	orderRepo repositories.OrderRepository
+	orderRepo   repositories.OrderRepository
+	paymentRepo repositories.PaymentRepository
+	inventory   clients.InventoryClient
+	limits      LimitsPolicy
+	publisher   events.Publisher
+	logger      *slog.Logger
 }
 
-func NewOrderService(orderRepo repositories.OrderRepository) OrderService {
+func NewOrderService(orderRepo repositories.OrderRepository, paymentRepo repositories.PaymentRepository, inventory clients.InventoryClient, limits LimitsPolicy, publisher events.Publisher, logger *slog.Logger) OrderService {
 	return &orderService{
-		orderRepo: orderRepo,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+		inventory:   inventory,
+		limits:      limits,
+		publisher:   publisher,
+		logger:      logger,
 	}
 }
 
-// placing single product order
-func (ser *orderService) PlaceSingleOrder(order dto.CreateOrderDTO) error {
-	if order.Quantity > 10 {
-		return errors.New("quantity should be less than 10")
-	}
+func orderAttrs(orderId string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("order.id", orderId))
+}
+
+func userAttrs(userId string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("user.id", userId))
+}
+
+// logger carrying the order and user ids
+func (ser *orderService) orderLogger(order models.Orders) *slog.Logger {
+	return ser.logger.With("order_id", order.Id.Hex(), "user_id", order.UserId.Hex())
+}
+
+// placing single product order, it waits in PENDING_PAYMENT until paid
+func (ser *orderService) PlaceSingleOrder(ctx context.Context, order dto.CreateOrderDTO) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.PlaceSingleOrder", userAttrs(order.UserId))
+	defer tracing.End(span, &err)
 
 	_, valErr := helper.ValidatePrimitiveId(order.ProductId)
 	_, valSellIdErr := helper.ValidatePrimitiveId(order.ProductSellingID)
 
 	if valErr != nil {
-		return valErr
+		return models.Orders{}, valErr
 	}
 
 	if valSellIdErr != nil {
-		return valSellIdErr
+		return models.Orders{}, valSellIdErr
+	}
+
+	if err := ser.limits.CheckOrder(ctx, order); err != nil {
+		return models.Orders{}, err
 	}
 
-	orderToPlace := new(models.Orders).SetPlaceOrder(order, helper.PLACED)
+	orderToPlace := new(models.Orders).SetPlaceOrder(order, helper.PENDING_PAYMENT)
 	orderToPlace.TotalPrice = float64(order.Price) * float64(order.Quantity)
-	_, err := ser.orderRepo.PlaceSingleOrder(orderToPlace)
+	_, err = ser.orderRepo.PlaceSingleOrder(ctx, orderToPlace)
 	if err != nil {
-		return err
+		return models.Orders{}, err
 	}
+	span.SetAttributes(attribute.String("order.id", orderToPlace.Id.Hex()))
+	metrics.OrderStatusTransitions.WithLabelValues("", helper.PENDING_PAYMENT).Inc()
+
+	logger := ser.orderLogger(orderToPlace)
+	logger.InfoContext(ctx, "order placed", "product_id", order.ProductId, "quantity", order.Quantity, "total_price", orderToPlace.TotalPrice)
 
-	// updating product count by quantity
+	// updating product count by quantity, the order stands once stored and a
+	// stock count the product service did not take is reported with it
 	quantityStr := strconv.Itoa(int(order.Quantity))
-	countErr := ser.UpdateProductCount("decrease", quantityStr, order.ProductId)
+	if countErr := ser.UpdateProductCount(ctx, "decrease", quantityStr, order.ProductId); countErr != nil {
+		logger.WarnContext(ctx, "placed units not taken off stock", "product_id", order.ProductId, "error", countErr)
+		orderToPlace.StockNotUpdated = true
+	}
 
-	return countErr
+	return orderToPlace, nil
 }
 
-func (ser *orderService) AddToCart(order dto.CreateOrderDTO) error {
-	if order.Quantity > 10 {
-		return errors.New("quantity should be less than 10")
-	}
+func (ser *orderService) AddToCart(ctx context.Context, order dto.CreateOrderDTO) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.AddToCart", userAttrs(order.UserId))
+	defer tracing.End(span, &err)
 
 	_, valErr := helper.ValidatePrimitiveId(order.ProductId)
 	_, valSellIdErr := helper.ValidatePrimitiveId(order.ProductSellingID)
 	userID, valUserIdErr := helper.ValidatePrimitiveId(order.UserId)
 
 	if valErr != nil {
-		return valErr
+		return models.Orders{}, valErr
 	}
 
 	if valSellIdErr != nil {
-		return valSellIdErr
+		return models.Orders{}, valSellIdErr
 	}
 
 	if valUserIdErr != nil {
-		return valUserIdErr
+		return models.Orders{}, valUserIdErr
+	}
+
+	if err := ser.limits.CheckCartLine(ctx, order); err != nil {
+		return models.Orders{}, err
 	}
 
 	orderToPlace := new(models.Orders).SetPlaceOrder(order, helper.CART)
 	orderToPlace.TotalPrice = float64(order.Price) * float64(order.Quantity)
-	_, err := ser.orderRepo.PlaceSingleOrder(orderToPlace)
+	_, err = ser.orderRepo.PlaceSingleOrder(ctx, orderToPlace)
 	if err != nil {
-		return err
+		return models.Orders{}, err
 	}
+	span.SetAttributes(attribute.String("order.id", orderToPlace.Id.Hex()))
+	metrics.OrderStatusTransitions.WithLabelValues("", helper.CART).Inc()
 
 	orderItem := models.OrderCarts{
 		OrderId:   orderToPlace.Id,
@@ -100,97 +217,161 @@ func (ser *orderService) AddToCart(order dto.CreateOrderDTO) error {
 		UpdatedAt: primitive.NewDateTimeFromTime(time.Now()),
 	}
 
-	return ser.orderRepo.AddToCartOrder(orderItem)
-}
-
-// update product counts accordingly
-func (ser *orderService) UpdateProductCount(tag string, num string, productId string) error {
-	reqUrl := helper.INCREASE_DECREASE_PRODUCT + "?tag=" + tag + "&number=" + num + "&product_id=" + productId
-
-	req, err := http.NewRequest(http.MethodPut, reqUrl, &bytes.Buffer{})
-
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, resErr := client.Do(req)
-
-	if resErr != nil {
-		return resErr
+	if err := ser.orderRepo.AddToCartOrder(ctx, orderItem); err != nil {
+		return models.Orders{}, err
 	}
 
-	defer resp.Body.Close()
+	ser.orderLogger(orderToPlace).InfoContext(ctx, "order added to cart", "product_id", order.ProductId, "quantity", order.Quantity)
+	ser.observeCartSize(ctx, "add", userID)
+	return orderToPlace, nil
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// record the user's cart line count after a cart change
+func (ser *orderService) observeCartSize(ctx context.Context, operation string, userId primitive.ObjectID) {
+	cartItems, err := ser.orderRepo.UserCartItem(ctx, userId)
 	if err != nil {
-		fmt.Printf("%s", err)
+		return
 	}
-	fmt.Println(body)
-	return nil
+	metrics.CartSize.WithLabelValues(operation).Observe(float64(len(cartItems)))
+}
+
+// update product counts accordingly
+func (ser *orderService) UpdateProductCount(ctx context.Context, tag string, num string, productId string) error {
+	return ser.inventory.UpdateProductCount(ctx, tag, num, productId)
 }
 
-func (ser *orderService) RemoveItemFromCart(orderId string) error {
+func (ser *orderService) RemoveItemFromCart(ctx context.Context, orderId string) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.RemoveItemFromCart", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
 	orderObjId, err := helper.ValidatePrimitiveId(orderId)
 
 	if err != nil {
 		return err
 	}
 
+	order, orderErr := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if orderErr != nil {
+		return orderErr
+	}
+
 	// remove item from cart
-	rmErr := ser.orderRepo.DeleteCartOrder(orderObjId)
+	rmErr := ser.orderRepo.DeleteCartOrder(ctx, orderObjId)
 	if rmErr != nil {
 		return rmErr
 	}
 
 	// after remove the cart item remove item data from orders
-	return ser.orderRepo.DeleteOrder(orderObjId)
+	if err := ser.orderRepo.DeleteOrder(ctx, orderObjId); err != nil {
+		return err
+	}
+
+	ser.orderLogger(order).InfoContext(ctx, "order removed from cart")
+	ser.observeCartSize(ctx, "remove", order.UserId)
+	return nil
 }
 
-func (ser *orderService) CreateOrderTrack(order models.Orders) error {
+func (ser *orderService) CreateOrderTrack(ctx context.Context, order models.Orders) error {
 	orderTrack := models.OrderTrack{}
 	orderTrack.OrderId = order.Id
 	orderTrack.OrderStatus = order.OrderStatus
 	orderTrack.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
 	orderTrack.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
 
-	return ser.orderRepo.CreateOrderTrack(orderTrack)
+	return ser.orderRepo.CreateOrderTrack(ctx, orderTrack)
 }
 
-func (ser *orderService) UpdateOrderStatus(orderId, status string) error {
+func (ser *orderService) UpdateOrderStatus(ctx context.Context, orderId, status string) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.UpdateOrderStatus", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+	span.SetAttributes(attribute.String("order.status", status))
+
 	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
 	if valErr != nil {
 		return valErr
 	}
 
-	err := ser.orderRepo.UpdateOrderStatus(status, orderObjId)
+	order, orderErr := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if orderErr != nil {
+		return orderErr
+	}
 
-	if err != nil {
-		return err
+	if status == helper.DISPATCHED || status == helper.COMPLETED {
+		if order.OrderStatus == helper.PENDING_PAYMENT {
+			return ErrPaymentPending
+		}
+		if helper.IsClosedStatus(order.OrderStatus) {
+			return ErrOrderClosed
+		}
+	}
+	if status == helper.DISPATCHED && order.OrderStatus != helper.PLACED && order.OrderStatus != helper.DISPATCHED ||
+		status == helper.COMPLETED && order.OrderStatus != helper.DISPATCHED {
+		return fmt.Errorf("%w: %s to %s", ErrStatusTransition, order.OrderStatus, status)
 	}
 
-	orderTrack := new(models.OrderTrack).SetOrderTrack(orderObjId, status)
+	// dispatching the whole order ships every unit that is left
+	if status == helper.DISPATCHED && order.UnshippedQuantity() > 0 {
+		_, err = ser.shipUnits(ctx, order, order.UnshippedQuantity())
+		return err
+	}
+	if status == helper.COMPLETED && order.BackorderedQuantity > 0 {
+		return fmt.Errorf("%w: %d units are still to ship or cancel", ErrBackordered, order.BackorderedQuantity)
+	}
 
-	trackErr := ser.orderRepo.CreateOrderTrack(orderTrack)
+	if err = ser.changeStatus(ctx, order, status); err != nil {
+		return err
+	}
+	trackErr := ser.trackStatus(ctx, order, status)
 
 	if status == helper.COMPLETED {
-		ser.CreateOrderHistory(orderObjId)
+		if hisErr := ser.CreateOrderHistory(ctx, orderObjId); hisErr != nil {
+			ser.orderLogger(order).ErrorContext(ctx, "failed to archive completed order", "error", hisErr)
+		}
 	}
 
 	return trackErr
 }
 
+// move order to status as long as it is still in the status it was read with,
+// ErrOrderChanged when someone else changed it first
+func (ser *orderService) changeStatus(ctx context.Context, order models.Orders, status string) error {
+	if err := ser.orderRepo.ChangeOrderStatus(ctx, order.Id, order.OrderStatus, status); err != nil {
+		return err
+	}
+	metrics.OrderStatusTransitions.WithLabelValues(order.OrderStatus, status).Inc()
+
+	ser.orderLogger(order).InfoContext(ctx, "order status updated", "from", order.OrderStatus, "to", status, "actor", helper.Actor(ctx))
+	return nil
+}
+
+// record the status change of order in its track and publish it
+func (ser *orderService) trackStatus(ctx context.Context, order models.Orders, status string) error {
+	orderTrack := new(models.OrderTrack).SetOrderTrack(order.Id, status)
+	orderTrack.Actor = helper.Actor(ctx)
+
+	if err := ser.orderRepo.CreateOrderTrack(ctx, orderTrack); err != nil {
+		return err
+	}
+	ser.publisher.Publish(events.OrderEvent{
+		Type:   events.ORDER_STATUS_CHANGED,
+		UserId: order.UserId,
+		Track:  orderTrack,
+	})
+	return nil
+}
+
 // make a order history after order has been completed
-func (ser *orderService) CreateOrderHistory(orderId primitive.ObjectID) error {
-	order, orderErr := ser.orderRepo.GetOrderById(orderId)
+func (ser *orderService) CreateOrderHistory(ctx context.Context, orderId primitive.ObjectID) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CreateOrderHistory", orderAttrs(orderId.Hex()))
+	defer tracing.End(span, &err)
+
+	order, orderErr := ser.orderRepo.GetOrderById(ctx, orderId)
 
 	if orderErr != nil {
 		return orderErr
 	}
 
-	orderTrack, trackErr := ser.orderRepo.GetAllOrderTrack(orderId)
+	orderTrack, trackErr := ser.orderRepo.GetAllOrderTrack(ctx, orderId)
 
 	if trackErr != nil {
 		return trackErr
@@ -199,44 +380,837 @@ func (ser *orderService) CreateOrderHistory(orderId primitive.ObjectID) error {
 	orderHistory := new(models.OrderHistory).SetOrderHistory(order, orderTrack)
 
 	// create a track history
-	hisErr := ser.orderRepo.CreateOrderHistory(orderHistory)
+	hisErr := ser.orderRepo.CreateOrderHistory(ctx, orderHistory)
 
 	if hisErr != nil {
 		return hisErr
 	}
 
 	// remove all data
-	delErr := ser.orderRepo.DeleteOrder(orderId)
+	delErr := ser.orderRepo.DeleteOrder(ctx, orderId)
 
 	if delErr != nil {
 		return delErr
 	}
 
-	delTrackErr := ser.orderRepo.DeleteOrderFromTrack([]primitive.ObjectID{orderId})
+	delTrackErr := ser.orderRepo.DeleteOrderFromTrack(ctx, []primitive.ObjectID{orderId})
+	if delTrackErr == nil {
+		ser.orderLogger(order).InfoContext(ctx, "order archived to history", "track_entries", len(orderTrack))
+	}
 	return delTrackErr
 }
 
-// if order get cancel then increase a product count
-func (ser *orderService) CancelOrder(orderId string) error {
+// if order get cancel then increase a product count, a paid order waits in
+// REFUND_PENDING until the refund of what is left of its payment settles. Once
+// some units have shipped only the units left can be cancelled, see CancelOrderUnits
+func (ser *orderService) CancelOrder(ctx context.Context, orderId string) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CancelOrder", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
 	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
 
 	if valErr != nil {
 		return valErr
 	}
 
-	order, orderErr := ser.orderRepo.GetOrderById(orderObjId)
+	order, orderErr := ser.orderRepo.GetOrderById(ctx, orderObjId)
 
 	if orderErr != nil {
 		return orderErr
 	}
 
-	upErr := ser.UpdateOrderStatus(orderId, helper.CANCELLED)
-	if upErr != nil {
-		return upErr
+	payment, refundable, err := refundablePayment(ctx, ser.paymentRepo, orderObjId)
+	if err != nil {
+		return err
+	}
+
+	// REFUND_PENDING without any refund recorded is a cancellation that failed to
+	// record its refund, a retry finishes it with the refund and the restock. A
+	// refund that failed at the gateway is retried through RetryRefund only
+	resuming := false
+	if order.OrderStatus == helper.REFUND_PENDING && refundable > 0 {
+		refunds, err := ser.paymentRepo.GetOrderRefunds(ctx, orderObjId)
+		if err != nil {
+			return err
+		}
+		resuming = len(refunds) == 0
+	}
+	if helper.IsClosedStatus(order.OrderStatus) && !resuming {
+		return ErrOrderClosed
+	}
+	if order.ShippedQuantity > 0 && order.UnshippedQuantity() > 0 {
+		return ErrUnitsShipped
+	}
+	if order.OrderStatus == helper.DISPATCHED {
+		return ErrOrderDispatched
 	}
 
-	quantity := strconv.Itoa(int(order.Quantity))
+	logger := ser.orderLogger(order)
+	if resuming {
+		logger.WarnContext(ctx, "finishing an interrupted cancellation", "refundable", refundable)
+	} else {
+		status := helper.CANCELLED
+		if refundable > 0 {
+			status = helper.REFUND_PENDING
+		}
 
-	err := ser.UpdateProductCount("increase", quantity, order.ProductId.String())
+		// only the order as it was read is cancelled, a concurrent cancellation
+		// such as CancelStaleOrders gets ErrOrderChanged and stops here
+		if err := ser.changeStatus(ctx, order, status); err != nil {
+			return err
+		}
+		if trackErr := ser.trackStatus(ctx, order, status); trackErr != nil {
+			logger.ErrorContext(ctx, "failed to track cancelled order", "error", trackErr)
+		}
+		logger.InfoContext(ctx, "order cancelled", "product_id", order.ProductId.Hex(), "quantity", order.Quantity, "status", status)
+	}
+
+	if refundable > 0 {
+		_, err := requestRefund(ctx, ser.paymentRepo, payment, refundable, helper.REFUND_REASON_CANCELLED, cancelRefundKey(order.Id))
+		switch {
+		case errors.Is(err, repositories.ErrRefundExists):
+			logger.WarnContext(ctx, "refund of cancelled order already requested", "payment_id", payment.Id.Hex())
+		case err != nil:
+			logger.ErrorContext(ctx, "failed to request refund of cancelled order", "payment_id", payment.Id.Hex(), "error", err)
+			return err
+		default:
+			logger.InfoContext(ctx, "refund requested", "payment_id", payment.Id.Hex(), "amount", refundable)
+		}
+	}
+
+	// flag the restock before sending it, a resumed cancellation never restocks twice
+	if err := ser.orderRepo.MarkOrderRestocked(ctx, order.Id); err != nil {
+		if errors.Is(err, repositories.ErrOrderChanged) {
+			logger.WarnContext(ctx, "cancelled order already restocked")
+			return nil
+		}
+		return err
+	}
+
+	quantity := strconv.Itoa(int(order.UnshippedQuantity()))
+
+	err = ser.UpdateProductCount(ctx, "increase", quantity, order.ProductId.Hex())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to restock cancelled order", "product_id", order.ProductId.Hex(), "error", err)
+	}
 	return err
 }
+
+// key of the refund of a cancelled order, one per order
+func cancelRefundKey(orderId primitive.ObjectID) string {
+	return "cancel:" + orderId.Hex()
+}
+
+// cancel quantity of the units not shipped yet. The order keeps its status and
+// TotalPrice covers the units left, only the cancelled units are restocked and
+// refunded. Cancelling every unit of the order cancels the whole order
+func (ser *orderService) CancelOrderUnits(ctx context.Context, orderId string, quantity int64) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CancelOrderUnits", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+	span.SetAttributes(attribute.Int64("order.units", quantity))
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return models.Orders{}, valErr
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+
+	if helper.IsClosedStatus(order.OrderStatus) {
+		return models.Orders{}, ErrOrderClosed
+	}
+	if order.OrderStatus == helper.CART {
+		return models.Orders{}, errors.New("cart lines are changed through the cart")
+	}
+
+	unshipped := order.UnshippedQuantity()
+	if quantity <= 0 || quantity > unshipped {
+		return models.Orders{}, fmt.Errorf("%w: %d of %d units can still be cancelled", ErrUnitsUnavailable, unshipped, order.Quantity)
+	}
+
+	if quantity == order.Quantity {
+		if err := ser.CancelOrder(ctx, orderId); err != nil {
+			return models.Orders{}, err
+		}
+		return ser.orderRepo.GetOrderById(ctx, orderObjId)
+	}
+
+	// the pending intent was created for the old total
+	if order.OrderStatus == helper.PENDING_PAYMENT {
+		attempts, err := ser.paymentRepo.GetOrderPayments(ctx, orderObjId)
+		if err != nil {
+			return models.Orders{}, err
+		}
+		for _, attempt := range attempts {
+			if attempt.Status == helper.PAYMENT_PENDING {
+				return models.Orders{}, ErrPaymentInProgress
+			}
+		}
+	}
+
+	updated := order
+	updated.Quantity -= quantity
+	updated.CancelledQuantity += quantity
+	updated.TotalPrice = order.TotalPrice / float64(order.Quantity) * float64(updated.Quantity)
+	if updated.OrderStatus == helper.DISPATCHED {
+		updated.BackorderedQuantity = updated.UnshippedQuantity()
+	}
+
+	if err := ser.orderRepo.UpdateOrderUnits(ctx, updated, order); err != nil {
+		return models.Orders{}, err
+	}
+
+	logger := ser.orderLogger(order)
+	logger.InfoContext(ctx, "order units cancelled", "product_id", order.ProductId.Hex(), "units", quantity, "quantity", updated.Quantity, "total_price", updated.TotalPrice)
+
+	if err := ser.trackUnits(ctx, updated, helper.CANCELLED, quantity); err != nil {
+		return updated, err
+	}
+
+	payment, refundable, err := refundablePayment(ctx, ser.paymentRepo, orderObjId)
+	if err != nil {
+		return updated, err
+	}
+	if amount := min(order.TotalPrice-updated.TotalPrice, refundable); !payment.Id.IsZero() && amount > 0 {
+		if _, err := requestRefund(ctx, ser.paymentRepo, payment, amount, helper.REFUND_REASON_CANCELLED, ""); err != nil {
+			logger.ErrorContext(ctx, "failed to request refund of cancelled units", "payment_id", payment.Id.Hex(), "error", err)
+			return updated, err
+		}
+		logger.InfoContext(ctx, "refund requested", "payment_id", payment.Id.Hex(), "amount", amount)
+	}
+
+	err = ser.UpdateProductCount(ctx, "increase", strconv.FormatInt(quantity, 10), order.ProductId.Hex())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to restock cancelled units", "product_id", order.ProductId.Hex(), "error", err)
+	}
+	return updated, err
+}
+
+// change the quantity or swap the selling variant of an order that has not been
+// dispatched. Stock moves by the quantity delta, the order is repriced and a
+// paid order, which can only get cheaper, is refunded the difference
+func (ser *orderService) EditOrder(ctx context.Context, orderId string, edit dto.EditOrderDTO) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.EditOrder", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return models.Orders{}, valErr
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+
+	switch {
+	case order.OrderStatus == helper.DISPATCHED:
+		return models.Orders{}, ErrOrderDispatched
+	case helper.IsClosedStatus(order.OrderStatus):
+		return models.Orders{}, ErrOrderClosed
+	case order.OrderStatus == helper.CART:
+		return models.Orders{}, errors.New("cart lines are changed through the cart")
+	case order.OrderStatus != helper.PENDING_PAYMENT && order.OrderStatus != helper.PLACED:
+		return models.Orders{}, fmt.Errorf("a %s order can not be edited", order.OrderStatus)
+	}
+
+	edited := order
+	if edit.Quantity > 0 {
+		edited.Quantity = edit.Quantity
+	}
+	unitPrice := order.TotalPrice / float64(order.Quantity)
+	if edit.ProductSellingID != "" {
+		sellingId, valErr := helper.ValidatePrimitiveId(edit.ProductSellingID)
+		if valErr != nil {
+			return models.Orders{}, valErr
+		}
+		if sellingId != order.ProductSellingID {
+			// the new variant is charged at what the product service sells it for today
+			product, err := ser.inventory.GetProduct(ctx, sellingId.Hex())
+			if errors.Is(err, clients.ErrProductNotFound) {
+				return models.Orders{}, ErrProductDiscontinued
+			}
+			if err != nil {
+				return models.Orders{}, err
+			}
+			if product.ProductId != order.ProductId.Hex() {
+				return models.Orders{}, ErrOtherProduct
+			}
+			if product.Price <= 0 {
+				return models.Orders{}, errors.New("the new variant has no price")
+			}
+			edited.ProductSellingID = sellingId
+			unitPrice = float64(product.Price)
+		}
+	}
+	edited.TotalPrice = unitPrice * float64(edited.Quantity)
+
+	if edited.Quantity == order.Quantity && edited.ProductSellingID == order.ProductSellingID {
+		return models.Orders{}, errors.New("the edit does not change the order")
+	}
+
+	if edited.Quantity > order.Quantity {
+		line := dto.CreateOrderDTO{UserId: order.UserId.Hex(), ProductId: order.ProductId.Hex(), Category: order.Category, Quantity: edited.Quantity}
+		if err := ser.limits.CheckCartLine(ctx, line); err != nil {
+			return models.Orders{}, err
+		}
+		// the order's own units are already in the window, only the extra ones are new
+		line.Quantity = edited.Quantity - order.Quantity
+		if err := ser.limits.CheckProductUnits(ctx, line); err != nil {
+			return models.Orders{}, err
+		}
+	}
+
+	payment, refundable, err := refundablePayment(ctx, ser.paymentRepo, orderObjId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+	if order.OrderStatus == helper.PENDING_PAYMENT {
+		// the pending intent was created for the old total
+		attempts, err := ser.paymentRepo.GetOrderPayments(ctx, orderObjId)
+		if err != nil {
+			return models.Orders{}, err
+		}
+		for _, attempt := range attempts {
+			if attempt.Status == helper.PAYMENT_PENDING {
+				return models.Orders{}, ErrPaymentInProgress
+			}
+		}
+	} else if !payment.Id.IsZero() && edited.TotalPrice > refundable {
+		return models.Orders{}, fmt.Errorf("%w: %.2f was paid, the edited order costs %.2f", ErrEditExceedsPayment, refundable, edited.TotalPrice)
+	}
+
+	logger := ser.orderLogger(order)
+	delta := edited.Quantity - order.Quantity
+
+	// reserve the extra units before the order claims them
+	if delta > 0 {
+		if err := ser.UpdateProductCount(ctx, "decrease", strconv.FormatInt(delta, 10), order.ProductId.Hex()); err != nil {
+			return models.Orders{}, err
+		}
+	}
+
+	if err := ser.orderRepo.UpdateOrderItem(ctx, edited, order); err != nil {
+		if delta > 0 {
+			if restockErr := ser.UpdateProductCount(ctx, "increase", strconv.FormatInt(delta, 10), order.ProductId.Hex()); restockErr != nil {
+				logger.ErrorContext(ctx, "failed to release units of a failed edit", "product_id", order.ProductId.Hex(), "units", delta, "error", restockErr)
+			}
+		}
+		return models.Orders{}, err
+	}
+
+	logger.InfoContext(ctx, "order edited", "quantity", edited.Quantity, "prod_selling_id", edited.ProductSellingID.Hex(), "total_price", edited.TotalPrice, "actor", helper.Actor(ctx))
+
+	orderTrack := new(models.OrderTrack).SetOrderTrack(order.Id, order.OrderStatus)
+	orderTrack.Actor = helper.Actor(ctx)
+	orderTrack.Edit = &models.OrderEdit{
+		FromQuantity:         order.Quantity,
+		ToQuantity:           edited.Quantity,
+		FromProductSellingID: order.ProductSellingID,
+		ToProductSellingID:   edited.ProductSellingID,
+		FromTotalPrice:       order.TotalPrice,
+		ToTotalPrice:         edited.TotalPrice,
+	}
+	if err := ser.orderRepo.CreateOrderTrack(ctx, orderTrack); err != nil {
+		return edited, err
+	}
+	ser.publisher.Publish(events.OrderEvent{
+		Type:   events.ORDER_STATUS_CHANGED,
+		UserId: order.UserId,
+		Track:  orderTrack,
+	})
+
+	if amount := refundable - edited.TotalPrice; !payment.Id.IsZero() && amount > 0 {
+		if _, err := requestRefund(ctx, ser.paymentRepo, payment, amount, helper.REFUND_REASON_EDITED, ""); err != nil {
+			logger.ErrorContext(ctx, "failed to request refund of edited order", "payment_id", payment.Id.Hex(), "error", err)
+			return edited, err
+		}
+		logger.InfoContext(ctx, "refund requested", "payment_id", payment.Id.Hex(), "amount", amount)
+	}
+
+	if delta < 0 {
+		err = ser.UpdateProductCount(ctx, "increase", strconv.FormatInt(-delta, 10), order.ProductId.Hex())
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to restock units removed by an edit", "product_id", order.ProductId.Hex(), "error", err)
+		}
+	}
+	return edited, err
+}
+
+// buy a live or archived order again at today's price, as a new order or into
+// the user's cart. Units the product service can not supply any more are
+// reported as unavailable instead of failing the reorder
+func (ser *orderService) Reorder(ctx context.Context, orderId, mode string) (_ models.Reorder, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.Reorder", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+	span.SetAttributes(attribute.String("reorder.mode", mode))
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return models.Reorder{}, valErr
+	}
+
+	source, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if errors.Is(err, repositories.ErrOrderNotFound) {
+		history, hisErr := ser.orderRepo.GetOrderHistory(ctx, orderObjId)
+		source, err = history.Order, hisErr
+	}
+	if err != nil {
+		return models.Reorder{}, err
+	}
+	if source.OrderStatus == helper.CART {
+		return models.Reorder{}, errors.New("cart lines can not be reordered")
+	}
+
+	reorder := models.Reorder{
+		SourceOrderId: source.Id,
+		Mode:          mode,
+		Orders:        []models.Orders{},
+		Unavailable:   []models.UnavailableItem{},
+	}
+	// a partially cancelled order is bought again with the units it kept
+	unavailable := models.UnavailableItem{
+		ProductId:        source.ProductId,
+		ProductSellingID: source.ProductSellingID,
+		Quantity:         source.Quantity,
+	}
+
+	product, err := ser.inventory.GetProduct(ctx, source.ProductSellingID.Hex())
+	if errors.Is(err, clients.ErrProductNotFound) {
+		unavailable.Reason = helper.UNAVAILABLE_DISCONTINUED
+		reorder.Unavailable = append(reorder.Unavailable, unavailable)
+		return reorder, nil
+	}
+	if err != nil {
+		return models.Reorder{}, err
+	}
+
+	quantity := min(source.Quantity, product.Available)
+	if quantity < source.Quantity {
+		unavailable.Quantity = source.Quantity - quantity
+		unavailable.Reason = helper.UNAVAILABLE_OUT_OF_STOCK
+		reorder.Unavailable = append(reorder.Unavailable, unavailable)
+	}
+	if quantity <= 0 {
+		return reorder, nil
+	}
+
+	line := dto.CreateOrderDTO{
+		ProductId:        source.ProductId.Hex(),
+		Category:         source.Category,
+		ProductSellingID: source.ProductSellingID.Hex(),
+		Quantity:         quantity,
+		Price:            product.Price,
+		UserId:           source.UserId.Hex(),
+	}
+
+	var placed models.Orders
+	if mode == helper.REORDER_TO_CART {
+		placed, err = ser.AddToCart(ctx, line)
+	} else {
+		placed, err = ser.PlaceSingleOrder(ctx, line)
+	}
+	if err != nil {
+		return models.Reorder{}, err
+	}
+	reorder.Orders = append(reorder.Orders, placed)
+	reorder.StockNotUpdated = placed.StockNotUpdated
+
+	ser.orderLogger(placed).InfoContext(ctx, "order reordered", "source_order_id", source.Id.Hex(), "mode", mode, "quantity", quantity, "unavailable", source.Quantity-quantity)
+	return reorder, nil
+}
+
+// ship quantity of the units not shipped yet. The order is DISPATCHED from the
+// first shipment on, the units left are backordered until shipped or cancelled
+func (ser *orderService) ShipOrderUnits(ctx context.Context, orderId string, quantity int64) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.ShipOrderUnits", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+	span.SetAttributes(attribute.Int64("order.units", quantity))
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return models.Orders{}, valErr
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+
+	switch {
+	case order.OrderStatus == helper.PENDING_PAYMENT:
+		return models.Orders{}, ErrPaymentPending
+	case helper.IsClosedStatus(order.OrderStatus):
+		return models.Orders{}, ErrOrderClosed
+	case order.OrderStatus != helper.PLACED && order.OrderStatus != helper.DISPATCHED:
+		return models.Orders{}, fmt.Errorf("a %s order can not be shipped", order.OrderStatus)
+	}
+
+	return ser.shipUnits(ctx, order, quantity)
+}
+
+func (ser *orderService) shipUnits(ctx context.Context, order models.Orders, quantity int64) (models.Orders, error) {
+	unshipped := order.UnshippedQuantity()
+	if quantity <= 0 || quantity > unshipped {
+		return models.Orders{}, fmt.Errorf("%w: %d of %d units are left to ship", ErrUnitsUnavailable, unshipped, order.Quantity)
+	}
+
+	updated := order
+	updated.OrderStatus = helper.DISPATCHED
+	updated.ShippedQuantity += quantity
+	updated.BackorderedQuantity = updated.UnshippedQuantity()
+
+	if err := ser.orderRepo.UpdateOrderUnits(ctx, updated, order); err != nil {
+		return models.Orders{}, err
+	}
+	if order.OrderStatus != helper.DISPATCHED {
+		metrics.OrderStatusTransitions.WithLabelValues(order.OrderStatus, helper.DISPATCHED).Inc()
+	}
+
+	ser.orderLogger(order).InfoContext(ctx, "order units shipped", "from", order.OrderStatus, "units", quantity, "backordered", updated.BackorderedQuantity, "actor", helper.Actor(ctx))
+	return updated, ser.trackUnits(ctx, updated, helper.DISPATCHED, quantity)
+}
+
+// record that units of the order moved to unitStatus, the entry carries the order's current status
+func (ser *orderService) trackUnits(ctx context.Context, order models.Orders, unitStatus string, units int64) error {
+	orderTrack := new(models.OrderTrack).SetOrderTrack(order.Id, order.OrderStatus)
+	orderTrack.Actor = helper.Actor(ctx)
+	orderTrack.UnitStatus = unitStatus
+	orderTrack.Units = units
+
+	if err := ser.orderRepo.CreateOrderTrack(ctx, orderTrack); err != nil {
+		return err
+	}
+	ser.publisher.Publish(events.OrderEvent{
+		Type:   events.ORDER_STATUS_CHANGED,
+		UserId: order.UserId,
+		Track:  orderTrack,
+	})
+	return nil
+}
+
+// the entry keeps the order's status, refunds of archived orders are not tracked
+func (ser *orderService) TrackRefund(ctx context.Context, refund models.Refund) error {
+	order, err := ser.orderRepo.GetOrderById(ctx, refund.OrderId)
+	if errors.Is(err, repositories.ErrOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	orderTrack := new(models.OrderTrack).SetOrderTrack(order.Id, order.OrderStatus)
+	orderTrack.Actor = helper.Actor(ctx)
+	orderTrack.RefundId = &refund.Id
+	orderTrack.RefundStatus = refund.Status
+
+	if err := ser.orderRepo.CreateOrderTrack(ctx, orderTrack); err != nil {
+		return err
+	}
+	ser.publisher.Publish(events.OrderEvent{
+		Type:   events.ORDER_STATUS_CHANGED,
+		UserId: order.UserId,
+		Track:  orderTrack,
+	})
+	return nil
+}
+
+// cancel orders that stayed in a status longer than its sla through CancelOrder,
+// their track entries carry the auto-cancel actor. Returns how many were cancelled
+func (ser *orderService) CancelStaleOrders(ctx context.Context, slas map[string]time.Duration) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CancelStaleOrders")
+	defer tracing.End(span, &err)
+	ctx = helper.WithActor(ctx, helper.ACTOR_AUTO_CANCEL)
+
+	statuses := make([]string, 0, len(slas))
+	for status := range slas {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	cancelled := 0
+	var errs []error
+	for _, status := range statuses {
+		sla := slas[status]
+		if sla <= 0 {
+			continue
+		}
+
+		stale, staleErr := ser.orderRepo.GetStaleOrders(ctx, status, time.Now().Add(-sla), AUTO_CANCEL_BATCH)
+		if staleErr != nil {
+			errs = append(errs, staleErr)
+			continue
+		}
+
+		for _, order := range stale {
+			cancelErr := ser.CancelOrder(ctx, order.Id.Hex())
+			if errors.Is(cancelErr, ErrOrderClosed) || errors.Is(cancelErr, repositories.ErrOrderChanged) ||
+				errors.Is(cancelErr, repositories.ErrOrderNotFound) {
+				// changed since the scan, nothing left to do
+				continue
+			}
+
+			logger := ser.orderLogger(order)
+			if cancelErr != nil {
+				logger.ErrorContext(ctx, "failed to auto-cancel order", "status", status, "error", cancelErr)
+				errs = append(errs, cancelErr)
+				continue
+			}
+
+			cancelled++
+			metrics.AutoCancelledOrders.WithLabelValues(status).Inc()
+			logger.WarnContext(ctx, "order auto-cancelled", "status", status, "sla", sla, "updated_at", order.UpdatedAt.Time())
+		}
+	}
+
+	span.SetAttributes(attribute.Int("order.auto_cancelled", cancelled))
+	return cancelled, errors.Join(errs...)
+}
+
+// report carts idle past the policy, once per abandonment, then remove cart
+// lines older than the ttl together with their CART orders
+func (ser *orderService) SweepCarts(ctx context.Context, policy CartPolicy) (_ CartSweep, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.SweepCarts")
+	defer tracing.End(span, &err)
+
+	sweep := CartSweep{}
+	var errs []error
+	now := time.Now()
+
+	carts, err := ser.orderRepo.GetAbandonedCarts(ctx, now.Add(-policy.AbandonedAfter), policy.AbandonedMinValue, true, CART_SWEEP_BATCH)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, cart := range carts {
+		// stamping first keeps another sweep from reporting the same abandonment
+		marked, markErr := ser.orderRepo.MarkCartAbandoned(ctx, cart.UserId, now)
+		if markErr != nil {
+			errs = append(errs, markErr)
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		sweep.Abandoned++
+		metrics.AbandonedCarts.Inc()
+		ser.publisher.Publish(events.OrderEvent{
+			Type:   events.CART_ABANDONED,
+			UserId: cart.UserId,
+			Cart:   cart,
+		})
+		ser.logger.InfoContext(ctx, "cart abandoned", "user_id", cart.UserId.Hex(), "lines", cart.Lines, "value", cart.Value, "last_activity_at", cart.LastActivityAt.Time())
+	}
+
+	lines, err := ser.orderRepo.GetStaleCartLines(ctx, now.Add(-policy.TTL), CART_SWEEP_BATCH)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, line := range lines {
+		if expireErr := ser.expireCartLine(ctx, line); expireErr != nil {
+			ser.logger.ErrorContext(ctx, "failed to expire cart line", "order_id", line.OrderId.Hex(), "user_id", line.UserId.Hex(), "error", expireErr)
+			errs = append(errs, expireErr)
+			continue
+		}
+
+		sweep.Expired++
+		metrics.ExpiredCartLines.Inc()
+	}
+
+	span.SetAttributes(attribute.Int("cart.abandoned", sweep.Abandoned), attribute.Int("cart.expired_lines", sweep.Expired))
+	return sweep, errors.Join(errs...)
+}
+
+// drop the line and its order, an order that left the cart is kept
+func (ser *orderService) expireCartLine(ctx context.Context, line models.OrderCarts) error {
+	order, orderErr := ser.orderRepo.GetOrderById(ctx, line.OrderId)
+	if orderErr != nil && !errors.Is(orderErr, repositories.ErrOrderNotFound) {
+		return orderErr
+	}
+
+	if err := ser.orderRepo.DeleteCartOrder(ctx, line.OrderId); err != nil {
+		return err
+	}
+
+	if orderErr == nil && order.OrderStatus == helper.CART {
+		if err := ser.orderRepo.DeleteOrder(ctx, line.OrderId); err != nil {
+			return err
+		}
+	}
+
+	ser.logger.InfoContext(ctx, "cart line expired", "order_id", line.OrderId.Hex(), "user_id", line.UserId.Hex(), "updated_at", line.UpdatedAt.Time())
+	return nil
+}
+
+// carts idle for at least idle and worth at least minValue, reported or not
+func (ser *orderService) GetAbandonedCarts(ctx context.Context, idle time.Duration, minValue float64, limit int64) (_ []models.AbandonedCart, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetAbandonedCarts")
+	defer tracing.End(span, &err)
+
+	return ser.orderRepo.GetAbandonedCarts(ctx, time.Now().Add(-idle), minValue, false, limit)
+}
+
+// live order, or the archived copy once it has been completed
+func (ser *orderService) GetOrder(ctx context.Context, orderId string) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrder", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return models.Orders{}, valErr
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if !errors.Is(err, repositories.ErrOrderNotFound) {
+		return order, err
+	}
+
+	history, err := ser.orderRepo.GetOrderHistory(ctx, orderObjId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+	return history.Order, nil
+}
+
+// status changes of the order oldest first, from history once archived
+func (ser *orderService) GetOrderTrack(ctx context.Context, orderId string) (_ []models.OrderTrack, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrderTrack", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	if _, err = ser.orderRepo.GetOrderById(ctx, orderObjId); err == nil {
+		return ser.orderRepo.GetAllOrderTrack(ctx, orderObjId)
+	}
+	if !errors.Is(err, repositories.ErrOrderNotFound) {
+		return nil, err
+	}
+
+	history, err := ser.orderRepo.GetOrderHistory(ctx, orderObjId)
+	if err != nil {
+		return nil, err
+	}
+	return history.OrderTrack, nil
+}
+
+// track entries of the order newer than lastTrackId, every entry when it is empty
+func (ser *orderService) GetOrderTrackSince(ctx context.Context, orderId, lastTrackId string) (_ []models.OrderTrack, err error) {
+	tracks, err := ser.GetOrderTrack(ctx, orderId)
+	if err != nil || lastTrackId == "" {
+		return tracks, err
+	}
+
+	lastId, valErr := helper.ValidatePrimitiveId(lastTrackId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	newer := []models.OrderTrack{}
+	for _, track := range tracks {
+		if bytes.Compare(track.Id[:], lastId[:]) > 0 {
+			newer = append(newer, track)
+		}
+	}
+	return newer, nil
+}
+
+// track entries of the user's live orders newer than lastTrackId, orders
+// archived in the meantime are not replayed
+func (ser *orderService) GetUserOrderTrackSince(ctx context.Context, userId, lastTrackId string) (_ []models.OrderTrack, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetUserOrderTrackSince", userAttrs(userId))
+	defer tracing.End(span, &err)
+
+	userObjId, valErr := helper.ValidatePrimitiveId(userId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	lastId, valErr := helper.ValidatePrimitiveId(lastTrackId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	orders, err := ser.orderRepo.GetOrdersByUser(ctx, userObjId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	orderIds := make([]primitive.ObjectID, 0, len(orders))
+	for _, order := range orders {
+		orderIds = append(orderIds, order.Id)
+	}
+	return ser.orderRepo.GetOrderTracksAfter(ctx, orderIds, lastId)
+}
+
+// live orders of the user, status narrows the list when set
+func (ser *orderService) ListOrders(ctx context.Context, userId, status string) (_ []models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.ListOrders", userAttrs(userId))
+	defer tracing.End(span, &err)
+
+	userObjId, valErr := helper.ValidatePrimitiveId(userId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	return ser.orderRepo.GetOrdersByUser(ctx, userObjId, status)
+}
+
+// orders behind every cart line of the user
+func (ser *orderService) GetCartItems(ctx context.Context, userId string) (_ []models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetCartItems", userAttrs(userId))
+	defer tracing.End(span, &err)
+
+	userObjId, valErr := helper.ValidatePrimitiveId(userId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	cartItems, err := ser.orderRepo.UserCartItem(ctx, userObjId)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]models.Orders, 0, len(cartItems))
+	for _, item := range cartItems {
+		order, orderErr := ser.orderRepo.GetOrderById(ctx, item.OrderId)
+		if errors.Is(orderErr, repositories.ErrOrderNotFound) {
+			continue
+		}
+		if orderErr != nil {
+			return nil, orderErr
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// remove a cart line only when it belongs to the given user
+func (ser *orderService) RemoveItemFromUserCart(ctx context.Context, userId, orderId string) error {
+	userObjId, valErr := helper.ValidatePrimitiveId(userId)
+	if valErr != nil {
+		return valErr
+	}
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return valErr
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if err != nil {
+		return err
+	}
+
+	if order.UserId != userObjId || order.OrderStatus != helper.CART {
+		return ErrNotInCart
+	}
+
+	return ser.RemoveItemFromCart(ctx, orderId)
+}
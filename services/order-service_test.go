@@ -0,0 +1,996 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/events"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/payments"
+	"github.com/aniket0951/order-services/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeInventory httptest stand-in for the product service stock and product endpoints,
+// calls records the stock updates only
+type fakeInventory struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	calls    []url.Values
+	failWith int
+	products map[string]clients.ProductInfo
+}
+
+func newFakeInventory(t *testing.T) *fakeInventory {
+	fake := &fakeInventory{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		if r.Method == http.MethodGet && r.URL.Path == "/api/"+helper.GET_SELLING_PRODUCT {
+			product, ok := fake.products[r.URL.Query().Get("prod_selling_id")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": product})
+			return
+		}
+
+		if r.Method != http.MethodPut || r.URL.Path != "/api/"+helper.INCREASE_DECREASE_PRODUCT {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fake.calls = append(fake.calls, r.URL.Query())
+		if fake.failWith != 0 {
+			w.WriteHeader(fake.failWith)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeInventory) SetProduct(product clients.ProductInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.products == nil {
+		f.products = map[string]clients.ProductInfo{}
+	}
+	f.products[product.ProductSellingID] = product
+}
+
+func (f *fakeInventory) Calls() []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]url.Values(nil), f.calls...)
+}
+
+type serviceFixture struct {
+	repo      repositories.OrderRepository
+	inventory *fakeInventory
+	broker    *events.Broker
+	service   OrderService
+
+	paymentRepo    repositories.PaymentRepository
+	gateway        *payments.MockGateway
+	paymentService PaymentService
+}
+
+func newServiceFixture(t *testing.T) *serviceFixture {
+	inventory := newFakeInventory(t)
+
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = inventory.server.URL + "/api/"
+
+	repo := repositories.NewMemoryOrderRepository()
+	paymentRepo := repositories.NewMemoryPaymentRepository()
+	broker := events.NewBroker(16)
+	f := &serviceFixture{
+		repo:        repo,
+		inventory:   inventory,
+		broker:      broker,
+		service:     NewOrderService(repo, paymentRepo, clients.NewInventoryClient(cfg, logging.Discard()), NewLimitsPolicy(repo, OrderLimits{MaxQuantity: 10}), broker, logging.Discard()),
+		paymentRepo: paymentRepo,
+		gateway:     payments.NewMockGateway("test-secret"),
+	}
+	f.paymentService = NewPaymentService(repo, paymentRepo, f.gateway, f.service, "INR", RefundPolicy{MaxAttempts: 3, Backoff: time.Minute}, logging.Discard())
+	return f
+}
+
+// place the order and mark it paid, the way a successful payment callback leaves it
+func (f *serviceFixture) placePaidOrder(t *testing.T, order dto.CreateOrderDTO) models.Orders {
+	t.Helper()
+	ctx := context.Background()
+
+	placed, err := f.service.PlaceSingleOrder(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.repo.UpdateOrderStatus(ctx, helper.PLACED, placed.Id); err != nil {
+		t.Fatal(err)
+	}
+	placed.OrderStatus = helper.PLACED
+	return placed
+}
+
+func validOrderDTO() dto.CreateOrderDTO {
+	return dto.CreateOrderDTO{
+		ProductId:        primitive.NewObjectID().Hex(),
+		Category:         "BOOKS",
+		ProductSellingID: primitive.NewObjectID().Hex(),
+		Quantity:         3,
+		Price:            150,
+		UserId:           primitive.NewObjectID().Hex(),
+	}
+}
+
+func TestPlaceSingleOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		mutate        func(*dto.CreateOrderDTO)
+		inventoryFail int
+		wantErr       bool
+		wantStored    bool
+		wantCalls     int
+		wantNoStock   bool
+	}{
+		{name: "places order and decreases stock", wantStored: true, wantCalls: 1},
+		{name: "rejects quantity above limit", mutate: func(o *dto.CreateOrderDTO) { o.Quantity = 11 }, wantErr: true},
+		{name: "rejects invalid product id", mutate: func(o *dto.CreateOrderDTO) { o.ProductId = "bad" }, wantErr: true},
+		{name: "rejects invalid selling id", mutate: func(o *dto.CreateOrderDTO) { o.ProductSellingID = "bad" }, wantErr: true},
+		{name: "reports product service failure with the order", inventoryFail: http.StatusInternalServerError, wantStored: true, wantCalls: 1, wantNoStock: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+			f.inventory.failWith = tt.inventoryFail
+
+			req := validOrderDTO()
+			if tt.mutate != nil {
+				tt.mutate(&req)
+			}
+
+			placed, err := f.service.PlaceSingleOrder(context.Background(), req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlaceSingleOrder err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if placed.StockNotUpdated != tt.wantNoStock {
+				t.Fatalf("StockNotUpdated = %v, want %v", placed.StockNotUpdated, tt.wantNoStock)
+			}
+
+			if tt.wantStored {
+				stored, getErr := f.repo.GetOrderById(context.Background(), placed.Id)
+				if getErr != nil {
+					t.Fatalf("placed order not stored: %v", getErr)
+				}
+				if stored.OrderStatus != helper.PENDING_PAYMENT || stored.TotalPrice != 450 || stored.Quantity != 3 {
+					t.Fatalf("stored order = %+v", stored)
+				}
+			}
+
+			calls := f.inventory.Calls()
+			if len(calls) != tt.wantCalls {
+				t.Fatalf("inventory calls = %d, want %d", len(calls), tt.wantCalls)
+			}
+			if tt.wantCalls > 0 {
+				if calls[0].Get("tag") != "decrease" || calls[0].Get("number") != "3" || calls[0].Get("product_id") != req.ProductId {
+					t.Fatalf("inventory call = %v", calls[0])
+				}
+			}
+		})
+	}
+}
+
+func TestAddToCart(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*dto.CreateOrderDTO)
+		wantErr bool
+	}{
+		{name: "adds order to cart"},
+		{name: "rejects quantity above limit", mutate: func(o *dto.CreateOrderDTO) { o.Quantity = 20 }, wantErr: true},
+		{name: "rejects invalid user id", mutate: func(o *dto.CreateOrderDTO) { o.UserId = "bad" }, wantErr: true},
+		{name: "rejects invalid product id", mutate: func(o *dto.CreateOrderDTO) { o.ProductId = "" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+
+			req := validOrderDTO()
+			if tt.mutate != nil {
+				tt.mutate(&req)
+			}
+
+			added, err := f.service.AddToCart(context.Background(), req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddToCart err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			stored, err := f.repo.GetOrderById(context.Background(), added.Id)
+			if err != nil || stored.OrderStatus != helper.CART {
+				t.Fatalf("cart order = %+v, %v", stored, err)
+			}
+
+			items, _ := f.repo.UserCartItem(context.Background(), added.UserId)
+			if len(items) != 1 || items[0].OrderId != added.Id {
+				t.Fatalf("cart lines = %+v", items)
+			}
+
+			if calls := f.inventory.Calls(); len(calls) != 0 {
+				t.Fatalf("adding to cart must not touch stock, got %v", calls)
+			}
+		})
+	}
+}
+
+func TestRemoveItemFromCart(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderId func(f *serviceFixture, cartOrder models.Orders) string
+		wantErr error
+		anyErr  bool
+	}{
+		{name: "removes cart line and order", orderId: func(_ *serviceFixture, o models.Orders) string { return o.Id.Hex() }},
+		{name: "rejects invalid id", orderId: func(*serviceFixture, models.Orders) string { return "bad" }, anyErr: true},
+		{name: "unknown order", orderId: func(*serviceFixture, models.Orders) string { return primitive.NewObjectID().Hex() }, wantErr: repositories.ErrOrderNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+			cartOrder, err := f.service.AddToCart(context.Background(), validOrderDTO())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = f.service.RemoveItemFromCart(context.Background(), tt.orderId(f, cartOrder))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			case err != nil:
+				t.Fatalf("RemoveItemFromCart: %v", err)
+			}
+
+			if _, err := f.repo.GetOrderById(context.Background(), cartOrder.Id); !errors.Is(err, repositories.ErrOrderNotFound) {
+				t.Fatalf("order should be deleted, got %v", err)
+			}
+			if items, _ := f.repo.UserCartItem(context.Background(), cartOrder.UserId); len(items) != 0 {
+				t.Fatalf("cart should be empty, got %+v", items)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		missing     bool
+		unpaid      bool
+		dispatched  bool
+		wantErr     error
+		wantArchive bool
+	}{
+		{name: "dispatch records track", status: helper.DISPATCHED},
+		{name: "complete archives order history", status: helper.COMPLETED, dispatched: true, wantArchive: true},
+		{name: "placed order is not completed", status: helper.COMPLETED, wantErr: ErrStatusTransition},
+		{name: "unknown order", status: helper.DISPATCHED, missing: true, wantErr: repositories.ErrOrderNotFound},
+		{name: "unpaid order is not dispatched", status: helper.DISPATCHED, unpaid: true, wantErr: ErrPaymentPending},
+		{name: "unpaid order is not completed", status: helper.COMPLETED, unpaid: true, wantErr: ErrPaymentPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+			ctx := context.Background()
+
+			var placed models.Orders
+			if tt.unpaid {
+				placed, _ = f.service.PlaceSingleOrder(ctx, validOrderDTO())
+			} else {
+				placed = f.placePaidOrder(t, validOrderDTO())
+			}
+			if tt.dispatched {
+				if err := f.service.UpdateOrderStatus(ctx, placed.Id.Hex(), helper.DISPATCHED); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			orderId := placed.Id.Hex()
+			if tt.missing {
+				orderId = primitive.NewObjectID().Hex()
+			}
+
+			err := f.service.UpdateOrderStatus(ctx, orderId, tt.status)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateOrderStatus: %v", err)
+			}
+
+			if !tt.wantArchive {
+				stored, _ := f.repo.GetOrderById(ctx, placed.Id)
+				if stored.OrderStatus != tt.status {
+					t.Fatalf("status = %s, want %s", stored.OrderStatus, tt.status)
+				}
+				tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id)
+				if len(tracks) != 1 || tracks[0].OrderStatus != tt.status {
+					t.Fatalf("tracks = %+v", tracks)
+				}
+				return
+			}
+
+			if _, err := f.repo.GetOrderById(ctx, placed.Id); !errors.Is(err, repositories.ErrOrderNotFound) {
+				t.Fatalf("completed order should leave the live collection, got %v", err)
+			}
+			if tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id); len(tracks) != 0 {
+				t.Fatalf("live track should be cleared, got %+v", tracks)
+			}
+			history, err := f.repo.GetOrderHistory(ctx, placed.Id)
+			if err != nil {
+				t.Fatalf("GetOrderHistory: %v", err)
+			}
+			if len(history.OrderTrack) != 2 || history.OrderTrack[1].OrderStatus != helper.COMPLETED {
+				t.Fatalf("history track = %+v", history.OrderTrack)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatusPublishesEvent(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed := f.placePaidOrder(t, validOrderDTO())
+
+	sub := f.broker.Subscribe(nil)
+	defer sub.Close()
+
+	if err := f.service.UpdateOrderStatus(ctx, placed.Id.Hex(), helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-sub.C:
+		if event.Type != events.ORDER_STATUS_CHANGED || event.UserId != placed.UserId {
+			t.Fatalf("event = %+v", event)
+		}
+		tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id)
+		if len(tracks) != 1 || event.Track.Id != tracks[0].Id || event.Track.OrderStatus != helper.DISPATCHED {
+			t.Fatalf("event track = %+v, stored = %+v", event.Track, tracks)
+		}
+	default:
+		t.Fatal("no event published")
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	order := validOrderDTO()
+	placed, err := f.service.PlaceSingleOrder(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.AddToCart(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := f.service.ListOrders(ctx, order.UserId, "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("ListOrders = %+v, %v", all, err)
+	}
+
+	placedOnly, err := f.service.ListOrders(ctx, order.UserId, helper.PENDING_PAYMENT)
+	if err != nil || len(placedOnly) != 1 || placedOnly[0].Id != placed.Id {
+		t.Fatalf("ListOrders(PLACED) = %+v, %v", placedOnly, err)
+	}
+
+	if _, err := f.service.ListOrders(ctx, "bad-id", ""); err == nil {
+		t.Fatal("expected an invalid id error")
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		missing     bool
+		cancelFirst bool
+		wantErr     error
+	}{
+		{name: "cancels and restocks"},
+		{name: "unknown order", missing: true, wantErr: repositories.ErrOrderNotFound},
+		{name: "already cancelled", cancelFirst: true, wantErr: ErrOrderClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+			ctx := context.Background()
+
+			req := validOrderDTO()
+			placed, err := f.service.PlaceSingleOrder(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			orderId := placed.Id.Hex()
+			if tt.missing {
+				orderId = primitive.NewObjectID().Hex()
+			}
+			if tt.cancelFirst {
+				if err := f.service.CancelOrder(ctx, orderId); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = f.service.CancelOrder(ctx, orderId)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CancelOrder: %v", err)
+			}
+
+			stored, _ := f.repo.GetOrderById(ctx, placed.Id)
+			if stored.OrderStatus != helper.CANCELLED {
+				t.Fatalf("status = %s, want CANCELLED", stored.OrderStatus)
+			}
+
+			calls := f.inventory.Calls()
+			if len(calls) != 2 {
+				t.Fatalf("inventory calls = %v", calls)
+			}
+			restock := calls[1]
+			if restock.Get("tag") != "increase" || restock.Get("number") != "3" || restock.Get("product_id") != req.ProductId {
+				t.Fatalf("restock call = %v", restock)
+			}
+		})
+	}
+}
+
+func TestPartialFulfilment(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	req := validOrderDTO()
+	placed, _ := f.payOrder(t, req)
+	orderId := placed.Id.Hex()
+
+	shipped, err := f.service.ShipOrderUnits(ctx, orderId, 1)
+	if err != nil {
+		t.Fatalf("ShipOrderUnits: %v", err)
+	}
+	if shipped.OrderStatus != helper.DISPATCHED || shipped.ShippedQuantity != 1 || shipped.BackorderedQuantity != 2 {
+		t.Fatalf("shipped = %+v", shipped)
+	}
+	if err := f.service.UpdateOrderStatus(ctx, orderId, helper.COMPLETED); !errors.Is(err, ErrBackordered) {
+		t.Fatalf("completing with backordered units err = %v", err)
+	}
+	// the whole order can not be cancelled once some units shipped, nothing is restocked or refunded
+	if err := f.service.CancelOrder(ctx, orderId); !errors.Is(err, ErrUnitsShipped) {
+		t.Fatalf("cancelling a partly shipped order err = %v", err)
+	}
+	for _, call := range f.inventory.Calls() {
+		if call.Get("tag") == "increase" {
+			t.Fatalf("inventory calls = %v, want no restock", f.inventory.Calls())
+		}
+	}
+	if refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id); len(refunds) != 0 {
+		t.Fatalf("refunds = %+v, want none", refunds)
+	}
+	if stored, _ := f.repo.GetOrderById(ctx, placed.Id); stored.OrderStatus != helper.DISPATCHED || stored.Quantity != 3 {
+		t.Fatalf("stored = %+v", stored)
+	}
+
+	if _, err := f.service.CancelOrderUnits(ctx, orderId, 3); !errors.Is(err, ErrUnitsUnavailable) {
+		t.Fatalf("cancelling shipped units err = %v", err)
+	}
+
+	cancelled, err := f.service.CancelOrderUnits(ctx, orderId, 1)
+	if err != nil {
+		t.Fatalf("CancelOrderUnits: %v", err)
+	}
+	if cancelled.OrderStatus != helper.DISPATCHED || cancelled.Quantity != 2 || cancelled.TotalPrice != 300 ||
+		cancelled.CancelledQuantity != 1 || cancelled.BackorderedQuantity != 1 {
+		t.Fatalf("cancelled = %+v", cancelled)
+	}
+
+	calls := f.inventory.Calls()
+	if restock := calls[len(calls)-1]; restock.Get("tag") != "increase" || restock.Get("number") != "1" || restock.Get("product_id") != req.ProductId {
+		t.Fatalf("restock call = %v", restock)
+	}
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 1 || refunds[0].Amount != 150 || refunds[0].Kind != helper.REFUND_PARTIAL || refunds[0].Reason != helper.REFUND_REASON_CANCELLED {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+
+	// dispatching the order ships the backordered unit
+	if err := f.service.UpdateOrderStatus(ctx, orderId, helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := f.repo.GetOrderById(ctx, placed.Id)
+	if stored.ShippedQuantity != 2 || stored.BackorderedQuantity != 0 {
+		t.Fatalf("stored = %+v", stored)
+	}
+
+	tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id)
+	want := []models.OrderTrack{
+		{OrderStatus: helper.PLACED},
+		{OrderStatus: helper.DISPATCHED, UnitStatus: helper.DISPATCHED, Units: 1},
+		{OrderStatus: helper.DISPATCHED, UnitStatus: helper.CANCELLED, Units: 1},
+		{OrderStatus: helper.DISPATCHED, UnitStatus: helper.DISPATCHED, Units: 1},
+	}
+	if len(tracks) != len(want) {
+		t.Fatalf("tracks = %+v", tracks)
+	}
+	for i, track := range tracks {
+		if track.OrderStatus != want[i].OrderStatus || track.UnitStatus != want[i].UnitStatus || track.Units != want[i].Units {
+			t.Fatalf("track %d = %+v, want %+v", i, track, want[i])
+		}
+	}
+
+	if err := f.service.UpdateOrderStatus(ctx, orderId, helper.COMPLETED); err != nil {
+		t.Fatalf("completing a fully shipped order: %v", err)
+	}
+	// the refund of the cancelled unit settles after the order was archived
+	if processed, err := f.paymentService.ProcessDueRefunds(ctx); err != nil || processed != 1 {
+		t.Fatalf("ProcessDueRefunds = %d, %v", processed, err)
+	}
+}
+
+func TestCancelDispatchedOrder(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed := f.placePaidOrder(t, validOrderDTO())
+	if err := f.service.UpdateOrderStatus(ctx, placed.Id.Hex(), helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); !errors.Is(err, ErrOrderDispatched) {
+		t.Fatalf("err = %v, want %v", err, ErrOrderDispatched)
+	}
+	stored, _ := f.repo.GetOrderById(ctx, placed.Id)
+	if stored.OrderStatus != helper.DISPATCHED {
+		t.Fatalf("status = %s, want DISPATCHED", stored.OrderStatus)
+	}
+}
+
+func TestCancelOrderUnits(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	// awaiting payment, the intent is created for the reduced total
+	unpaid, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reduced, err := f.service.CancelOrderUnits(ctx, unpaid.Id.Hex(), 2)
+	if err != nil || reduced.OrderStatus != helper.PENDING_PAYMENT || reduced.Quantity != 1 || reduced.TotalPrice != 150 {
+		t.Fatalf("CancelOrderUnits = %+v, %v", reduced, err)
+	}
+	payment, err := f.paymentService.Checkout(ctx, unpaid.Id.Hex())
+	if err != nil || payment.Amount != 150 {
+		t.Fatalf("Checkout = %+v, %v", payment, err)
+	}
+
+	more, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.paymentService.Checkout(ctx, more.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.CancelOrderUnits(ctx, more.Id.Hex(), 1); !errors.Is(err, ErrPaymentInProgress) {
+		t.Fatalf("cancel during payment err = %v", err)
+	}
+
+	// every unit cancels the whole order and refunds what was paid
+	placed, _ := f.payOrder(t, validOrderDTO())
+	whole, err := f.service.CancelOrderUnits(ctx, placed.Id.Hex(), 3)
+	if err != nil || whole.OrderStatus != helper.REFUND_PENDING || whole.Quantity != 3 {
+		t.Fatalf("cancel every unit = %+v, %v", whole, err)
+	}
+	if _, err := f.service.CancelOrderUnits(ctx, placed.Id.Hex(), 1); !errors.Is(err, ErrOrderClosed) {
+		t.Fatalf("cancel a closed order err = %v", err)
+	}
+
+	if _, err := f.service.ShipOrderUnits(ctx, more.Id.Hex(), 1); !errors.Is(err, ErrPaymentPending) {
+		t.Fatalf("ship unpaid order err = %v", err)
+	}
+	if _, err := f.service.CancelOrderUnits(ctx, primitive.NewObjectID().Hex(), 1); !errors.Is(err, repositories.ErrOrderNotFound) {
+		t.Fatalf("unknown order err = %v", err)
+	}
+}
+
+func TestEditOrder(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	req := validOrderDTO()
+	placed, _ := f.payOrder(t, req)
+	orderId := placed.Id.Hex()
+
+	fewer, err := f.service.EditOrder(ctx, orderId, dto.EditOrderDTO{Quantity: 2})
+	if err != nil || fewer.Quantity != 2 || fewer.TotalPrice != 300 || fewer.OrderStatus != helper.PLACED {
+		t.Fatalf("EditOrder = %+v, %v", fewer, err)
+	}
+	calls := f.inventory.Calls()
+	if restock := calls[len(calls)-1]; restock.Get("tag") != "increase" || restock.Get("number") != "1" || restock.Get("product_id") != req.ProductId {
+		t.Fatalf("restock call = %v", restock)
+	}
+
+	variant := primitive.NewObjectID()
+	f.inventory.SetProduct(clients.ProductInfo{ProductId: req.ProductId, ProductSellingID: variant.Hex(), Price: 100, Available: 10})
+	swapped, err := f.service.EditOrder(ctx, orderId, dto.EditOrderDTO{ProductSellingID: variant.Hex()})
+	if err != nil || swapped.ProductSellingID != variant || swapped.Quantity != 2 || swapped.TotalPrice != 200 {
+		t.Fatalf("swap variant = %+v, %v", swapped, err)
+	}
+	if len(f.inventory.Calls()) != len(calls) {
+		t.Fatalf("swapping the variant must not move stock, calls = %v", f.inventory.Calls())
+	}
+
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 2 || refunds[0].Amount != 150 || refunds[1].Amount != 100 || refunds[1].Reason != helper.REFUND_REASON_EDITED {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+
+	tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id)
+	last := tracks[len(tracks)-1]
+	if last.OrderStatus != helper.PLACED || last.Edit == nil || last.Edit.FromProductSellingID != placed.ProductSellingID ||
+		last.Edit.ToProductSellingID != variant || last.Edit.FromTotalPrice != 300 || last.Edit.ToTotalPrice != 200 {
+		t.Fatalf("edit track = %+v", last)
+	}
+
+	unpaid, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	more, err := f.service.EditOrder(ctx, unpaid.Id.Hex(), dto.EditOrderDTO{Quantity: 5})
+	if err != nil || more.TotalPrice != 750 {
+		t.Fatalf("grow unpaid order = %+v, %v", more, err)
+	}
+	if reserve := f.inventory.Calls()[len(f.inventory.Calls())-1]; reserve.Get("tag") != "decrease" || reserve.Get("number") != "2" {
+		t.Fatalf("reserve call = %v", reserve)
+	}
+
+	otherProduct := primitive.NewObjectID()
+	f.inventory.SetProduct(clients.ProductInfo{ProductId: primitive.NewObjectID().Hex(), ProductSellingID: otherProduct.Hex(), Price: 10, Available: 10})
+
+	tests := []struct {
+		name    string
+		orderId string
+		edit    dto.EditOrderDTO
+		wantErr error
+	}{
+		{name: "paid order costing more", orderId: orderId, edit: dto.EditOrderDTO{Quantity: 3}, wantErr: ErrEditExceedsPayment},
+		{name: "over the quantity limit", orderId: unpaid.Id.Hex(), edit: dto.EditOrderDTO{Quantity: 11}, wantErr: ErrLimitExceeded},
+		{name: "unknown order", orderId: primitive.NewObjectID().Hex(), edit: dto.EditOrderDTO{Quantity: 1}, wantErr: repositories.ErrOrderNotFound},
+		{name: "no change", orderId: orderId, edit: dto.EditOrderDTO{Quantity: 2}},
+		{name: "variant of another product", orderId: orderId, edit: dto.EditOrderDTO{ProductSellingID: otherProduct.Hex()}, wantErr: ErrOtherProduct},
+		{name: "variant no longer sold", orderId: orderId, edit: dto.EditOrderDTO{ProductSellingID: primitive.NewObjectID().Hex()}, wantErr: ErrProductDiscontinued},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.service.EditOrder(ctx, tt.orderId, tt.edit)
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// stock that could not be reserved leaves the order as it was
+	f.inventory.mu.Lock()
+	f.inventory.failWith = http.StatusInternalServerError
+	f.inventory.mu.Unlock()
+	if _, err := f.service.EditOrder(ctx, unpaid.Id.Hex(), dto.EditOrderDTO{Quantity: 6}); err == nil {
+		t.Fatal("expected the failed reservation to fail the edit")
+	}
+	if stored, _ := f.repo.GetOrderById(ctx, unpaid.Id); stored.Quantity != 5 {
+		t.Fatalf("stored = %+v", stored)
+	}
+
+	if err := f.service.UpdateOrderStatus(ctx, orderId, helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.EditOrder(ctx, orderId, dto.EditOrderDTO{Quantity: 1}); !errors.Is(err, ErrOrderDispatched) {
+		t.Fatalf("edit after dispatch err = %v", err)
+	}
+}
+
+func TestEditOrderProductWindow(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = f.inventory.server.URL + "/api/"
+	service := NewOrderService(f.repo, f.paymentRepo, clients.NewInventoryClient(cfg, logging.Discard()),
+		NewLimitsPolicy(f.repo, OrderLimits{MaxQuantity: 10, MaxProductUnits: 8, ProductWindow: 24 * time.Hour}), f.broker, logging.Discard())
+
+	// 3 units already bought today, the order being edited holds 3 more
+	req := validOrderDTO()
+	if _, err := service.PlaceSingleOrder(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	unpaid, err := service.PlaceSingleOrder(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.EditOrder(ctx, unpaid.Id.Hex(), dto.EditOrderDTO{Quantity: 6}); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("edit past the product window err = %v, want ErrLimitExceeded", err)
+	}
+	if stored, _ := f.repo.GetOrderById(ctx, unpaid.Id); stored.Quantity != 3 {
+		t.Fatalf("stored = %+v", stored)
+	}
+
+	if edited, err := service.EditOrder(ctx, unpaid.Id.Hex(), dto.EditOrderDTO{Quantity: 5}); err != nil || edited.Quantity != 5 {
+		t.Fatalf("edit within the product window = %+v, %v", edited, err)
+	}
+}
+
+func TestReorder(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	req := validOrderDTO()
+	archived := f.completeOrder(t, req)
+	f.inventory.SetProduct(clients.ProductInfo{ProductId: req.ProductId, ProductSellingID: req.ProductSellingID, Price: 175, Available: 100})
+
+	again, err := f.service.Reorder(ctx, archived.Id.Hex(), helper.REORDER_AS_ORDER)
+	if err != nil || len(again.Orders) != 1 || len(again.Unavailable) != 0 || again.SourceOrderId != archived.Id || again.StockNotUpdated {
+		t.Fatalf("Reorder = %+v, %v", again, err)
+	}
+	if placed := again.Orders[0]; placed.Id == archived.Id || placed.OrderStatus != helper.PENDING_PAYMENT ||
+		placed.UserId != archived.UserId || placed.Quantity != 3 || placed.TotalPrice != 525 {
+		t.Fatalf("reordered = %+v", placed)
+	}
+
+	// only two units left in stock
+	live := f.placePaidOrder(t, validOrderDTO())
+	f.inventory.SetProduct(clients.ProductInfo{ProductSellingID: live.ProductSellingID.Hex(), Price: 150, Available: 2})
+	carted, err := f.service.Reorder(ctx, live.Id.Hex(), helper.REORDER_TO_CART)
+	if err != nil || len(carted.Orders) != 1 || carted.Orders[0].OrderStatus != helper.CART || carted.Orders[0].Quantity != 2 {
+		t.Fatalf("Reorder to cart = %+v, %v", carted, err)
+	}
+	if len(carted.Unavailable) != 1 || carted.Unavailable[0].Quantity != 1 || carted.Unavailable[0].Reason != helper.UNAVAILABLE_OUT_OF_STOCK {
+		t.Fatalf("unavailable = %+v", carted.Unavailable)
+	}
+	if items, _ := f.service.GetCartItems(ctx, live.UserId.Hex()); len(items) != 1 {
+		t.Fatalf("cart = %+v", items)
+	}
+
+	discontinued := f.placePaidOrder(t, validOrderDTO())
+	gone, err := f.service.Reorder(ctx, discontinued.Id.Hex(), helper.REORDER_AS_ORDER)
+	if err != nil || len(gone.Orders) != 0 || len(gone.Unavailable) != 1 || gone.Unavailable[0].Reason != helper.UNAVAILABLE_DISCONTINUED ||
+		gone.Unavailable[0].Quantity != 3 {
+		t.Fatalf("discontinued = %+v, %v", gone, err)
+	}
+
+	if _, err := f.service.Reorder(ctx, primitive.NewObjectID().Hex(), helper.REORDER_AS_ORDER); !errors.Is(err, repositories.ErrOrderNotFound) {
+		t.Fatalf("unknown order err = %v", err)
+	}
+
+	// stock the product service did not take is reported with the placed order
+	f.inventory.mu.Lock()
+	f.inventory.failWith = http.StatusInternalServerError
+	f.inventory.mu.Unlock()
+	unreserved, err := f.service.Reorder(ctx, archived.Id.Hex(), helper.REORDER_AS_ORDER)
+	if err != nil || len(unreserved.Orders) != 1 || !unreserved.StockNotUpdated {
+		t.Fatalf("Reorder with a failed stock update = %+v, %v", unreserved, err)
+	}
+	if stored, err := f.repo.GetOrderById(ctx, unreserved.Orders[0].Id); err != nil || stored.OrderStatus != helper.PENDING_PAYMENT {
+		t.Fatalf("stored = %+v, %v", stored, err)
+	}
+}
+
+func TestGetOrderTrackSince(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	order := validOrderDTO()
+	placed := f.placePaidOrder(t, order)
+	for _, status := range []string{helper.DISPATCHED, helper.COMPLETED} {
+		if err := f.service.UpdateOrderStatus(ctx, placed.Id.Hex(), status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// completed orders are archived, the track comes from history
+	all, err := f.service.GetOrderTrackSince(ctx, placed.Id.Hex(), "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("GetOrderTrackSince = %+v, %v", all, err)
+	}
+
+	newer, err := f.service.GetOrderTrackSince(ctx, placed.Id.Hex(), all[0].Id.Hex())
+	if err != nil || len(newer) != 1 || newer[0].OrderStatus != helper.COMPLETED {
+		t.Fatalf("GetOrderTrackSince(first) = %+v, %v", newer, err)
+	}
+
+	if _, err := f.service.GetOrderTrackSince(ctx, primitive.NewObjectID().Hex(), ""); !errors.Is(err, repositories.ErrOrderNotFound) {
+		t.Fatalf("unknown order err = %v", err)
+	}
+
+	live := f.placePaidOrder(t, order)
+	if err := f.service.UpdateOrderStatus(ctx, live.Id.Hex(), helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+
+	userTrack, err := f.service.GetUserOrderTrackSince(ctx, order.UserId, all[1].Id.Hex())
+	if err != nil || len(userTrack) != 1 || userTrack[0].OrderId != live.Id {
+		t.Fatalf("GetUserOrderTrackSince = %+v, %v", userTrack, err)
+	}
+}
+
+func TestCancelStaleOrders(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	// store an order that has been sitting in status for age
+	sitting := func(status string, age time.Duration) models.Orders {
+		t.Helper()
+		order := new(models.Orders).SetPlaceOrder(validOrderDTO(), status)
+		order.UpdatedAt = primitive.NewDateTimeFromTime(time.Now().Add(-age))
+		if _, err := f.repo.PlaceSingleOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+
+	unpaid := sitting(helper.PENDING_PAYMENT, time.Hour)
+	undispatched := sitting(helper.PLACED, 4*time.Hour)
+	recent := sitting(helper.PLACED, time.Minute)
+	dispatched := sitting(helper.DISPATCHED, 48*time.Hour)
+
+	slas := map[string]time.Duration{
+		helper.PENDING_PAYMENT: 30 * time.Minute,
+		helper.PLACED:          3 * time.Hour,
+	}
+
+	cancelled, err := f.service.CancelStaleOrders(ctx, slas)
+	if err != nil || cancelled != 2 {
+		t.Fatalf("CancelStaleOrders = %d, %v", cancelled, err)
+	}
+
+	for _, tt := range []struct {
+		order      models.Orders
+		wantStatus string
+	}{
+		{order: unpaid, wantStatus: helper.CANCELLED},
+		{order: undispatched, wantStatus: helper.CANCELLED},
+		{order: recent, wantStatus: helper.PLACED},
+		{order: dispatched, wantStatus: helper.DISPATCHED},
+	} {
+		stored, _ := f.repo.GetOrderById(ctx, tt.order.Id)
+		if stored.OrderStatus != tt.wantStatus {
+			t.Fatalf("order in %s = %s, want %s", tt.order.OrderStatus, stored.OrderStatus, tt.wantStatus)
+		}
+	}
+
+	tracks, _ := f.repo.GetAllOrderTrack(ctx, undispatched.Id)
+	if len(tracks) != 1 || tracks[0].Actor != helper.ACTOR_AUTO_CANCEL {
+		t.Fatalf("track = %+v, want the auto-cancel actor", tracks)
+	}
+	if calls := f.inventory.Calls(); len(calls) != 2 || calls[0].Get("tag") != "increase" {
+		t.Fatalf("auto-cancelled orders must be restocked, calls = %v", calls)
+	}
+
+	// nothing left to do on the next run
+	if cancelled, err := f.service.CancelStaleOrders(ctx, slas); err != nil || cancelled != 0 {
+		t.Fatalf("second run = %d, %v", cancelled, err)
+	}
+
+	if err := f.service.UpdateOrderStatus(ctx, recent.Id.Hex(), helper.DISPATCHED); err != nil {
+		t.Fatal(err)
+	}
+	if tracks, _ := f.repo.GetAllOrderTrack(ctx, recent.Id); tracks[0].Actor != "" {
+		t.Fatalf("api changes carry no actor, got %q", tracks[0].Actor)
+	}
+}
+
+func TestSweepCarts(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	// cart line of a new CART order for the user, last touched age ago
+	cartLine := func(userId string, price int64, age time.Duration) models.Orders {
+		t.Helper()
+		order := validOrderDTO()
+		order.UserId = userId
+		order.Price = price
+		added, err := f.service.AddToCart(ctx, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at := primitive.NewDateTimeFromTime(time.Now().Add(-age))
+		lines, _ := f.repo.UserCartItem(ctx, added.UserId)
+		for _, line := range lines {
+			if line.OrderId == added.Id {
+				_ = f.repo.DeleteCartOrder(ctx, added.Id)
+				line.Id, line.UpdatedAt = primitive.NewObjectID(), at
+				if err := f.repo.AddToCartOrder(ctx, line); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return added
+	}
+
+	idleUser, cheapUser, activeUser := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	expired := cartLine(idleUser, 100, 40*24*time.Hour)
+	kept := cartLine(idleUser, 100, 2*24*time.Hour)
+	cartLine(cheapUser, 1, 2*24*time.Hour)
+	cartLine(activeUser, 100, time.Hour)
+
+	sub := f.broker.Subscribe(nil)
+	defer sub.Close()
+
+	policy := CartPolicy{TTL: 30 * 24 * time.Hour, AbandonedAfter: 24 * time.Hour, AbandonedMinValue: 50}
+	sweep, err := f.service.SweepCarts(ctx, policy)
+	if err != nil || sweep != (CartSweep{Abandoned: 1, Expired: 1}) {
+		t.Fatalf("SweepCarts = %+v, %v", sweep, err)
+	}
+
+	select {
+	case event := <-sub.C:
+		if event.Type != events.CART_ABANDONED || event.UserId != expired.UserId || event.Cart.Lines != 2 || event.Cart.Value != 600 {
+			t.Fatalf("event = %+v", event)
+		}
+	default:
+		t.Fatal("no abandonment published")
+	}
+
+	if _, err := f.repo.GetOrderById(ctx, expired.Id); !errors.Is(err, repositories.ErrOrderNotFound) {
+		t.Fatalf("expired cart order still stored, err = %v", err)
+	}
+	if items, _ := f.service.GetCartItems(ctx, idleUser); len(items) != 1 || items[0].Id != kept.Id {
+		t.Fatalf("cart after sweep = %+v", items)
+	}
+
+	// the abandonment is reported once, the marketing query still lists it
+	if sweep, err := f.service.SweepCarts(ctx, policy); err != nil || sweep != (CartSweep{}) {
+		t.Fatalf("second SweepCarts = %+v, %v", sweep, err)
+	}
+	carts, err := f.service.GetAbandonedCarts(ctx, 24*time.Hour, 0, 10)
+	if err != nil || len(carts) != 2 || !carts[0].Notified || carts[1].Notified {
+		t.Fatalf("GetAbandonedCarts = %+v, %v", carts, err)
+	}
+}
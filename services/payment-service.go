@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/payments"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PaymentService takes the payment of PENDING_PAYMENT orders, a successful
+// callback moves the order on to PLACED. It also sends the refunds requested
+// on cancellation to the gateway, the order reaches REFUNDED once they settle
+type PaymentService interface {
+	Checkout(ctx context.Context, orderId string) (models.PaymentAttempt, error)
+	GetOrderPayments(ctx context.Context, orderId string) ([]models.PaymentAttempt, error)
+	HandleCallback(ctx context.Context, payload []byte, signature string) (models.PaymentAttempt, error)
+
+	GetOrderRefunds(ctx context.Context, orderId string) ([]models.Refund, error)
+	ProcessDueRefunds(ctx context.Context) (int, error)
+	// RetryRefund queue a refund the processor gave up on for the gateway again
+	RetryRefund(ctx context.Context, refundId, operator string) (models.Refund, error)
+}
+
+// ErrNotAwaitingPayment order is not in PENDING_PAYMENT
+var ErrNotAwaitingPayment = errors.New("order is not awaiting payment")
+
+// ErrRefundNotFailed refund is still being processed or has settled
+var ErrRefundNotFailed = errors.New("only failed refunds can be retried")
+
+// REFUND_BATCH refunds sent to the gateway per ProcessDueRefunds call
+const REFUND_BATCH = 50
+
+// RefundPolicy how often a refund is retried against the gateway, the wait
+// starts at Backoff and doubles after every failed attempt
+type RefundPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+type paymentService struct {
+	orderRepo    repositories.OrderRepository
+	paymentRepo  repositories.PaymentRepository
+	gateway      payments.PaymentGateway
+	orderService OrderService
+	currency     string
+	refunds      RefundPolicy
+	logger       *slog.Logger
+}
+
+func NewPaymentService(orderRepo repositories.OrderRepository, paymentRepo repositories.PaymentRepository, gateway payments.PaymentGateway, orderService OrderService, currency string, refunds RefundPolicy, logger *slog.Logger) PaymentService {
+	return &paymentService{
+		orderRepo:    orderRepo,
+		paymentRepo:  paymentRepo,
+		gateway:      gateway,
+		orderService: orderService,
+		currency:     currency,
+		refunds:      refunds,
+		logger:       logger,
+	}
+}
+
+func (ser *paymentService) paymentLogger(payment models.PaymentAttempt) *slog.Logger {
+	return ser.logger.With("order_id", payment.OrderId.Hex(), "user_id", payment.UserId.Hex(), "payment_id", payment.Id.Hex())
+}
+
+// create a payment intent for the order, an attempt still pending is handed
+// out again instead of opening a second one
+func (ser *paymentService) Checkout(ctx context.Context, orderId string) (_ models.PaymentAttempt, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.Checkout", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return models.PaymentAttempt{}, valErr
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	if order.OrderStatus != helper.PENDING_PAYMENT {
+		return models.PaymentAttempt{}, ErrNotAwaitingPayment
+	}
+
+	attempts, err := ser.paymentRepo.GetOrderPayments(ctx, orderObjId)
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	for _, attempt := range attempts {
+		if attempt.Status == helper.PAYMENT_PENDING {
+			return ser.withIntent(ctx, attempt)
+		}
+	}
+
+	payment := new(models.PaymentAttempt).SetPaymentAttempt(order, ser.gateway.Name(), "", ser.currency, helper.PAYMENT_PENDING)
+	if payment, err = ser.withIntent(ctx, payment); err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	if err = ser.paymentRepo.CreatePaymentAttempt(ctx, payment); err != nil {
+		return models.PaymentAttempt{}, err
+	}
+	span.SetAttributes(attribute.String("payment.id", payment.Id.Hex()))
+	metrics.PaymentAttempts.WithLabelValues(payment.Gateway, payment.Status).Inc()
+
+	ser.paymentLogger(payment).InfoContext(ctx, "payment intent created", "intent_id", payment.IntentId, "amount", payment.Amount, "attempt", len(attempts)+1)
+	return payment, nil
+}
+
+// ask the gateway for the intent, keyed by the attempt so retries get the same one back
+func (ser *paymentService) withIntent(ctx context.Context, payment models.PaymentAttempt) (models.PaymentAttempt, error) {
+	intent, err := ser.gateway.CreateIntent(ctx, payments.IntentRequest{
+		OrderId:        payment.OrderId.Hex(),
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		IdempotencyKey: payment.Id.Hex(),
+	})
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	payment.IntentId = intent.Id
+	payment.ClientSecret = intent.ClientSecret
+	return payment, nil
+}
+
+func (ser *paymentService) GetOrderPayments(ctx context.Context, orderId string) (_ []models.PaymentAttempt, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.GetOrderPayments", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	if _, err = ser.orderService.GetOrder(ctx, orderId); err != nil {
+		return nil, err
+	}
+
+	return ser.paymentRepo.GetOrderPayments(ctx, orderObjId)
+}
+
+// settle the attempt the gateway reports on, callbacks for settled attempts are acknowledged
+// unchanged apart from placing a paid order the first delivery left pending
+func (ser *paymentService) HandleCallback(ctx context.Context, payload []byte, signature string) (_ models.PaymentAttempt, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.HandleCallback")
+	defer tracing.End(span, &err)
+
+	event, err := ser.gateway.VerifyCallback(payload, signature)
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+	span.SetAttributes(attribute.String("payment.intent_id", event.IntentId))
+
+	payment, err := ser.paymentRepo.GetPaymentByIntent(ctx, ser.gateway.Name(), event.IntentId)
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	if payment.Status == helper.PAYMENT_SUCCEEDED {
+		return payment, ser.placePaidOrder(ctx, payment)
+	}
+	if payment.Status != helper.PAYMENT_PENDING {
+		return payment, nil
+	}
+
+	status := helper.PAYMENT_FAILED
+	if event.Status == payments.CALLBACK_SUCCEEDED {
+		status = helper.PAYMENT_SUCCEEDED
+	}
+
+	err = ser.paymentRepo.SettlePayment(ctx, payment.Id, status, event.FailureReason)
+	if errors.Is(err, repositories.ErrPaymentNotFound) {
+		// a concurrent callback settled it first
+		return ser.paymentRepo.GetPaymentByIntent(ctx, ser.gateway.Name(), event.IntentId)
+	}
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	payment.Status = status
+	payment.FailureReason = event.FailureReason
+	metrics.PaymentAttempts.WithLabelValues(payment.Gateway, payment.Status).Inc()
+
+	logger := ser.paymentLogger(payment)
+	if status == helper.PAYMENT_FAILED {
+		logger.WarnContext(ctx, "payment failed, order stays pending payment", "reason", event.FailureReason)
+		return payment, nil
+	}
+	logger.InfoContext(ctx, "payment succeeded", "amount", payment.Amount)
+
+	order, err := ser.orderRepo.GetOrderById(ctx, payment.OrderId)
+	if err != nil {
+		return payment, err
+	}
+
+	if helper.IsClosedStatus(order.OrderStatus) {
+		// cancelled while the customer was paying, give the money back
+		refund, refundErr := requestRefund(ctx, ser.paymentRepo, payment, payment.Amount, helper.REFUND_REASON_LATE_PAYMENT, "")
+		if refundErr != nil {
+			return payment, refundErr
+		}
+		logger.WarnContext(ctx, "payment succeeded for a cancelled order, refund requested", "order_status", order.OrderStatus, "refund_id", refund.Id.Hex())
+		return payment, nil
+	}
+
+	if order.OrderStatus != helper.PENDING_PAYMENT {
+		logger.WarnContext(ctx, "payment succeeded for an order no longer awaiting payment", "order_status", order.OrderStatus)
+		return payment, nil
+	}
+
+	return payment, ser.orderService.UpdateOrderStatus(ctx, payment.OrderId.Hex(), helper.PLACED)
+}
+
+// redelivered callback of a succeeded attempt, finish placing the order when
+// the first delivery settled the attempt but failed to move the order on
+func (ser *paymentService) placePaidOrder(ctx context.Context, payment models.PaymentAttempt) error {
+	order, err := ser.orderRepo.GetOrderById(ctx, payment.OrderId)
+	if err != nil || order.OrderStatus != helper.PENDING_PAYMENT {
+		return err
+	}
+
+	ser.paymentLogger(payment).WarnContext(ctx, "paid order still pending payment, placing it on redelivery")
+	return ser.orderService.UpdateOrderStatus(ctx, payment.OrderId.Hex(), helper.PLACED)
+}
+
+// latest succeeded payment of the order and how much of it has not been
+// refunded or promised back yet, zero when the order was never paid
+func refundablePayment(ctx context.Context, paymentRepo repositories.PaymentRepository, orderId primitive.ObjectID) (models.PaymentAttempt, float64, error) {
+	attempts, err := paymentRepo.GetOrderPayments(ctx, orderId)
+	if err != nil {
+		return models.PaymentAttempt{}, 0, err
+	}
+
+	var payment models.PaymentAttempt
+	for _, attempt := range attempts {
+		if attempt.Status == helper.PAYMENT_SUCCEEDED {
+			payment = attempt
+		}
+	}
+	if payment.Id.IsZero() {
+		return models.PaymentAttempt{}, 0, nil
+	}
+
+	refunds, err := paymentRepo.GetOrderRefunds(ctx, orderId)
+	if err != nil {
+		return models.PaymentAttempt{}, 0, err
+	}
+
+	refundable := payment.Amount
+	for _, refund := range refunds {
+		if refund.PaymentId == payment.Id && refund.Status != helper.PAYMENT_FAILED {
+			refundable -= refund.Amount
+		}
+	}
+	return payment, refundable, nil
+}
+
+// record a pending refund of amount on the payment, it is sent to the gateway by
+// ProcessDueRefunds. A non empty key is taken once, see ErrRefundExists
+func requestRefund(ctx context.Context, paymentRepo repositories.PaymentRepository, payment models.PaymentAttempt, amount float64, reason, key string) (models.Refund, error) {
+	kind := helper.REFUND_FULL
+	if amount < payment.Amount {
+		kind = helper.REFUND_PARTIAL
+	}
+
+	refund := new(models.Refund).SetRefund(payment, kind, amount, reason, helper.PAYMENT_PENDING)
+	refund.Key = key
+	if err := paymentRepo.CreateRefund(ctx, refund); err != nil {
+		return models.Refund{}, err
+	}
+	return refund, nil
+}
+
+func refundAttrs(refundId string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("refund.id", refundId))
+}
+
+func (ser *paymentService) refundLogger(refund models.Refund) *slog.Logger {
+	return ser.logger.With("order_id", refund.OrderId.Hex(), "user_id", refund.UserId.Hex(), "refund_id", refund.Id.Hex())
+}
+
+func (ser *paymentService) GetOrderRefunds(ctx context.Context, orderId string) (_ []models.Refund, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.GetOrderRefunds", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	orderObjId, valErr := helper.ValidatePrimitiveId(orderId)
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	if _, err = ser.orderService.GetOrder(ctx, orderId); err != nil {
+		return nil, err
+	}
+
+	return ser.paymentRepo.GetOrderRefunds(ctx, orderObjId)
+}
+
+// send every refund due for an attempt to the gateway, returns how many were tried.
+// A failing refund does not hold up the others
+func (ser *paymentService) ProcessDueRefunds(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.ProcessDueRefunds")
+	defer tracing.End(span, &err)
+
+	due, err := ser.paymentRepo.GetDueRefunds(ctx, time.Now(), REFUND_BATCH)
+	if err != nil {
+		return 0, err
+	}
+	span.SetAttributes(attribute.Int("refund.due", len(due)))
+
+	var errs []error
+	for _, refund := range due {
+		if refundErr := ser.processRefund(ctx, refund); refundErr != nil {
+			ser.refundLogger(refund).ErrorContext(ctx, "failed to process refund", "error", refundErr)
+			errs = append(errs, refundErr)
+		}
+	}
+	return len(due), errors.Join(errs...)
+}
+
+func (ser *paymentService) processRefund(ctx context.Context, refund models.Refund) error {
+	result, gatewayErr := ser.gateway.Refund(ctx, payments.RefundRequest{
+		IntentId:       refund.IntentId,
+		Amount:         refund.Amount,
+		Currency:       refund.Currency,
+		Reason:         refund.Reason,
+		IdempotencyKey: refund.Id.Hex(),
+	})
+
+	refund.Attempts++
+	logger := ser.refundLogger(refund)
+	outcome := "retry"
+
+	switch {
+	case gatewayErr == nil:
+		refund.Status = helper.PAYMENT_SUCCEEDED
+		refund.GatewayRefundId = result.Id
+		refund.LastError = ""
+		outcome = "succeeded"
+	case refund.Attempts >= ser.refunds.MaxAttempts:
+		refund.Status = helper.PAYMENT_FAILED
+		refund.LastError = gatewayErr.Error()
+		outcome = "failed"
+	default:
+		refund.LastError = gatewayErr.Error()
+		wait := ser.refunds.Backoff << (refund.Attempts - 1)
+		refund.NextAttemptAt = primitive.NewDateTimeFromTime(time.Now().Add(wait))
+	}
+
+	if err := ser.paymentRepo.SaveRefundAttempt(ctx, refund); err != nil {
+		return err
+	}
+	metrics.RefundAttempts.WithLabelValues(refund.Gateway, outcome).Inc()
+
+	switch refund.Status {
+	case helper.PAYMENT_SUCCEEDED:
+		logger.InfoContext(ctx, "refund settled", "amount", refund.Amount, "attempts", refund.Attempts)
+		return ser.completeRefundedOrder(ctx, refund.OrderId)
+	case helper.PAYMENT_FAILED:
+		logger.ErrorContext(ctx, "refund failed for good, needs manual handling", "amount", refund.Amount, "attempts", refund.Attempts, "error", gatewayErr)
+		return ser.orderService.TrackRefund(helper.WithActor(ctx, helper.ACTOR_REFUND_PROCESSOR), refund)
+	default:
+		logger.WarnContext(ctx, "refund attempt failed, retrying later", "attempts", refund.Attempts, "next_attempt_at", refund.NextAttemptAt.Time(), "error", gatewayErr)
+	}
+	return nil
+}
+
+// the refund starts over with fresh attempts and is due straight away, the order
+// track records who queued it
+func (ser *paymentService) RetryRefund(ctx context.Context, refundId, operator string) (_ models.Refund, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.RetryRefund", refundAttrs(refundId))
+	defer tracing.End(span, &err)
+	ctx = helper.WithActor(ctx, operator)
+
+	refundObjId, err := helper.ValidatePrimitiveId(refundId)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	refund, err := ser.paymentRepo.GetRefundById(ctx, refundObjId)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	if refund.Status != helper.PAYMENT_FAILED {
+		return models.Refund{}, ErrRefundNotFailed
+	}
+
+	now := time.Now()
+	err = ser.paymentRepo.RequeueRefund(ctx, refund.Id, now)
+	if errors.Is(err, repositories.ErrRefundNotFound) {
+		// another operator queued it first
+		return models.Refund{}, ErrRefundNotFailed
+	}
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	refund.Status = helper.PAYMENT_PENDING
+	refund.Attempts = 0
+	refund.NextAttemptAt = primitive.NewDateTimeFromTime(now)
+	ser.refundLogger(refund).InfoContext(ctx, "failed refund queued again", "operator", operator, "last_error", refund.LastError)
+
+	if err := ser.orderService.TrackRefund(ctx, refund); err != nil {
+		ser.refundLogger(refund).ErrorContext(ctx, "failed to track the queued refund", "error", err)
+	}
+	return refund, nil
+}
+
+// move a REFUND_PENDING order to REFUNDED once none of its refunds is pending any more,
+// refunds of returned units belong to an archived order that keeps its status
+func (ser *paymentService) completeRefundedOrder(ctx context.Context, orderId primitive.ObjectID) error {
+	order, err := ser.orderRepo.GetOrderById(ctx, orderId)
+	if errors.Is(err, repositories.ErrOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if order.OrderStatus != helper.REFUND_PENDING {
+		return nil
+	}
+
+	refunds, err := ser.paymentRepo.GetOrderRefunds(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	for _, refund := range refunds {
+		if refund.Status == helper.PAYMENT_PENDING {
+			return nil
+		}
+	}
+
+	return ser.orderService.UpdateOrderStatus(ctx, orderId.Hex(), helper.REFUNDED)
+}
@@ -0,0 +1,550 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/payments"
+	"github.com/aniket0951/order-services/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// signed callback body for the intent, as the gateway would send it
+func (f *serviceFixture) callback(t *testing.T, intentId, status, reason string) ([]byte, string) {
+	t.Helper()
+	payload, err := json.Marshal(payments.CallbackEvent{IntentId: intentId, Status: status, FailureReason: reason})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload, f.gateway.Sign(payload)
+}
+
+func (f *serviceFixture) deliver(t *testing.T, intentId, status, reason string) (models.PaymentAttempt, error) {
+	t.Helper()
+	payload, signature := f.callback(t, intentId, status, reason)
+	return f.paymentService.HandleCallback(context.Background(), payload, signature)
+}
+
+func TestCheckout(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment, err := f.paymentService.Checkout(ctx, placed.Id.Hex())
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if payment.Status != helper.PAYMENT_PENDING || payment.Amount != placed.TotalPrice || payment.IntentId == "" || payment.ClientSecret == "" {
+		t.Fatalf("payment = %+v", payment)
+	}
+
+	// a pending attempt is handed out again
+	again, err := f.paymentService.Checkout(ctx, placed.Id.Hex())
+	if err != nil || again.Id != payment.Id || again.IntentId != payment.IntentId || again.ClientSecret != payment.ClientSecret {
+		t.Fatalf("second checkout = %+v, %v", again, err)
+	}
+
+	if attempts, _ := f.paymentService.GetOrderPayments(ctx, placed.Id.Hex()); len(attempts) != 1 {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+
+	tests := []struct {
+		name    string
+		orderId func() string
+		wantErr error
+	}{
+		{name: "unknown order", orderId: func() string { return primitive.NewObjectID().Hex() }, wantErr: repositories.ErrOrderNotFound},
+		{name: "paid order", orderId: func() string { return f.placePaidOrder(t, validOrderDTO()).Id.Hex() }, wantErr: ErrNotAwaitingPayment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.paymentService.Checkout(ctx, tt.orderId()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		badSign     bool
+		wantErr     error
+		wantPayment string
+		wantOrder   string
+	}{
+		{name: "success places the order", status: payments.CALLBACK_SUCCEEDED, wantPayment: helper.PAYMENT_SUCCEEDED, wantOrder: helper.PLACED},
+		{name: "failure keeps the order pending", status: payments.CALLBACK_FAILED, wantPayment: helper.PAYMENT_FAILED, wantOrder: helper.PENDING_PAYMENT},
+		{name: "bad signature", status: payments.CALLBACK_SUCCEEDED, badSign: true, wantErr: payments.ErrInvalidSignature, wantPayment: helper.PAYMENT_PENDING, wantOrder: helper.PENDING_PAYMENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+			ctx := context.Background()
+
+			placed, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+			if err != nil {
+				t.Fatal(err)
+			}
+			payment, err := f.paymentService.Checkout(ctx, placed.Id.Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payload, signature := f.callback(t, payment.IntentId, tt.status, "card declined")
+			if tt.badSign {
+				signature = payments.NewMockGateway("other-secret").Sign(payload)
+			}
+
+			_, err = f.paymentService.HandleCallback(ctx, payload, signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, _ := f.paymentRepo.GetPaymentByIntent(ctx, f.gateway.Name(), payment.IntentId)
+			if stored.Status != tt.wantPayment {
+				t.Fatalf("payment status = %s, want %s", stored.Status, tt.wantPayment)
+			}
+			order, _ := f.repo.GetOrderById(ctx, placed.Id)
+			if order.OrderStatus != tt.wantOrder {
+				t.Fatalf("order status = %s, want %s", order.OrderStatus, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestHandleCallbackRetryAndRedelivery(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed, _ := f.paymentService.Checkout(ctx, placed.Id.Hex())
+	if _, err := f.deliver(t, failed.IntentId, payments.CALLBACK_FAILED, "insufficient funds"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed attempt can be retried with a new intent
+	retry, err := f.paymentService.Checkout(ctx, placed.Id.Hex())
+	if err != nil || retry.Id == failed.Id || retry.IntentId == failed.IntentId {
+		t.Fatalf("retry = %+v, %v", retry, err)
+	}
+
+	payload, signature := f.callback(t, retry.IntentId, payments.CALLBACK_SUCCEEDED, "")
+	for i := 0; i < 2; i++ {
+		payment, err := f.paymentService.HandleCallback(ctx, payload, signature)
+		if err != nil || payment.Status != helper.PAYMENT_SUCCEEDED {
+			t.Fatalf("delivery %d = %+v, %v", i+1, payment, err)
+		}
+	}
+
+	tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id)
+	if len(tracks) != 1 || tracks[0].OrderStatus != helper.PLACED {
+		t.Fatalf("a redelivered callback must not place the order twice, tracks = %+v", tracks)
+	}
+
+	if _, err := f.deliver(t, "pi_unknown", payments.CALLBACK_SUCCEEDED, ""); !errors.Is(err, repositories.ErrPaymentNotFound) {
+		t.Fatalf("unknown intent err = %v", err)
+	}
+}
+
+func TestRedeliveredCallbackPlacesPaidOrder(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := f.paymentService.Checkout(ctx, placed.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first delivery settled the attempt, then failed before placing the order
+	if err := f.paymentRepo.SettlePayment(ctx, payment.Id, helper.PAYMENT_SUCCEEDED, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.deliver(t, payment.IntentId, payments.CALLBACK_SUCCEEDED, ""); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if order, _ := f.repo.GetOrderById(ctx, placed.Id); order.OrderStatus != helper.PLACED {
+		t.Fatalf("status = %s, want PLACED after the redelivery", order.OrderStatus)
+	}
+}
+
+// place the order and pay it through checkout and a successful callback
+func (f *serviceFixture) payOrder(t *testing.T, order dto.CreateOrderDTO) (models.Orders, models.PaymentAttempt) {
+	t.Helper()
+	ctx := context.Background()
+
+	placed, err := f.service.PlaceSingleOrder(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := f.paymentService.Checkout(ctx, placed.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment, err = f.deliver(t, payment.IntentId, payments.CALLBACK_SUCCEEDED, ""); err != nil {
+		t.Fatal(err)
+	}
+	return placed, payment
+}
+
+func TestCancelPaidOrderRefunds(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, payment := f.payOrder(t, validOrderDTO())
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+
+	order, _ := f.repo.GetOrderById(ctx, placed.Id)
+	if order.OrderStatus != helper.REFUND_PENDING {
+		t.Fatalf("status = %s, want REFUND_PENDING until the refund settles", order.OrderStatus)
+	}
+	if len(f.inventory.Calls()) != 2 {
+		t.Fatalf("a cancelled order is restocked straight away, calls = %v", f.inventory.Calls())
+	}
+
+	refunds, err := f.paymentService.GetOrderRefunds(ctx, placed.Id.Hex())
+	if err != nil || len(refunds) != 1 {
+		t.Fatalf("refunds = %+v, %v", refunds, err)
+	}
+	refund := refunds[0]
+	if refund.Kind != helper.REFUND_FULL || refund.Amount != payment.Amount || refund.PaymentId != payment.Id ||
+		refund.Reason != helper.REFUND_REASON_CANCELLED || refund.Status != helper.PAYMENT_PENDING {
+		t.Fatalf("refund = %+v", refund)
+	}
+
+	if err := f.service.UpdateOrderStatus(ctx, placed.Id.Hex(), helper.DISPATCHED); !errors.Is(err, ErrOrderClosed) {
+		t.Fatalf("dispatching a cancelled order err = %v", err)
+	}
+
+	processed, err := f.paymentService.ProcessDueRefunds(ctx)
+	if err != nil || processed != 1 {
+		t.Fatalf("ProcessDueRefunds = %d, %v", processed, err)
+	}
+
+	refunds, _ = f.paymentService.GetOrderRefunds(ctx, placed.Id.Hex())
+	if refunds[0].Status != helper.PAYMENT_SUCCEEDED || refunds[0].GatewayRefundId == "" || refunds[0].Attempts != 1 {
+		t.Fatalf("settled refund = %+v", refunds[0])
+	}
+	order, _ = f.repo.GetOrderById(ctx, placed.Id)
+	if order.OrderStatus != helper.REFUNDED {
+		t.Fatalf("status = %s, want REFUNDED", order.OrderStatus)
+	}
+
+	if processed, _ := f.paymentService.ProcessDueRefunds(ctx); processed != 0 {
+		t.Fatalf("settled refunds must not be sent again, processed = %d", processed)
+	}
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); !errors.Is(err, ErrOrderClosed) {
+		t.Fatalf("cancel a refunded order err = %v", err)
+	}
+}
+
+// payment repository whose next failures refund writes fail
+type failingRefundRepo struct {
+	repositories.PaymentRepository
+	failures int
+}
+
+func (repo *failingRefundRepo) CreateRefund(ctx context.Context, refund models.Refund) error {
+	if repo.failures > 0 {
+		repo.failures--
+		return errors.New("refunds unavailable")
+	}
+	return repo.PaymentRepository.CreateRefund(ctx, refund)
+}
+
+func TestCancelRetryAfterFailedRefundWrite(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = f.inventory.server.URL + "/api/"
+	service := NewOrderService(f.repo, &failingRefundRepo{PaymentRepository: f.paymentRepo, failures: 1}, clients.NewInventoryClient(cfg, logging.Discard()),
+		NewLimitsPolicy(f.repo, OrderLimits{MaxQuantity: 10}), f.broker, logging.Discard())
+
+	placed, payment := f.payOrder(t, validOrderDTO())
+	if err := service.CancelOrder(ctx, placed.Id.Hex()); err == nil {
+		t.Fatal("CancelOrder should report the failed refund write")
+	}
+	if order, _ := f.repo.GetOrderById(ctx, placed.Id); order.OrderStatus != helper.REFUND_PENDING {
+		t.Fatalf("status = %s, want REFUND_PENDING", order.OrderStatus)
+	}
+
+	// the retry records the refund and restocks instead of reporting a closed order
+	if err := service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatalf("retried CancelOrder: %v", err)
+	}
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 1 || refunds[0].Amount != payment.Amount || refunds[0].Status != helper.PAYMENT_PENDING {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+	if calls := f.inventory.Calls(); len(calls) != 2 || calls[1].Get("tag") != "increase" {
+		t.Fatalf("inventory calls = %v, want the order restocked once", calls)
+	}
+
+	if err := service.CancelOrder(ctx, placed.Id.Hex()); !errors.Is(err, ErrOrderClosed) {
+		t.Fatalf("CancelOrder of a finished cancellation err = %v", err)
+	}
+}
+
+func TestRefundRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantRefund   string
+		wantOrder    string
+		wantAttempts int
+	}{
+		{name: "settles after a failed attempt", failures: 1, wantRefund: helper.PAYMENT_SUCCEEDED, wantOrder: helper.REFUNDED, wantAttempts: 2},
+		{name: "gives up after max attempts", failures: 5, wantRefund: helper.PAYMENT_FAILED, wantOrder: helper.REFUND_PENDING, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture(t)
+			// no wait between attempts so every call below finds the refund due
+			f.paymentService = NewPaymentService(f.repo, f.paymentRepo, f.gateway, f.service, "INR", RefundPolicy{MaxAttempts: 3, Backoff: time.Nanosecond}, logging.Discard())
+			ctx := context.Background()
+
+			placed, _ := f.payOrder(t, validOrderDTO())
+			if err := f.service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+				t.Fatal(err)
+			}
+
+			f.gateway.FailRefunds(tt.failures)
+			for i := 0; i < 4; i++ {
+				if _, err := f.paymentService.ProcessDueRefunds(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+			if len(refunds) != 1 || refunds[0].Status != tt.wantRefund || refunds[0].Attempts != tt.wantAttempts {
+				t.Fatalf("refunds = %+v", refunds)
+			}
+			if tt.wantRefund == helper.PAYMENT_FAILED && refunds[0].LastError == "" {
+				t.Fatal("a failed refund keeps the last gateway error")
+			}
+			order, _ := f.repo.GetOrderById(ctx, placed.Id)
+			if order.OrderStatus != tt.wantOrder {
+				t.Fatalf("status = %s, want %s", order.OrderStatus, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestCancelAfterFailedRefund(t *testing.T) {
+	f := newServiceFixture(t)
+	f.paymentService = NewPaymentService(f.repo, f.paymentRepo, f.gateway, f.service, "INR", RefundPolicy{MaxAttempts: 1, Backoff: time.Nanosecond}, logging.Discard())
+	ctx := context.Background()
+
+	placed, _ := f.payOrder(t, validOrderDTO())
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	f.gateway.FailRefunds(1)
+	if _, err := f.paymentService.ProcessDueRefunds(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed refund is retried through RetryRefund, cancelling again neither
+	// asks for a second refund nor restocks again
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); !errors.Is(err, ErrOrderClosed) {
+		t.Fatalf("cancel after a failed refund err = %v, want ErrOrderClosed", err)
+	}
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 1 || refunds[0].Status != helper.PAYMENT_FAILED {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+	if calls := f.inventory.Calls(); len(calls) != 2 {
+		t.Fatalf("inventory calls = %v, want one restock", calls)
+	}
+}
+
+func TestConcurrentCancelRefundsOnce(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, _ := f.payOrder(t, validOrderDTO())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f.service.CancelOrder(ctx, placed.Id.Hex())
+		}(i)
+	}
+	wg.Wait()
+
+	cancelled := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			cancelled++
+		case !errors.Is(err, ErrOrderClosed) && !errors.Is(err, repositories.ErrOrderChanged):
+			t.Fatalf("concurrent CancelOrder err = %v", err)
+		}
+	}
+	if cancelled != 1 {
+		t.Fatalf("%d cancellations went through, want 1", cancelled)
+	}
+
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 1 {
+		t.Fatalf("refunds = %+v, want one", refunds)
+	}
+	if calls := f.inventory.Calls(); len(calls) != 2 {
+		t.Fatalf("inventory calls = %v, want one restock", calls)
+	}
+}
+
+func TestRetryFailedRefund(t *testing.T) {
+	f := newServiceFixture(t)
+	f.paymentService = NewPaymentService(f.repo, f.paymentRepo, f.gateway, f.service, "INR", RefundPolicy{MaxAttempts: 2, Backoff: time.Nanosecond}, logging.Discard())
+	ctx := context.Background()
+
+	placed, _ := f.payOrder(t, validOrderDTO())
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	refundId := refunds[0].Id.Hex()
+
+	if _, err := f.paymentService.RetryRefund(ctx, refundId, "ops"); !errors.Is(err, ErrRefundNotFailed) {
+		t.Fatalf("retry of a pending refund err = %v", err)
+	}
+
+	f.gateway.FailRefunds(2)
+	for i := 0; i < 2; i++ {
+		if _, err := f.paymentService.ProcessDueRefunds(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tracks, _ := f.repo.GetAllOrderTrack(ctx, placed.Id)
+	last := tracks[len(tracks)-1]
+	if last.RefundId == nil || last.RefundId.Hex() != refundId || last.RefundStatus != helper.PAYMENT_FAILED ||
+		last.Actor != helper.ACTOR_REFUND_PROCESSOR || last.OrderStatus != helper.REFUND_PENDING {
+		t.Fatalf("the failed refund should be on the order track, last entry = %+v", last)
+	}
+
+	retried, err := f.paymentService.RetryRefund(ctx, refundId, "ops")
+	if err != nil || retried.Status != helper.PAYMENT_PENDING || retried.Attempts != 0 {
+		t.Fatalf("RetryRefund = %+v, %v", retried, err)
+	}
+	tracks, _ = f.repo.GetAllOrderTrack(ctx, placed.Id)
+	if last := tracks[len(tracks)-1]; last.RefundStatus != helper.PAYMENT_PENDING || last.Actor != "ops" {
+		t.Fatalf("the queued refund should be on the order track, last entry = %+v", last)
+	}
+
+	if _, err := f.paymentService.ProcessDueRefunds(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := f.repo.GetOrderById(ctx, placed.Id); order.OrderStatus != helper.REFUNDED {
+		t.Fatalf("status = %s, want REFUNDED once the retried refund settles", order.OrderStatus)
+	}
+
+	if _, err := f.paymentService.RetryRefund(ctx, primitive.NewObjectID().Hex(), "ops"); !errors.Is(err, repositories.ErrRefundNotFound) {
+		t.Fatalf("unknown refund err = %v", err)
+	}
+}
+
+func TestRefundBackoff(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, _ := f.payOrder(t, validOrderDTO())
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.gateway.FailRefunds(1)
+	if processed, _ := f.paymentService.ProcessDueRefunds(ctx); processed != 1 {
+		t.Fatalf("processed = %d, want 1", processed)
+	}
+
+	// the fixture waits a minute before the next attempt
+	if processed, _ := f.paymentService.ProcessDueRefunds(ctx); processed != 0 {
+		t.Fatalf("retried before the backoff elapsed, processed = %d", processed)
+	}
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if refunds[0].Status != helper.PAYMENT_PENDING || refunds[0].NextAttemptAt.Time().Before(time.Now().Add(50*time.Second)) {
+		t.Fatalf("refund = %+v", refunds[0])
+	}
+}
+
+func TestPartialAndLateRefunds(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	placed, payment := f.payOrder(t, validOrderDTO())
+	partial, err := requestRefund(ctx, f.paymentRepo, payment, 100, "DAMAGED_ITEM", "")
+	if err != nil || partial.Kind != helper.REFUND_PARTIAL {
+		t.Fatalf("partial refund = %+v, %v", partial, err)
+	}
+
+	// cancelling refunds only what is left of the payment
+	if err := f.service.CancelOrder(ctx, placed.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 2 || refunds[1].Amount != payment.Amount-100 || refunds[1].Kind != helper.REFUND_PARTIAL {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+	if _, err := f.paymentService.ProcessDueRefunds(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := f.repo.GetOrderById(ctx, placed.Id); order.OrderStatus != helper.REFUNDED {
+		t.Fatalf("status = %s, want REFUNDED once both refunds settled", order.OrderStatus)
+	}
+
+	// paid after it was cancelled, the money goes straight back
+	late, err := f.service.PlaceSingleOrder(ctx, validOrderDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	attempt, _ := f.paymentService.Checkout(ctx, late.Id.Hex())
+	if err := f.service.CancelOrder(ctx, late.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.deliver(t, attempt.IntentId, payments.CALLBACK_SUCCEEDED, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	refunds, _ = f.paymentRepo.GetOrderRefunds(ctx, late.Id)
+	if len(refunds) != 1 || refunds[0].Reason != helper.REFUND_REASON_LATE_PAYMENT || refunds[0].Kind != helper.REFUND_FULL {
+		t.Fatalf("late payment refunds = %+v", refunds)
+	}
+	if order, _ := f.repo.GetOrderById(ctx, late.Id); order.OrderStatus != helper.CANCELLED {
+		t.Fatalf("status = %s, want CANCELLED", order.OrderStatus)
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/metrics"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReturnService returns of completed orders. A request is approved or rejected
+// by an operator, the approved parcel is picked up and inspected, and a passed
+// inspection restocks the sellable units and refunds the returned ones. A
+// refund that could not be requested leaves the return in REFUND_FAILED until
+// an operator retries it
+type ReturnService interface {
+	RequestReturn(ctx context.Context, orderId string, request dto.CreateReturnDTO) (models.ReturnRequest, error)
+	GetReturn(ctx context.Context, returnId string) (models.ReturnRequest, error)
+	GetOrderReturns(ctx context.Context, orderId string) ([]models.ReturnRequest, error)
+
+	ReviewReturn(ctx context.Context, returnId string, review dto.ReviewReturnDTO) (models.ReturnRequest, error)
+	SchedulePickup(ctx context.Context, returnId string, pickup dto.SchedulePickupDTO) (models.ReturnRequest, error)
+	InspectReturn(ctx context.Context, returnId string, inspection dto.ReturnInspectionDTO) (models.ReturnRequest, error)
+	RetryRefund(ctx context.Context, returnId string, retry dto.RetryReturnRefundDTO) (models.ReturnRequest, error)
+}
+
+// ErrOrderNotCompleted order has not reached COMPLETED yet
+var ErrOrderNotCompleted = errors.New("only completed orders can be returned")
+
+// ErrReturnWindowClosed order completed longer ago than the return window
+var ErrReturnWindowClosed = errors.New("the return window of the order has closed")
+
+// ErrReturnQuantity more units than the order has left to return
+var ErrReturnQuantity = errors.New("return quantity is more than the units left to return")
+
+// ErrReturnStep the return is not in a status that allows the requested step
+var ErrReturnStep = errors.New("return can not take this step")
+
+type returnService struct {
+	orderRepo   repositories.OrderRepository
+	returnRepo  repositories.ReturnRepository
+	paymentRepo repositories.PaymentRepository
+	inventory   clients.InventoryClient
+	window      time.Duration
+	logger      *slog.Logger
+}
+
+func NewReturnService(orderRepo repositories.OrderRepository, returnRepo repositories.ReturnRepository, paymentRepo repositories.PaymentRepository, inventory clients.InventoryClient, window time.Duration, logger *slog.Logger) ReturnService {
+	return &returnService{
+		orderRepo:   orderRepo,
+		returnRepo:  returnRepo,
+		paymentRepo: paymentRepo,
+		inventory:   inventory,
+		window:      window,
+		logger:      logger,
+	}
+}
+
+func returnAttrs(returnId string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("return.id", returnId))
+}
+
+func (ser *returnService) returnLogger(ret models.ReturnRequest) *slog.Logger {
+	return ser.logger.With("return_id", ret.Id.Hex(), "order_id", ret.OrderId.Hex(), "user_id", ret.UserId.Hex())
+}
+
+// the archived copy of a completed order, ErrOrderNotCompleted while it is still live
+func (ser *returnService) completedOrder(ctx context.Context, orderId string) (models.OrderHistory, error) {
+	orderObjId, err := helper.ValidatePrimitiveId(orderId)
+	if err != nil {
+		return models.OrderHistory{}, err
+	}
+
+	history, err := ser.orderRepo.GetOrderHistory(ctx, orderObjId)
+	if !errors.Is(err, repositories.ErrOrderNotFound) {
+		return history, err
+	}
+
+	if _, liveErr := ser.orderRepo.GetOrderById(ctx, orderObjId); liveErr != nil {
+		return models.OrderHistory{}, liveErr
+	}
+	return models.OrderHistory{}, ErrOrderNotCompleted
+}
+
+func (ser *returnService) RequestReturn(ctx context.Context, orderId string, request dto.CreateReturnDTO) (_ models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.RequestReturn", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	history, err := ser.completedOrder(ctx, orderId)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+	order := history.Order
+
+	// the order is archived the moment it completes
+	if closesAt := history.CreatedAt.Time().Add(ser.window); time.Now().After(closesAt) {
+		return models.ReturnRequest{}, fmt.Errorf("%w on %s", ErrReturnWindowClosed, closesAt.Format(time.DateOnly))
+	}
+
+	returns, err := ser.returnRepo.GetOrderReturns(ctx, order.Id)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+	left := order.Quantity
+	for _, ret := range returns {
+		if ret.Status != helper.RETURN_REJECTED {
+			left -= ret.Quantity
+		}
+	}
+	if request.Quantity > left {
+		return models.ReturnRequest{}, fmt.Errorf("%w: %d of %d units can still be returned", ErrReturnQuantity, max(left, 0), order.Quantity)
+	}
+
+	ret := new(models.ReturnRequest).SetReturnRequest(order, request.Quantity, request.Reason, request.Comment, helper.RETURN_REQUESTED)
+	ret.AddEvent(helper.RETURN_REQUESTED, order.UserId.Hex(), request.Comment)
+	if err := ser.returnRepo.CreateReturn(ctx, ret); err != nil {
+		return models.ReturnRequest{}, err
+	}
+	span.SetAttributes(attribute.String("return.id", ret.Id.Hex()))
+	metrics.ReturnTransitions.WithLabelValues(helper.RETURN_REQUESTED).Inc()
+
+	ser.returnLogger(ret).InfoContext(ctx, "return requested", "quantity", ret.Quantity, "reason", ret.Reason)
+	return ret, nil
+}
+
+func (ser *returnService) GetReturn(ctx context.Context, returnId string) (_ models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.GetReturn", returnAttrs(returnId))
+	defer tracing.End(span, &err)
+
+	returnObjId, err := helper.ValidatePrimitiveId(returnId)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	return ser.returnRepo.GetReturnById(ctx, returnObjId)
+}
+
+// every return of the order, oldest first
+func (ser *returnService) GetOrderReturns(ctx context.Context, orderId string) (_ []models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.GetOrderReturns", orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	history, err := ser.completedOrder(ctx, orderId)
+	if errors.Is(err, ErrOrderNotCompleted) {
+		return []models.ReturnRequest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ser.returnRepo.GetOrderReturns(ctx, history.Order.Id)
+}
+
+// load the return and check it is in one of the statuses the step starts from
+func (ser *returnService) returnAt(ctx context.Context, returnId, step string, from ...string) (models.ReturnRequest, error) {
+	ret, err := ser.GetReturn(ctx, returnId)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	for _, status := range from {
+		if ret.Status == status {
+			return ret, nil
+		}
+	}
+	return models.ReturnRequest{}, fmt.Errorf("%w: a %s return can not be moved to %s", ErrReturnStep, ret.Status, step)
+}
+
+// store the return in its new status, fails when someone else moved it first
+func (ser *returnService) advance(ctx context.Context, ret models.ReturnRequest, from string) error {
+	if err := ser.returnRepo.SaveReturn(ctx, ret, from); err != nil {
+		return err
+	}
+	if ret.Status != from {
+		metrics.ReturnTransitions.WithLabelValues(ret.Status).Inc()
+	}
+	return nil
+}
+
+func (ser *returnService) ReviewReturn(ctx context.Context, returnId string, review dto.ReviewReturnDTO) (_ models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.ReviewReturn", returnAttrs(returnId))
+	defer tracing.End(span, &err)
+
+	status := helper.RETURN_APPROVED
+	if review.Decision == "REJECT" {
+		status = helper.RETURN_REJECTED
+	}
+
+	ret, err := ser.returnAt(ctx, returnId, status, helper.RETURN_REQUESTED)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	ret.Status = status
+	ret.AddEvent(status, review.Operator, review.Note)
+	if err := ser.advance(ctx, ret, helper.RETURN_REQUESTED); err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	ser.returnLogger(ret).InfoContext(ctx, "return reviewed", "status", status, "operator", review.Operator)
+	return ret, nil
+}
+
+// book the pickup of an approved return, booking again moves the pickup
+func (ser *returnService) SchedulePickup(ctx context.Context, returnId string, pickup dto.SchedulePickupDTO) (_ models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.SchedulePickup", returnAttrs(returnId))
+	defer tracing.End(span, &err)
+
+	if !pickup.PickupAt.After(time.Now()) {
+		return models.ReturnRequest{}, errors.New("pickup_at must be in the future")
+	}
+
+	ret, err := ser.returnAt(ctx, returnId, helper.RETURN_PICKUP_SCHEDULED, helper.RETURN_APPROVED, helper.RETURN_PICKUP_SCHEDULED)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	from := ret.Status
+	note := "pickup at " + pickup.PickupAt.UTC().Format(time.RFC3339)
+	if pickup.Note != "" {
+		note += ", " + pickup.Note
+	}
+
+	ret.Status = helper.RETURN_PICKUP_SCHEDULED
+	ret.PickupAt = primitive.NewDateTimeFromTime(pickup.PickupAt)
+	ret.AddEvent(helper.RETURN_PICKUP_SCHEDULED, pickup.Operator, note)
+	if err := ser.advance(ctx, ret, from); err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	ser.returnLogger(ret).InfoContext(ctx, "return pickup scheduled", "pickup_at", pickup.PickupAt, "operator", pickup.Operator)
+	return ret, nil
+}
+
+// record what the inspection found. A passed return is completed first so a
+// second inspection can not restock or refund it again, then the sellable
+// units go back to stock and the returned units are refunded
+func (ser *returnService) InspectReturn(ctx context.Context, returnId string, inspection dto.ReturnInspectionDTO) (_ models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.InspectReturn", returnAttrs(returnId))
+	defer tracing.End(span, &err)
+
+	ret, err := ser.returnAt(ctx, returnId, "inspection", helper.RETURN_PICKUP_SCHEDULED)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	passed := inspection.Passed != nil && *inspection.Passed
+	if inspection.RestockQuantity > ret.Quantity || (!passed && inspection.RestockQuantity > 0) {
+		return models.ReturnRequest{}, fmt.Errorf("restock_quantity must be between 0 and the %d returned units of a passed inspection", ret.Quantity)
+	}
+
+	ret.Inspection = &models.ReturnInspection{Passed: passed, RestockQuantity: inspection.RestockQuantity, Notes: inspection.Notes}
+
+	if !passed {
+		ret.Status = helper.RETURN_INSPECTION_FAILED
+		ret.AddEvent(helper.RETURN_INSPECTION_FAILED, inspection.Operator, inspection.Notes)
+		if err := ser.advance(ctx, ret, helper.RETURN_PICKUP_SCHEDULED); err != nil {
+			return models.ReturnRequest{}, err
+		}
+		ser.returnLogger(ret).WarnContext(ctx, "return failed inspection", "operator", inspection.Operator)
+		return ret, nil
+	}
+
+	ret.Status = helper.RETURN_COMPLETED
+	ret.AddEvent(helper.RETURN_INSPECTED, inspection.Operator, inspection.Notes)
+	if err := ser.advance(ctx, ret, helper.RETURN_PICKUP_SCHEDULED); err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	ser.restock(ctx, &ret, inspection.Operator)
+	return ser.settle(ctx, ret, inspection.Operator)
+}
+
+// request the refund of a return whose refund failed. The return is moved back
+// to COMPLETED before the refund is requested, so two retries can not both refund it
+func (ser *returnService) RetryRefund(ctx context.Context, returnId string, retry dto.RetryReturnRefundDTO) (_ models.ReturnRequest, err error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.RetryRefund", returnAttrs(returnId))
+	defer tracing.End(span, &err)
+
+	ret, err := ser.returnAt(ctx, returnId, "refund retry", helper.RETURN_REFUND_FAILED)
+	if err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	ret.Status = helper.RETURN_COMPLETED
+	if err := ser.advance(ctx, ret, helper.RETURN_REFUND_FAILED); err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	return ser.settle(ctx, ret, retry.Operator)
+}
+
+// refund a completed return and store the outcome. A refund that could not be
+// requested is noted on the timeline and parks the return in REFUND_FAILED
+func (ser *returnService) settle(ctx context.Context, ret models.ReturnRequest, operator string) (models.ReturnRequest, error) {
+	logger := ser.returnLogger(ret)
+
+	if err := ser.refund(ctx, &ret, operator); err != nil {
+		logger.ErrorContext(ctx, "failed to request the refund of a return", "operator", operator, "error", err)
+		ret.Status = helper.RETURN_REFUND_FAILED
+		ret.AddEvent(helper.RETURN_REFUND_FAILED, operator, err.Error())
+	} else {
+		ret.AddEvent(helper.RETURN_COMPLETED, operator, "")
+	}
+
+	if err := ser.advance(ctx, ret, helper.RETURN_COMPLETED); err != nil {
+		return models.ReturnRequest{}, err
+	}
+
+	if ret.Status == helper.RETURN_COMPLETED {
+		logger.InfoContext(ctx, "return completed", "restocked", ret.Inspection.RestockQuantity, "operator", operator)
+	}
+	return ret, nil
+}
+
+// put the sellable units back on sale, a failure is noted on the timeline for an operator to follow up
+func (ser *returnService) restock(ctx context.Context, ret *models.ReturnRequest, operator string) {
+	units := ret.Inspection.RestockQuantity
+	if units == 0 {
+		return
+	}
+
+	if err := ser.inventory.UpdateProductCount(ctx, "increase", strconv.FormatInt(units, 10), ret.ProductId.Hex()); err != nil {
+		ser.returnLogger(*ret).ErrorContext(ctx, "failed to restock returned units", "units", units, "error", err)
+		ret.AddEvent(helper.RETURN_RESTOCK_FAILED, operator, err.Error())
+		return
+	}
+	ret.AddEvent(helper.RETURN_RESTOCKED, operator, strconv.FormatInt(units, 10)+" units")
+}
+
+// request a refund of the returned units at the price they were paid for
+func (ser *returnService) refund(ctx context.Context, ret *models.ReturnRequest, operator string) error {
+	history, err := ser.orderRepo.GetOrderHistory(ctx, ret.OrderId)
+	if err != nil {
+		return err
+	}
+	order := history.Order
+
+	payment, refundable, err := refundablePayment(ctx, ser.paymentRepo, order.Id)
+	if err != nil {
+		return err
+	}
+
+	amount := min(order.TotalPrice/float64(order.Quantity)*float64(ret.Quantity), refundable)
+	if payment.Id.IsZero() || amount <= 0 {
+		return nil
+	}
+
+	refund, err := requestRefund(ctx, ser.paymentRepo, payment, amount, helper.REFUND_REASON_RETURNED, "")
+	if err != nil {
+		return err
+	}
+
+	ret.RefundId = &refund.Id
+	ret.AddEvent(helper.RETURN_REFUND_REQUESTED, operator, strconv.FormatFloat(amount, 'f', 2, 64)+" "+refund.Currency)
+	return nil
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (f *serviceFixture) newReturnService(window time.Duration) ReturnService {
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = f.inventory.server.URL + "/api/"
+	return NewReturnService(f.repo, repositories.NewMemoryReturnRepository(), f.paymentRepo, clients.NewInventoryClient(cfg, logging.Discard()), window, logging.Discard())
+}
+
+// pay for the order and deliver it, completing it moves it to the order history
+func (f *serviceFixture) completeOrder(t *testing.T, order dto.CreateOrderDTO) models.Orders {
+	t.Helper()
+	ctx := context.Background()
+
+	placed, _ := f.payOrder(t, order)
+	for _, status := range []string{helper.DISPATCHED, helper.COMPLETED} {
+		if err := f.service.UpdateOrderStatus(ctx, placed.Id.Hex(), status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return placed
+}
+
+func passed(ok bool) *bool { return &ok }
+
+func TestReturnFlow(t *testing.T) {
+	f := newServiceFixture(t)
+	returns := f.newReturnService(14 * 24 * time.Hour)
+	ctx := context.Background()
+
+	order := validOrderDTO()
+	placed := f.completeOrder(t, order)
+
+	ret, err := returns.RequestReturn(ctx, placed.Id.Hex(), dto.CreateReturnDTO{Quantity: 2, Reason: "DAMAGED", Comment: "cover torn"})
+	if err != nil {
+		t.Fatalf("RequestReturn: %v", err)
+	}
+	if ret.Status != helper.RETURN_REQUESTED || ret.ProductId.Hex() != order.ProductId || len(ret.Timeline) != 1 {
+		t.Fatalf("requested = %+v", ret)
+	}
+
+	if _, err := returns.SchedulePickup(ctx, ret.Id.Hex(), dto.SchedulePickupDTO{PickupAt: time.Now().Add(time.Hour), Operator: "ops"}); !errors.Is(err, ErrReturnStep) {
+		t.Fatalf("pickup before review err = %v", err)
+	}
+	if ret, err = returns.ReviewReturn(ctx, ret.Id.Hex(), dto.ReviewReturnDTO{Decision: "APPROVE", Operator: "ops"}); err != nil || ret.Status != helper.RETURN_APPROVED {
+		t.Fatalf("ReviewReturn = %+v, %v", ret, err)
+	}
+	if _, err := returns.ReviewReturn(ctx, ret.Id.Hex(), dto.ReviewReturnDTO{Decision: "REJECT", Operator: "ops"}); !errors.Is(err, ErrReturnStep) {
+		t.Fatalf("second review err = %v", err)
+	}
+
+	if _, err := returns.SchedulePickup(ctx, ret.Id.Hex(), dto.SchedulePickupDTO{PickupAt: time.Now().Add(-time.Hour), Operator: "ops"}); err == nil {
+		t.Fatal("a pickup in the past must be rejected")
+	}
+	pickupAt := time.Now().Add(24 * time.Hour)
+	if ret, err = returns.SchedulePickup(ctx, ret.Id.Hex(), dto.SchedulePickupDTO{PickupAt: pickupAt, Operator: "ops"}); err != nil || ret.Status != helper.RETURN_PICKUP_SCHEDULED {
+		t.Fatalf("SchedulePickup = %+v, %v", ret, err)
+	}
+	if ret.PickupAt.Time().Unix() != pickupAt.Unix() {
+		t.Fatalf("pickup_at = %v, want %v", ret.PickupAt.Time(), pickupAt)
+	}
+
+	if _, err := returns.InspectReturn(ctx, ret.Id.Hex(), dto.ReturnInspectionDTO{Passed: passed(true), RestockQuantity: 3, Operator: "warehouse"}); err == nil {
+		t.Fatal("restocking more units than were returned must be rejected")
+	}
+	if ret, err = returns.InspectReturn(ctx, ret.Id.Hex(), dto.ReturnInspectionDTO{Passed: passed(true), RestockQuantity: 1, Operator: "warehouse", Notes: "one copy water damaged"}); err != nil {
+		t.Fatalf("InspectReturn: %v", err)
+	}
+	if ret.Status != helper.RETURN_COMPLETED || ret.RefundId == nil || ret.Inspection == nil || ret.Inspection.RestockQuantity != 1 {
+		t.Fatalf("inspected = %+v", ret)
+	}
+
+	steps := []string{}
+	for _, event := range ret.Timeline {
+		steps = append(steps, event.Step)
+	}
+	want := []string{helper.RETURN_REQUESTED, helper.RETURN_APPROVED, helper.RETURN_PICKUP_SCHEDULED, helper.RETURN_INSPECTED,
+		helper.RETURN_RESTOCKED, helper.RETURN_REFUND_REQUESTED, helper.RETURN_COMPLETED}
+	if len(steps) != len(want) {
+		t.Fatalf("timeline = %v, want %v", steps, want)
+	}
+	for i := range want {
+		if steps[i] != want[i] {
+			t.Fatalf("timeline = %v, want %v", steps, want)
+		}
+	}
+
+	calls := f.inventory.Calls()
+	if restock := calls[len(calls)-1]; restock.Get("tag") != "increase" || restock.Get("number") != "1" || restock.Get("product_id") != order.ProductId {
+		t.Fatalf("restock call = %v", restock)
+	}
+
+	refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id)
+	if len(refunds) != 1 || refunds[0].Id != *ret.RefundId || refunds[0].Amount != 300 ||
+		refunds[0].Kind != helper.REFUND_PARTIAL || refunds[0].Reason != helper.REFUND_REASON_RETURNED {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+
+	// the refund of an archived order settles without touching the order
+	if processed, err := f.paymentService.ProcessDueRefunds(ctx); err != nil || processed != 1 {
+		t.Fatalf("ProcessDueRefunds = %d, %v", processed, err)
+	}
+	if refunds, _ = f.paymentRepo.GetOrderRefunds(ctx, placed.Id); refunds[0].Status != helper.PAYMENT_SUCCEEDED {
+		t.Fatalf("settled refund = %+v", refunds[0])
+	}
+	if completed, err := f.service.GetOrder(ctx, placed.Id.Hex()); err != nil || completed.OrderStatus != helper.COMPLETED {
+		t.Fatalf("order = %+v, %v", completed, err)
+	}
+
+	if _, err := returns.InspectReturn(ctx, ret.Id.Hex(), dto.ReturnInspectionDTO{Passed: passed(true), Operator: "warehouse"}); !errors.Is(err, ErrReturnStep) {
+		t.Fatalf("second inspection err = %v", err)
+	}
+
+	list, err := returns.GetOrderReturns(ctx, placed.Id.Hex())
+	if err != nil || len(list) != 1 || list[0].Id != ret.Id {
+		t.Fatalf("GetOrderReturns = %+v, %v", list, err)
+	}
+}
+
+func TestRequestReturnErrors(t *testing.T) {
+	f := newServiceFixture(t)
+	returns := f.newReturnService(14 * 24 * time.Hour)
+	ctx := context.Background()
+
+	placed := f.completeOrder(t, validOrderDTO())
+	live, _ := f.payOrder(t, validOrderDTO())
+
+	first, err := returns.RequestReturn(ctx, placed.Id.Hex(), dto.CreateReturnDTO{Quantity: 2, Reason: "WRONG_ITEM"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		orderId string
+		qty     int64
+		wantErr error
+	}{
+		{name: "unknown order", orderId: primitive.NewObjectID().Hex(), qty: 1, wantErr: repositories.ErrOrderNotFound},
+		{name: "order not delivered", orderId: live.Id.Hex(), qty: 1, wantErr: ErrOrderNotCompleted},
+		{name: "more than is left", orderId: placed.Id.Hex(), qty: 2, wantErr: ErrReturnQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := returns.RequestReturn(ctx, tt.orderId, dto.CreateReturnDTO{Quantity: tt.qty, Reason: "OTHER"}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// a rejected return frees its units again
+	if _, err := returns.ReviewReturn(ctx, first.Id.Hex(), dto.ReviewReturnDTO{Decision: "REJECT", Operator: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := returns.RequestReturn(ctx, placed.Id.Hex(), dto.CreateReturnDTO{Quantity: 3, Reason: "OTHER"}); err != nil {
+		t.Fatalf("return after a rejection: %v", err)
+	}
+
+	closed := f.newReturnService(time.Nanosecond)
+	if _, err := closed.RequestReturn(ctx, placed.Id.Hex(), dto.CreateReturnDTO{Quantity: 1, Reason: "OTHER"}); !errors.Is(err, ErrReturnWindowClosed) {
+		t.Fatalf("closed window err = %v", err)
+	}
+}
+
+func TestInspectReturnFailures(t *testing.T) {
+	f := newServiceFixture(t)
+	returns := f.newReturnService(14 * 24 * time.Hour)
+	ctx := context.Background()
+
+	placed := f.completeOrder(t, validOrderDTO())
+
+	// walk a new return of one unit up to its pickup
+	pickedUp := func() models.ReturnRequest {
+		ret, err := returns.RequestReturn(ctx, placed.Id.Hex(), dto.CreateReturnDTO{Quantity: 1, Reason: "NOT_AS_DESCRIBED"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := returns.ReviewReturn(ctx, ret.Id.Hex(), dto.ReviewReturnDTO{Decision: "APPROVE", Operator: "ops"}); err != nil {
+			t.Fatal(err)
+		}
+		if ret, err = returns.SchedulePickup(ctx, ret.Id.Hex(), dto.SchedulePickupDTO{PickupAt: time.Now().Add(time.Hour), Operator: "ops"}); err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+
+	failed, err := returns.InspectReturn(ctx, pickedUp().Id.Hex(), dto.ReturnInspectionDTO{Passed: passed(false), Operator: "warehouse", Notes: "not our item"})
+	if err != nil || failed.Status != helper.RETURN_INSPECTION_FAILED || failed.RefundId != nil {
+		t.Fatalf("failed inspection = %+v, %v", failed, err)
+	}
+
+	// a failed restock is noted and the refund still goes out
+	f.inventory.mu.Lock()
+	f.inventory.failWith = http.StatusInternalServerError
+	f.inventory.mu.Unlock()
+	ret, err := returns.InspectReturn(ctx, pickedUp().Id.Hex(), dto.ReturnInspectionDTO{Passed: passed(true), RestockQuantity: 1, Operator: "warehouse"})
+	if err != nil || ret.Status != helper.RETURN_COMPLETED || ret.RefundId == nil {
+		t.Fatalf("inspection with a failed restock = %+v, %v", ret, err)
+	}
+	restockFailed := false
+	for _, event := range ret.Timeline {
+		restockFailed = restockFailed || event.Step == helper.RETURN_RESTOCK_FAILED
+	}
+	if !restockFailed {
+		t.Fatalf("timeline = %+v, want a RESTOCK_FAILED step", ret.Timeline)
+	}
+
+	if refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id); len(refunds) != 1 || refunds[0].Amount != 150 {
+		t.Fatalf("refunds = %+v, want only the passed return refunded", refunds)
+	}
+}
+
+func TestRetryReturnRefund(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = f.inventory.server.URL + "/api/"
+	returns := NewReturnService(f.repo, repositories.NewMemoryReturnRepository(), &failingRefundRepo{PaymentRepository: f.paymentRepo, failures: 1},
+		clients.NewInventoryClient(cfg, logging.Discard()), 14*24*time.Hour, logging.Discard())
+
+	placed := f.completeOrder(t, validOrderDTO())
+	ret, err := returns.RequestReturn(ctx, placed.Id.Hex(), dto.CreateReturnDTO{Quantity: 1, Reason: "DAMAGED"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := returns.ReviewReturn(ctx, ret.Id.Hex(), dto.ReviewReturnDTO{Decision: "APPROVE", Operator: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := returns.SchedulePickup(ctx, ret.Id.Hex(), dto.SchedulePickupDTO{PickupAt: time.Now().Add(time.Hour), Operator: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := returns.RetryRefund(ctx, ret.Id.Hex(), dto.RetryReturnRefundDTO{Operator: "ops"}); !errors.Is(err, ErrReturnStep) {
+		t.Fatalf("retry before inspection err = %v", err)
+	}
+
+	// the failed refund is noted and parks the return, the restock is not repeated by the retry
+	failed, err := returns.InspectReturn(ctx, ret.Id.Hex(), dto.ReturnInspectionDTO{Passed: passed(true), RestockQuantity: 1, Operator: "warehouse"})
+	if err != nil || failed.Status != helper.RETURN_REFUND_FAILED || failed.RefundId != nil {
+		t.Fatalf("inspection with a failed refund = %+v, %v", failed, err)
+	}
+	if last := failed.Timeline[len(failed.Timeline)-1]; last.Step != helper.RETURN_REFUND_FAILED || last.Note == "" {
+		t.Fatalf("timeline = %+v, want a REFUND_FAILED step last", failed.Timeline)
+	}
+	restocks := len(f.inventory.Calls())
+
+	retried, err := returns.RetryRefund(ctx, ret.Id.Hex(), dto.RetryReturnRefundDTO{Operator: "ops"})
+	if err != nil || retried.Status != helper.RETURN_COMPLETED || retried.RefundId == nil {
+		t.Fatalf("RetryRefund = %+v, %v", retried, err)
+	}
+	if last := retried.Timeline[len(retried.Timeline)-1]; last.Step != helper.RETURN_COMPLETED {
+		t.Fatalf("timeline = %+v, want COMPLETED last", retried.Timeline)
+	}
+	if calls := f.inventory.Calls(); len(calls) != restocks {
+		t.Fatalf("inventory calls = %v, the retry must not restock again", calls)
+	}
+	if refunds, _ := f.paymentRepo.GetOrderRefunds(ctx, placed.Id); len(refunds) != 1 || refunds[0].Id != *retried.RefundId || refunds[0].Amount != 150 {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+
+	if _, err := returns.RetryRefund(ctx, ret.Id.Hex(), dto.RetryReturnRefundDTO{Operator: "ops"}); !errors.Is(err, ErrReturnStep) {
+		t.Fatalf("retry of a completed return err = %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/helper"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/repositories"
+	"github.com/aniket0951/order-services/tracing"
+)
+
+// WishlistService save-for-later list of a user. Cart lines move into it with
+// a snapshot of their unit price and move back into the cart at the current price
+type WishlistService interface {
+	// GetWishlist the user's items, each priced against the product service
+	GetWishlist(ctx context.Context, userId string) ([]models.WishlistItem, error)
+	SaveForLater(ctx context.Context, userId, orderId string) (models.WishlistItem, error)
+	MoveToCart(ctx context.Context, userId, itemId string) (models.Orders, error)
+	RemoveWishlistItem(ctx context.Context, userId, itemId string) error
+}
+
+// ErrProductDiscontinued the product service no longer sells the saved variant
+var ErrProductDiscontinued = errors.New("the product is no longer sold")
+
+// WISHLIST_PRICE_CONCURRENCY product service calls in flight while pricing a wishlist
+const WISHLIST_PRICE_CONCURRENCY = 8
+
+type wishlistService struct {
+	orderRepo    repositories.OrderRepository
+	wishlistRepo repositories.WishlistRepository
+	orderService OrderService
+	inventory    clients.InventoryClient
+	priceTimeout time.Duration
+	logger       *slog.Logger
+}
+
+func NewWishlistService(orderRepo repositories.OrderRepository, wishlistRepo repositories.WishlistRepository, orderService OrderService, inventory clients.InventoryClient, priceTimeout time.Duration, logger *slog.Logger) WishlistService {
+	return &wishlistService{
+		orderRepo:    orderRepo,
+		wishlistRepo: wishlistRepo,
+		orderService: orderService,
+		inventory:    inventory,
+		priceTimeout: priceTimeout,
+		logger:       logger,
+	}
+}
+
+func (ser *wishlistService) itemLogger(item models.WishlistItem) *slog.Logger {
+	return ser.logger.With("wishlist_item_id", item.Id.Hex(), "user_id", item.UserId.Hex(), "prod_selling_id", item.ProductSellingID.Hex())
+}
+
+// the item when it belongs to the user, another user's item is reported as missing
+func (ser *wishlistService) userItem(ctx context.Context, userId, itemId string) (models.WishlistItem, error) {
+	userObjId, err := helper.ValidatePrimitiveId(userId)
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	itemObjId, err := helper.ValidatePrimitiveId(itemId)
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	item, err := ser.wishlistRepo.GetWishlistItem(ctx, itemObjId)
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	if item.UserId != userObjId {
+		return models.WishlistItem{}, repositories.ErrWishlistItemNotFound
+	}
+	return item, nil
+}
+
+func (ser *wishlistService) GetWishlist(ctx context.Context, userId string) (_ []models.WishlistItem, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.GetWishlist", userAttrs(userId))
+	defer tracing.End(span, &err)
+
+	userObjId, err := helper.ValidatePrimitiveId(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := ser.wishlistRepo.GetUserWishlist(ctx, userObjId)
+	if err != nil {
+		return nil, err
+	}
+
+	// price the items concurrently under one deadline, an unreachable product
+	// service leaves an item unpriced rather than failing the list
+	priceCtx, cancel := context.WithTimeout(ctx, ser.priceTimeout)
+	defer cancel()
+
+	slots := make(chan struct{}, WISHLIST_PRICE_CONCURRENCY)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		go func(item *models.WishlistItem) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			ser.price(priceCtx, item)
+		}(&items[i])
+	}
+	wg.Wait()
+
+	return items, nil
+}
+
+// set the current price of the item and how far it dropped since it was saved
+func (ser *wishlistService) price(ctx context.Context, item *models.WishlistItem) {
+	product, err := ser.inventory.GetProduct(ctx, item.ProductSellingID.Hex())
+	if errors.Is(err, clients.ErrProductNotFound) {
+		item.Discontinued = true
+		return
+	}
+	if err != nil {
+		ser.itemLogger(*item).WarnContext(ctx, "failed to fetch the current price", "error", err)
+		return
+	}
+
+	item.CurrentPrice = product.Price
+	if product.Price < item.SavedPrice {
+		item.PriceDrop = item.SavedPrice - product.Price
+	}
+}
+
+// snapshot the cart line, then drop it from the cart. Saving the same variant
+// again adds to the item, a retry of the line after a failed removal is not counted twice
+func (ser *wishlistService) SaveForLater(ctx context.Context, userId, orderId string) (_ models.WishlistItem, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.SaveForLater", userAttrs(userId), orderAttrs(orderId))
+	defer tracing.End(span, &err)
+
+	userObjId, err := helper.ValidatePrimitiveId(userId)
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	orderObjId, err := helper.ValidatePrimitiveId(orderId)
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	order, err := ser.orderRepo.GetOrderById(ctx, orderObjId)
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	if order.UserId != userObjId || order.OrderStatus != helper.CART || order.Quantity <= 0 {
+		return models.WishlistItem{}, ErrNotInCart
+	}
+
+	unitPrice := int64(math.Round(order.TotalPrice / float64(order.Quantity)))
+	item, err := ser.wishlistRepo.SaveWishlistItem(ctx, new(models.WishlistItem).SetWishlistItem(order, unitPrice))
+	if err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	if err := ser.orderService.RemoveItemFromCart(ctx, orderId); err != nil {
+		return models.WishlistItem{}, err
+	}
+
+	ser.itemLogger(item).InfoContext(ctx, "cart line saved for later", "order_id", orderId, "saved_price", unitPrice)
+	return item, nil
+}
+
+// take the item off the wishlist, then add it to the cart at the current price.
+// Claiming it first lets only one of concurrent moves add it, a failed add puts it back
+func (ser *wishlistService) MoveToCart(ctx context.Context, userId, itemId string) (_ models.Orders, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.MoveToCart", userAttrs(userId))
+	defer tracing.End(span, &err)
+
+	item, err := ser.userItem(ctx, userId, itemId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+
+	product, err := ser.inventory.GetProduct(ctx, item.ProductSellingID.Hex())
+	if errors.Is(err, clients.ErrProductNotFound) {
+		return models.Orders{}, ErrProductDiscontinued
+	}
+	if err != nil {
+		return models.Orders{}, err
+	}
+
+	if err := ser.wishlistRepo.ClaimWishlistItem(ctx, item); err != nil {
+		return models.Orders{}, err
+	}
+
+	line, err := ser.orderService.AddToCart(ctx, dto.CreateOrderDTO{
+		ProductId:        item.ProductId.Hex(),
+		Category:         item.Category,
+		ProductSellingID: item.ProductSellingID.Hex(),
+		Quantity:         item.Quantity,
+		Price:            product.Price,
+		UserId:           item.UserId.Hex(),
+	})
+	if err != nil {
+		if _, saveErr := ser.wishlistRepo.SaveWishlistItem(ctx, item); saveErr != nil {
+			ser.itemLogger(item).ErrorContext(ctx, "failed to put the item back on the wishlist", "quantity", item.Quantity, "error", saveErr)
+		}
+		return models.Orders{}, err
+	}
+
+	ser.itemLogger(item).InfoContext(ctx, "wishlist item moved to cart", "order_id", line.Id.Hex(), "saved_price", item.SavedPrice, "price", product.Price)
+	return line, nil
+}
+
+func (ser *wishlistService) RemoveWishlistItem(ctx context.Context, userId, itemId string) (err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.RemoveWishlistItem", userAttrs(userId))
+	defer tracing.End(span, &err)
+
+	item, err := ser.userItem(ctx, userId, itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := ser.wishlistRepo.DeleteWishlistItem(ctx, item.Id); err != nil {
+		return err
+	}
+
+	ser.itemLogger(item).InfoContext(ctx, "wishlist item removed")
+	return nil
+}
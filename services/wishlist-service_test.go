@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aniket0951/order-services/clients"
+	"github.com/aniket0951/order-services/config"
+	"github.com/aniket0951/order-services/dto"
+	"github.com/aniket0951/order-services/logging"
+	"github.com/aniket0951/order-services/models"
+	"github.com/aniket0951/order-services/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (f *serviceFixture) newWishlistService() WishlistService {
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = f.inventory.server.URL + "/api/"
+	return NewWishlistService(f.repo, repositories.NewMemoryWishlistRepository(), f.service, clients.NewInventoryClient(cfg, logging.Discard()), cfg.ProductService.Timeout, logging.Discard())
+}
+
+func TestWishlist(t *testing.T) {
+	f := newServiceFixture(t)
+	wishlist := f.newWishlistService()
+	ctx := context.Background()
+
+	req := validOrderDTO()
+	line, err := f.service.AddToCart(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wishlist.SaveForLater(ctx, primitive.NewObjectID().Hex(), line.Id.Hex()); !errors.Is(err, ErrNotInCart) {
+		t.Fatalf("SaveForLater of another user's line err = %v", err)
+	}
+	placed := f.placePaidOrder(t, validOrderDTO())
+	if _, err := wishlist.SaveForLater(ctx, placed.UserId.Hex(), placed.Id.Hex()); !errors.Is(err, ErrNotInCart) {
+		t.Fatalf("SaveForLater of a placed order err = %v", err)
+	}
+
+	saved, err := wishlist.SaveForLater(ctx, req.UserId, line.Id.Hex())
+	if err != nil || saved.SavedPrice != 150 || saved.Quantity != 3 || saved.ProductSellingID != line.ProductSellingID {
+		t.Fatalf("SaveForLater = %+v, %v", saved, err)
+	}
+	if items, _ := f.service.GetCartItems(ctx, req.UserId); len(items) != 0 {
+		t.Fatalf("cart after save for later = %+v", items)
+	}
+
+	// the price dropped since the item was saved
+	f.inventory.SetProduct(clients.ProductInfo{ProductSellingID: req.ProductSellingID, Price: 120, Available: 10})
+	items, err := wishlist.GetWishlist(ctx, req.UserId)
+	if err != nil || len(items) != 1 || items[0].CurrentPrice != 120 || items[0].PriceDrop != 30 || items[0].Discontinued {
+		t.Fatalf("GetWishlist = %+v, %v", items, err)
+	}
+
+	moved, err := wishlist.MoveToCart(ctx, req.UserId, saved.Id.Hex())
+	if err != nil || moved.Quantity != 3 || moved.TotalPrice != 360 {
+		t.Fatalf("MoveToCart = %+v, %v", moved, err)
+	}
+	if items, _ := wishlist.GetWishlist(ctx, req.UserId); len(items) != 0 {
+		t.Fatalf("wishlist after move = %+v", items)
+	}
+	if items, _ := f.service.GetCartItems(ctx, req.UserId); len(items) != 1 || items[0].Id != moved.Id {
+		t.Fatalf("cart after move = %+v", items)
+	}
+}
+
+func TestWishlistUnavailableAndRemoved(t *testing.T) {
+	f := newServiceFixture(t)
+	wishlist := f.newWishlistService()
+	ctx := context.Background()
+
+	req := validOrderDTO()
+	line, err := f.service.AddToCart(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := wishlist.SaveForLater(ctx, req.UserId, line.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the product service does not know the variant any more
+	items, err := wishlist.GetWishlist(ctx, req.UserId)
+	if err != nil || len(items) != 1 || !items[0].Discontinued || items[0].CurrentPrice != 0 || items[0].PriceDrop != 0 {
+		t.Fatalf("GetWishlist = %+v, %v", items, err)
+	}
+	if _, err := wishlist.MoveToCart(ctx, req.UserId, saved.Id.Hex()); !errors.Is(err, ErrProductDiscontinued) {
+		t.Fatalf("MoveToCart of a discontinued product err = %v", err)
+	}
+
+	// a price rise is no drop
+	f.inventory.SetProduct(clients.ProductInfo{ProductSellingID: req.ProductSellingID, Price: 180, Available: 10})
+	if items, _ := wishlist.GetWishlist(ctx, req.UserId); len(items) != 1 || items[0].CurrentPrice != 180 || items[0].PriceDrop != 0 {
+		t.Fatalf("GetWishlist after a price rise = %+v", items)
+	}
+
+	if err := wishlist.RemoveWishlistItem(ctx, primitive.NewObjectID().Hex(), saved.Id.Hex()); !errors.Is(err, repositories.ErrWishlistItemNotFound) {
+		t.Fatalf("RemoveWishlistItem by another user err = %v", err)
+	}
+	if err := wishlist.RemoveWishlistItem(ctx, req.UserId, saved.Id.Hex()); err != nil {
+		t.Fatalf("RemoveWishlistItem: %v", err)
+	}
+	if _, err := wishlist.MoveToCart(ctx, req.UserId, saved.Id.Hex()); !errors.Is(err, repositories.ErrWishlistItemNotFound) {
+		t.Fatalf("MoveToCart of a removed item err = %v", err)
+	}
+}
+
+// cart that refuses every line
+type failingCart struct {
+	OrderService
+}
+
+func (failingCart) AddToCart(ctx context.Context, order dto.CreateOrderDTO) (models.Orders, error) {
+	return models.Orders{}, errors.New("cart is down")
+}
+
+func TestWishlistMoveToCartOnce(t *testing.T) {
+	f := newServiceFixture(t)
+	wishlistRepo := repositories.NewMemoryWishlistRepository()
+	cfg := config.Default()
+	cfg.ProductService.BaseURL = f.inventory.server.URL + "/api/"
+	inventory := clients.NewInventoryClient(cfg, logging.Discard())
+	wishlist := NewWishlistService(f.repo, wishlistRepo, f.service, inventory, cfg.ProductService.Timeout, logging.Discard())
+	ctx := context.Background()
+
+	req := validOrderDTO()
+	line, err := f.service.AddToCart(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := wishlist.SaveForLater(ctx, req.UserId, line.Id.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.inventory.SetProduct(clients.ProductInfo{ProductSellingID: req.ProductSellingID, Price: 150, Available: 10})
+
+	// a failed add puts the item back on the wishlist
+	broken := NewWishlistService(f.repo, wishlistRepo, failingCart{f.service}, inventory, cfg.ProductService.Timeout, logging.Discard())
+	if _, err := broken.MoveToCart(ctx, req.UserId, saved.Id.Hex()); err == nil {
+		t.Fatal("MoveToCart with the cart down succeeded")
+	}
+	if items, _ := wishlist.GetWishlist(ctx, req.UserId); len(items) != 1 || items[0].Id != saved.Id || items[0].Quantity != 3 {
+		t.Fatalf("wishlist after a failed move = %+v", items)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = wishlist.MoveToCart(ctx, req.UserId, saved.Id.Hex())
+		}(i)
+	}
+	wg.Wait()
+
+	moved := 0
+	for _, err := range errs {
+		if err == nil {
+			moved++
+		} else if !errors.Is(err, repositories.ErrWishlistItemNotFound) {
+			t.Fatalf("concurrent MoveToCart err = %v", err)
+		}
+	}
+	if moved != 1 {
+		t.Fatalf("%d moves succeeded, want 1", moved)
+	}
+	if items, _ := f.service.GetCartItems(ctx, req.UserId); len(items) != 1 || items[0].Quantity != 3 {
+		t.Fatalf("cart after concurrent moves = %+v", items)
+	}
+}
+
+// product service that answers no price until the caller gives up
+type stalledInventory struct {
+	clients.InventoryClient
+}
+
+func (stalledInventory) GetProduct(ctx context.Context, productSellingId string) (clients.ProductInfo, error) {
+	<-ctx.Done()
+	return clients.ProductInfo{}, ctx.Err()
+}
+
+func TestWishlistPricesUnderOneDeadline(t *testing.T) {
+	f := newServiceFixture(t)
+	ctx := context.Background()
+
+	wishlistRepo := repositories.NewMemoryWishlistRepository()
+	saving := NewWishlistService(f.repo, wishlistRepo, f.service, nil, time.Second, logging.Discard())
+	req := validOrderDTO()
+	for i := 0; i < 3*WISHLIST_PRICE_CONCURRENCY; i++ {
+		req.ProductSellingID = primitive.NewObjectID().Hex()
+		line, err := f.service.AddToCart(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := saving.SaveForLater(ctx, req.UserId, line.Id.Hex()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeout := 100 * time.Millisecond
+	wishlist := NewWishlistService(f.repo, wishlistRepo, f.service, stalledInventory{}, timeout, logging.Discard())
+
+	start := time.Now()
+	items, err := wishlist.GetWishlist(ctx, req.UserId)
+	if err != nil || len(items) != 3*WISHLIST_PRICE_CONCURRENCY {
+		t.Fatalf("GetWishlist = %d items, %v", len(items), err)
+	}
+	// one deadline for the whole list, not one per item
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Fatalf("GetWishlist took %s", elapsed)
+	}
+	for _, item := range items {
+		if item.CurrentPrice != 0 || item.Discontinued {
+			t.Fatalf("item = %+v, want it left unpriced", item)
+		}
+	}
+}